@@ -9,7 +9,7 @@ import (
 	"github.com/spf13/cobra"
 
 	aiPkg "github.com/Achilles1089/sovereign-stack/internal/ai"
-	"github.com/Achilles1089/sovereign-stack/internal/config"
+	"github.com/Achilles1089/sovereign-stack/internal/audit"
 	"github.com/Achilles1089/sovereign-stack/internal/hardware"
 )
 
@@ -60,14 +60,12 @@ func init() {
 	rootCmd.AddCommand(aiCmd)
 }
 
-func getLlamaClient() *aiPkg.Client {
-	cfgPath := config.ConfigPath(GetConfigPath())
-	cfg := config.LoadOrDefault(cfgPath)
-	host := cfg.AI.Host
-	if host == "" {
-		host = "localhost:8085"
+func getLlamaClient() (*aiPkg.Client, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
 	}
-	return aiPkg.NewClient(host)
+	return aiPkg.NewClientFromConfig(cfg), nil
 }
 
 func runAIStatus(cmd *cobra.Command, args []string) error {
@@ -76,8 +74,10 @@ func runAIStatus(cmd *cobra.Command, args []string) error {
 	fmt.Println("  ──────────────────────────────")
 	fmt.Println()
 
-	cfgPath := config.ConfigPath(GetConfigPath())
-	cfg := config.LoadOrDefault(cfgPath)
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
 
 	hw := &cfg.Hardware
 	if hw.GPUType != "" && hw.GPUType != "none" {
@@ -89,7 +89,10 @@ func runAIStatus(cmd *cobra.Command, args []string) error {
 	fmt.Printf("  Default Model: %s\n", cfg.AI.DefaultModel)
 	fmt.Println()
 
-	client := getLlamaClient()
+	client, err := getLlamaClient()
+	if err != nil {
+		return err
+	}
 	fmt.Printf("  Server Host:   %s\n", client.Host)
 	fmt.Printf("  Engine:        llama-server\n")
 
@@ -116,12 +119,15 @@ func runAIStatus(cmd *cobra.Command, args []string) error {
 
 func runAIPull(cmd *cobra.Command, args []string) error {
 	model := args[0]
-	client := getLlamaClient()
+	client, err := getLlamaClient()
+	if err != nil {
+		return err
+	}
 
 	fmt.Printf("\n  Pulling model: %s\n", model)
 	fmt.Printf("  From: %s\n\n", client.Host)
 
-	err := client.PullModel(model, func(status string, completed, total int64) {
+	err = client.PullModel(model, func(status string, completed, total int64) {
 		if total > 0 {
 			pct := float64(completed) / float64(total) * 100
 			fmt.Printf("\r  %-30s %.1f%%", status, pct)
@@ -129,6 +135,7 @@ func runAIPull(cmd *cobra.Command, args []string) error {
 			fmt.Printf("\r  %s", status)
 		}
 	})
+	auditLogger().LogModelEvent(audit.CLIActor(), "pull", model, err)
 
 	if err != nil {
 		return fmt.Errorf("pull failed: %w", err)
@@ -139,9 +146,14 @@ func runAIPull(cmd *cobra.Command, args []string) error {
 }
 
 func runAIChat(cmd *cobra.Command, args []string) error {
-	cfgPath := config.ConfigPath(GetConfigPath())
-	cfg := config.LoadOrDefault(cfgPath)
-	client := getLlamaClient()
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	client, err := getLlamaClient()
+	if err != nil {
+		return err
+	}
 	model := cfg.AI.DefaultModel
 	if model == "" {
 		model = "rwkv7-2.9B"
@@ -201,7 +213,10 @@ func runAIChat(cmd *cobra.Command, args []string) error {
 }
 
 func runAIModels(cmd *cobra.Command, args []string) error {
-	client := getLlamaClient()
+	client, err := getLlamaClient()
+	if err != nil {
+		return err
+	}
 
 	models, err := client.ListModels()
 	if err != nil {
@@ -223,8 +238,10 @@ func runAIModels(cmd *cobra.Command, args []string) error {
 }
 
 func runAICatalog(cmd *cobra.Command, args []string) error {
-	cfgPath := config.ConfigPath(GetConfigPath())
-	cfg := config.LoadOrDefault(cfgPath)
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
 	tier := hardware.GetGPUTier(&cfg.Hardware)
 
 	tierName := map[hardware.GPUTier]string{
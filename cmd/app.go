@@ -1,14 +1,18 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/spf13/cobra"
 
 	"github.com/Achilles1089/sovereign-stack/internal/apps"
+	"github.com/Achilles1089/sovereign-stack/internal/audit"
 	"github.com/Achilles1089/sovereign-stack/internal/config"
+	"github.com/Achilles1089/sovereign-stack/internal/rbac"
 )
 
 var appCmd = &cobra.Command{
@@ -37,7 +41,17 @@ var appRemoveCmd = &cobra.Command{
 	RunE:  runAppRemove,
 }
 
+var (
+	appDryRun        bool
+	appAllocatePorts bool
+	appCascade       bool
+)
+
 func init() {
+	appInstallCmd.Flags().BoolVar(&appDryRun, "dry-run", false, "Show the compose changes without applying them")
+	appInstallCmd.Flags().BoolVar(&appAllocatePorts, "allocate-ports", false, "Move host ports already in use to free ones from apps.port_range")
+	appRemoveCmd.Flags().BoolVar(&appDryRun, "dry-run", false, "Show the compose changes without applying them")
+	appRemoveCmd.Flags().BoolVar(&appCascade, "cascade", false, "Also remove the installed apps that need it")
 	appCmd.AddCommand(appListCmd)
 	appCmd.AddCommand(appInstallCmd)
 	appCmd.AddCommand(appRemoveCmd)
@@ -84,33 +98,111 @@ func runAppInstall(cmd *cobra.Command, args []string) error {
 	if app == nil {
 		return fmt.Errorf("app '%s' not found. Run 'sovereign app list' to see available apps", name)
 	}
+	if err := authorize(rbac.PermAppInstall, rbac.AppResource(app.Name, app.Category)); err != nil {
+		return err
+	}
 
 	// Check if initialized
 	cfgPath := config.ConfigPath(GetConfigPath())
-	_, err := config.Load(cfgPath)
+	cfg, err := config.Load(cfgPath)
 	if err != nil {
 		return fmt.Errorf("sovereign not initialized. Run 'sovereign init' first")
 	}
 
-	fmt.Printf("\n  Installing %s v%s...\n", app.DisplayName, app.Version)
-	fmt.Println("  → Pulling Docker image...")
-	fmt.Println("  → Generating configuration...")
-
-	if err := apps.InstallApp(app); err != nil {
-		return fmt.Errorf("installation failed: %w", err)
+	// The apps it needs are installed first
+	order, err := apps.InstallOrder(app)
+	if err != nil {
+		return err
+	}
+	for i := range order {
+		if i < len(order)-1 {
+			if err := authorize(rbac.PermAppInstall, rbac.AppResource(order[i].Name, order[i].Category)); err != nil {
+				return err
+			}
+		}
+		if appAllocatePorts || cfg.Apps.AllocatePorts {
+			if order[i], err = apps.AllocatePorts(cfg, order[i], order[:i]...); err != nil {
+				return err
+			}
+		}
 	}
+	app = order[len(order)-1]
 
-	fmt.Println("  → Starting container...")
-	fmt.Printf("  ✓ %s installed successfully!\n", app.DisplayName)
+	if appDryRun {
+		for i, a := range order {
+			plan, err := apps.PlanInstall(cfg, a)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("\n  Installing %s v%s would change:\n\n", a.DisplayName, a.Version)
+			printPortMappings(a)
+			printPreflight(apps.RunPreflight(cfg, a, order[:i]...))
+			printPlan(plan)
+		}
+		printDryRunFooter()
+		return nil
+	}
 
-	if app.CaddyRoute != nil {
-		fmt.Printf("  → Access at: http://localhost:%d\n", app.CaddyRoute.Port)
+	fmt.Printf("\n  Installing %s v%s...\n\n", app.DisplayName, app.Version)
+	if len(order) > 1 {
+		fmt.Printf("  → Requires %s, installed first\n\n", strings.Join(apps.Names(order[:len(order)-1]), ", "))
+	}
+	failed := 0
+	pres := make([]*apps.Preflight, len(order))
+	for i, a := range order {
+		if len(order) > 1 {
+			fmt.Printf("  %s:\n", a.DisplayName)
+		}
+		printPortMappings(a)
+		pres[i] = apps.RunPreflight(cfg, a, order[:i]...)
+		printPreflight(pres[i])
+		if err := pres[i].Err(); err != nil {
+			failed += len(pres[i].Failures())
+			auditLogger().LogAppInstall(audit.CLIActor(), a.Name, err)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("installation failed: %d preflight checks failed", failed)
 	}
 
+	for i, a := range order {
+		if err := installOne(cfg, cfgPath, a, pres[i]); err != nil {
+			return fmt.Errorf("installation of %s failed: %w", a.Name, err)
+		}
+	}
 	fmt.Println()
 	return nil
 }
 
+// installOne installs one app whose preflight pre passed and shows where it
+// can be reached
+func installOne(cfg *config.Config, cfgPath string, app *apps.AppManifest, pre *apps.Preflight) error {
+	fmt.Printf("  → Installing %s...\n", app.DisplayName)
+	err := apps.InstallChecked(cfg, app, pre)
+	if err == nil && len(pre.Enable) > 0 {
+		if err = cfg.Save(cfgPath); err == nil {
+			fmt.Printf("  → Enabled %s\n", strings.Join(pre.Enable, ", "))
+		}
+	}
+	auditLogger().LogAppInstall(audit.CLIActor(), app.Name, err)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("  ✓ %s installed successfully!\n", app.DisplayName)
+	if state, err := apps.LoadState(app.Name); err == nil {
+		if url := state.URL(cfg); url != "" {
+			fmt.Printf("  → Access at: %s\n", url)
+		}
+		for _, m := range state.Ports {
+			if m.Remapped() {
+				fmt.Printf("  → Port %d/%s published on %d\n", m.DefaultPort, m.Protocol, m.HostPort)
+			}
+		}
+	}
+	return nil
+}
+
 func runAppRemove(cmd *cobra.Command, args []string) error {
 	name := args[0]
 
@@ -118,13 +210,80 @@ func runAppRemove(cmd *cobra.Command, args []string) error {
 	if app == nil {
 		return fmt.Errorf("app '%s' not found", name)
 	}
+	if err := authorize(rbac.PermAppRemove, rbac.AppResource(app.Name, app.Category)); err != nil {
+		return err
+	}
 
-	fmt.Printf("\n  Removing %s...\n", app.DisplayName)
+	// Apps that need it go first, and only with --cascade
+	order, err := apps.RemoveOrder(name, appCascade)
+	if errors.Is(err, apps.ErrRequired) {
+		return fmt.Errorf("%w; remove them first or use --cascade", err)
+	}
+	if err != nil {
+		return err
+	}
+	for _, n := range order[:len(order)-1] {
+		res := rbac.AppResource(n, "")
+		if a := apps.FindApp(n); a != nil {
+			res = rbac.AppResource(a.Name, a.Category)
+		}
+		if err := authorize(rbac.PermAppRemove, res); err != nil {
+			return err
+		}
+	}
 
-	if err := apps.RemoveApp(name); err != nil {
-		return fmt.Errorf("removal failed: %w", err)
+	if appDryRun {
+		for _, n := range order {
+			plan, err := apps.PlanRemove(n)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("\n  Removing %s would change:\n\n", displayName(n))
+			printPlan(plan)
+		}
+		printDryRunFooter()
+		return nil
+	}
+
+	fmt.Printf("\n  Removing %s...\n", app.DisplayName)
+	if len(order) > 1 {
+		fmt.Printf("  → Needed by %s, removed first\n", strings.Join(order[:len(order)-1], ", "))
 	}
 
-	fmt.Printf("  ✓ %s removed.\n\n", app.DisplayName)
+	for _, n := range order {
+		err := apps.RemoveApp(n)
+		auditLogger().LogAppRemove(audit.CLIActor(), n, err)
+		if err != nil {
+			return fmt.Errorf("removal of %s failed: %w", n, err)
+		}
+		fmt.Printf("  ✓ %s removed.\n", displayName(n))
+	}
+	fmt.Println()
 	return nil
 }
+
+// displayName returns the display name of a catalog app, or name itself
+func displayName(name string) string {
+	if app := apps.FindApp(name); app != nil {
+		return app.DisplayName
+	}
+	return name
+}
+
+// printPortMappings shows the host ports AllocatePorts moved
+func printPortMappings(app *apps.AppManifest) {
+	var moved []apps.PortMapping
+	for _, m := range app.PortMappings() {
+		if m.Remapped() {
+			moved = append(moved, m)
+		}
+	}
+	if len(moved) == 0 {
+		return
+	}
+	fmt.Println("  Ports:")
+	for _, m := range moved {
+		fmt.Printf("    %d/%s → %d (default in use)\n", m.DefaultPort, m.Protocol, m.HostPort)
+	}
+	fmt.Println()
+}
@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Achilles1089/sovereign-stack/internal/audit"
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Inspect the audit log",
+	Long:  `Query and verify the tamper-evident audit log in ~/.sovereign/audit.`,
+}
+
+var auditVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify the audit log hash chain",
+	Long: `Walk every audit log segment, oldest first, and check that each event's
+hash matches its contents and links to the event before it. Reports the first
+broken link and exits non-zero if the history has been altered.`,
+	RunE: runAuditVerify,
+}
+
+var auditPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Apply the audit retention policy now",
+	Long: `Compress rotated audit segments and delete the oldest ones that exceed the
+limits in the audit section of config.yaml (max_age_days, max_total_mb,
+max_files). This also runs automatically whenever the log rotates.`,
+	RunE: runAuditPrune,
+}
+
+var auditLogCmd = &cobra.Command{
+	Use:   "log",
+	Short: "Show audit events",
+	Long: `Show audit events from the current and rotated logs, newest last.
+
+Example:
+  sovereign audit log --since 24h --action 'app.*'
+  sovereign audit log --actor alice --failed`,
+	RunE: runAuditLog,
+}
+
+var (
+	auditSince    string
+	auditUntil    string
+	auditActor    string
+	auditTarget   string
+	auditSeverity string
+	auditAction   string
+	auditFailed   bool
+	auditLimit    int
+	auditCursor   string
+)
+
+func init() {
+	auditLogCmd.Flags().StringVar(&auditSince, "since", "", "Only events after this time (RFC 3339, YYYY-MM-DD or duration like 24h)")
+	auditLogCmd.Flags().StringVar(&auditUntil, "until", "", "Only events before this time")
+	auditLogCmd.Flags().StringVar(&auditActor, "actor", "", "Only events by this actor")
+	auditLogCmd.Flags().StringVar(&auditTarget, "target", "", "Only events whose target starts with this prefix")
+	auditLogCmd.Flags().StringVar(&auditSeverity, "severity", "", "Only events with this severity (info, warning, critical)")
+	auditLogCmd.Flags().StringVar(&auditAction, "action", "", "Only events whose action matches this glob, e.g. 'app.*'")
+	auditLogCmd.Flags().BoolVar(&auditFailed, "failed", false, "Only failed actions")
+	auditLogCmd.Flags().IntVarP(&auditLimit, "limit", "n", 50, "Maximum events to show")
+	auditLogCmd.Flags().StringVar(&auditCursor, "cursor", "", "Continue from a previous page")
+
+	auditCmd.AddCommand(auditLogCmd)
+	auditCmd.AddCommand(auditVerifyCmd)
+	auditCmd.AddCommand(auditPruneCmd)
+	rootCmd.AddCommand(auditCmd)
+}
+
+func runAuditVerify(cmd *cobra.Command, args []string) error {
+	fmt.Println()
+	fmt.Println("  ⚡ Sovereign Stack — Audit Verify")
+	fmt.Println("  ─────────────────────────────────")
+	fmt.Println()
+
+	result, err := auditLogger().Verify()
+	if err != nil {
+		return fmt.Errorf("verification failed: %w", err)
+	}
+
+	fmt.Printf("  Files:   %d\n", result.Files)
+	fmt.Printf("  Events:  %d\n", result.Events)
+	if result.Unchained > 0 {
+		fmt.Printf("  Legacy:  %d events predate hash chaining and are not covered\n", result.Unchained)
+	}
+	if result.Pruned > 0 {
+		fmt.Printf("  Pruned:  %d older segments removed by retention\n", result.Pruned)
+	}
+	fmt.Println()
+
+	if !result.OK() {
+		b := result.Break
+		fmt.Printf("  ✗ Chain broken in %s line %d", b.File, b.Line)
+		if b.EventID != "" {
+			fmt.Printf(" (event %s)", b.EventID)
+		}
+		fmt.Printf("\n    %s\n\n", b.Reason)
+		return fmt.Errorf("audit log failed verification")
+	}
+
+	if result.Head != "" {
+		fmt.Printf("  Head:    %s\n\n", result.Head)
+	}
+	fmt.Println("  ✓ Audit log intact")
+	fmt.Println()
+	return nil
+}
+
+func runAuditPrune(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println()
+	fmt.Println("  ⚡ Sovereign Stack — Audit Prune")
+	fmt.Println("  ─────────────────────────────────")
+	fmt.Println()
+
+	logger := audit.NewLoggerFromConfig(cfg)
+	result, err := logger.ApplyRetention()
+	if err != nil {
+		return fmt.Errorf("retention failed: %w", err)
+	}
+
+	for _, name := range result.Compressed {
+		fmt.Printf("  Compressed  %s\n", name)
+	}
+	for _, name := range result.Removed {
+		fmt.Printf("  Removed     %s\n", name)
+	}
+
+	if len(result.Compressed) == 0 && len(result.Removed) == 0 {
+		fmt.Println("  ✓ Audit history is within retention limits")
+		fmt.Println()
+		return nil
+	}
+
+	fmt.Println()
+	if len(result.Removed) == 0 {
+		fmt.Printf("  ✓ Compressed %d segments\n", len(result.Compressed))
+		fmt.Println()
+		return nil
+	}
+
+	freed := float64(result.FreedBytes) / 1024 / 1024
+	logger.Record(audit.CLIActor(), "audit.prune", "audit",
+		fmt.Sprintf("Pruned %d audit segments (%.1f MB)", len(result.Removed), freed), nil)
+	fmt.Printf("  ✓ Removed %d segments, freed %.1f MB\n", len(result.Removed), freed)
+	fmt.Println()
+	return nil
+}
+
+func runAuditLog(cmd *cobra.Command, args []string) error {
+	now := time.Now()
+	since, err := audit.ParseTime(auditSince, now)
+	if err != nil {
+		return err
+	}
+	until, err := audit.ParseTime(auditUntil, now)
+	if err != nil {
+		return err
+	}
+
+	opts := audit.QueryOptions{
+		Since:        since,
+		Until:        until,
+		Actor:        auditActor,
+		TargetPrefix: auditTarget,
+		Severity:     auditSeverity,
+		Action:       auditAction,
+		Limit:        auditLimit,
+		Cursor:       auditCursor,
+		Descending:   true,
+	}
+	if auditFailed {
+		failed := false
+		opts.Success = &failed
+	}
+
+	result, err := auditLogger().Search(opts)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println()
+	fmt.Println("  ⚡ Sovereign Stack — Audit Log")
+	fmt.Println("  ─────────────────────────────────")
+	fmt.Println()
+
+	if len(result.Events) == 0 {
+		fmt.Println("  No matching events.")
+		fmt.Println()
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  TIME\tACTION\tACTOR\tTARGET\tRESULT")
+	fmt.Fprintln(w, "  ────\t──────\t─────\t──────\t──────")
+
+	// Fetched newest-first; print oldest-first like a log
+	for i := len(result.Events) - 1; i >= 0; i-- {
+		ev := result.Events[i]
+		status := "✓"
+		if !ev.Success {
+			status = "✗"
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s %s\n",
+			ev.Timestamp.Local().Format("2006-01-02 15:04:05"), ev.Action, ev.Actor, ev.Target, status, ev.Severity)
+	}
+	w.Flush()
+
+	if result.NextCursor != "" {
+		fmt.Println()
+		fmt.Printf("  Older events: sovereign audit log --cursor %s\n", result.NextCursor)
+	}
+	fmt.Println()
+	return nil
+}
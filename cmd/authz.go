@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+
+	"github.com/Achilles1089/sovereign-stack/internal/apps"
+	"github.com/Achilles1089/sovereign-stack/internal/rbac"
+)
+
+// cliUsername returns the RBAC identity of whoever runs the CLI:
+// $SOVEREIGN_USER if set, otherwise the OS login name
+func cliUsername() string {
+	if name := os.Getenv("SOVEREIGN_USER"); name != "" {
+		return name
+	}
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
+}
+
+// authorize checks the CLI caller's permission on a resource. It is a no-op
+// while RBAC is disabled, and root is always allowed.
+func authorize(perm rbac.Permission, res rbac.Resource) error {
+	cfg, err := rbac.LoadConfig()
+	if err != nil {
+		return err
+	}
+	if !cfg.Enabled || os.Geteuid() == 0 {
+		return nil
+	}
+
+	name := cliUsername()
+	u, err := rbac.GetUser(name)
+	if err != nil {
+		return fmt.Errorf("RBAC is enabled and %q is not a sovereign user (set SOVEREIGN_USER)", name)
+	}
+	if !u.Can(perm, res) {
+		return fmt.Errorf("permission denied: %s needs %s on %s", name, perm, res)
+	}
+	return nil
+}
+
+// serviceResource builds the RBAC resource for a compose service, tagging
+// app containers with their category
+func serviceResource(name string) rbac.Resource {
+	if app := apps.FindApp(name); app != nil {
+		return rbac.ServiceResource(name, app.Category)
+	}
+	return rbac.ServiceResource(name, "")
+}
@@ -3,12 +3,15 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/spf13/cobra"
 
+	"github.com/Achilles1089/sovereign-stack/internal/audit"
 	backupPkg "github.com/Achilles1089/sovereign-stack/internal/backup"
 	"github.com/Achilles1089/sovereign-stack/internal/config"
+	"github.com/Achilles1089/sovereign-stack/internal/secrets"
 )
 
 var backupCmd = &cobra.Command{
@@ -55,9 +58,16 @@ By default, backups run daily at 3:00 AM.`,
 	RunE: runBackupSchedule,
 }
 
-var backupDisable bool
+// backupSecret holds the restic repository password
+const backupSecret = "backup/password"
+
+var (
+	backupDisable bool
+	backupTag     string
+)
 
 func init() {
+	backupCmd.Flags().StringVar(&backupTag, "tag", "manual", "Tag for the snapshot (scheduled backups use \"auto\")")
 	backupScheduleCmd.Flags().BoolVar(&backupDisable, "disable", false, "Remove the automated backup schedule")
 	backupCmd.AddCommand(backupListCmd)
 	backupCmd.AddCommand(backupRestoreCmd)
@@ -67,15 +77,34 @@ func init() {
 	rootCmd.AddCommand(backupCmd)
 }
 
-func getBackupManager() *backupPkg.Manager {
-	cfgPath := config.ConfigPath(GetConfigPath())
-	cfg := config.LoadOrDefault(cfgPath)
+func getBackupManager() (*backupPkg.Manager, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
 
 	mgr := backupPkg.NewManager(config.ConfigDir())
-	if cfg.Backup.Password != "" {
-		mgr.SetPassword(cfg.Backup.Password)
+
+	ref := cfg.Backup.Password
+	if ref == "" {
+		ref = secrets.Ref(backupSecret)
+	}
+	if !secrets.IsRef(ref) {
+		fmt.Fprintf(os.Stderr, "  ⚠ backup.password is stored in plaintext — move it with: sovereign secret set %s\n", backupSecret)
+		mgr.SetPassword(ref)
+		return mgr, nil
+	}
+
+	name := strings.TrimPrefix(ref, secrets.RefPrefix)
+	migrated, err := mgr.LoadPassword(secrets.Default(), name)
+	if err != nil {
+		return nil, err
 	}
-	return mgr
+	if migrated {
+		fmt.Fprintf(os.Stderr, "  ⚠ Stored the built-in password of this older repository as secret %s — rotate it:\n", name)
+		fmt.Fprintf(os.Stderr, "    RESTIC_REPOSITORY=%s restic key passwd, then sovereign secret set %s\n", mgr.RepoPath, name)
+	}
+	return mgr, nil
 }
 
 func runBackupCreate(cmd *cobra.Command, args []string) error {
@@ -84,7 +113,7 @@ func runBackupCreate(cmd *cobra.Command, args []string) error {
 	fmt.Println("  ───────────────────────────")
 	fmt.Println()
 
-	if !backupPkg.IsResticInstalled() {
+	if !backupPkg.NewManager(config.ConfigDir()).IsResticInstalled() {
 		fmt.Println("  ⚠  Restic is not installed.")
 		fmt.Println("  Install with:")
 		fmt.Println("    macOS:  brew install restic")
@@ -93,15 +122,21 @@ func runBackupCreate(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	mgr := getBackupManager()
+	mgr, err := getBackupManager()
+	if err != nil {
+		return err
+	}
 
 	fmt.Println("  Initializing repository (if needed)...")
 	if err := mgr.InitRepo(); err != nil {
+		auditLogger().LogBackup(audit.CLIActor(), "create", backupTag, err)
 		return fmt.Errorf("failed to initialize backup repo: %w", err)
 	}
 
 	fmt.Println("  Creating encrypted backup snapshot...")
-	if err := mgr.Backup("manual"); err != nil {
+	err = mgr.Backup(backupTag)
+	auditLogger().LogBackup(audit.CLIActor(), "create", backupTag, err)
+	if err != nil {
 		return fmt.Errorf("backup failed: %w", err)
 	}
 
@@ -117,11 +152,14 @@ func runBackupList(cmd *cobra.Command, args []string) error {
 	fmt.Println("  ──────────────────────────────────────")
 	fmt.Println()
 
-	if !backupPkg.IsResticInstalled() {
+	if !backupPkg.NewManager(config.ConfigDir()).IsResticInstalled() {
 		return fmt.Errorf("restic is not installed")
 	}
 
-	mgr := getBackupManager()
+	mgr, err := getBackupManager()
+	if err != nil {
+		return err
+	}
 	snapshots, err := mgr.ListSnapshots()
 	if err != nil {
 		return err
@@ -163,8 +201,13 @@ func runBackupRestore(cmd *cobra.Command, args []string) error {
 	fmt.Println()
 	fmt.Printf("  Restoring snapshot %s...\n", snapshotID)
 
-	mgr := getBackupManager()
-	if err := mgr.Restore(snapshotID, ""); err != nil {
+	mgr, err := getBackupManager()
+	if err != nil {
+		return err
+	}
+	err = mgr.Restore(snapshotID, "")
+	auditLogger().LogBackup(audit.CLIActor(), "restore", snapshotID, err)
+	if err != nil {
 		return fmt.Errorf("restore failed: %w", err)
 	}
 
@@ -179,8 +222,13 @@ func runBackupPrune(cmd *cobra.Command, args []string) error {
 	fmt.Println("  Retention: keep last 7, daily 30, weekly 12")
 	fmt.Println()
 
-	mgr := getBackupManager()
-	if err := mgr.Prune(7, 30, 12); err != nil {
+	mgr, err := getBackupManager()
+	if err != nil {
+		return err
+	}
+	err = mgr.Prune(7, 30, 12)
+	auditLogger().LogBackup(audit.CLIActor(), "prune", "snapshots", err)
+	if err != nil {
 		return fmt.Errorf("prune failed: %w", err)
 	}
 
@@ -193,8 +241,13 @@ func runBackupInit(cmd *cobra.Command, args []string) error {
 	fmt.Println()
 	fmt.Println("  Initializing backup repository...")
 
-	mgr := getBackupManager()
-	if err := mgr.InitRepo(); err != nil {
+	mgr, err := getBackupManager()
+	if err != nil {
+		return err
+	}
+	err = mgr.InitRepo()
+	auditLogger().LogBackup(audit.CLIActor(), "init", "repository", err)
+	if err != nil {
 		return fmt.Errorf("init failed: %w", err)
 	}
 
@@ -210,7 +263,9 @@ func runBackupSchedule(cmd *cobra.Command, args []string) error {
 	fmt.Println()
 
 	if backupDisable {
-		if err := backupPkg.RemoveCron(); err != nil {
+		err := backupPkg.NewManager(config.ConfigDir()).RemoveCron()
+		auditLogger().Record(audit.CLIActor(), "backup.schedule", "backup/schedule", "Removed automated backup schedule", err)
+		if err != nil {
 			return fmt.Errorf("failed to remove schedule: %w", err)
 		}
 		fmt.Println("  ✓ Automated backup schedule removed")
@@ -218,8 +273,10 @@ func runBackupSchedule(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	cfgPath := config.ConfigPath(GetConfigPath())
-	cfg := config.LoadOrDefault(cfgPath)
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
 	schedule := cfg.Backup.Schedule
 	if schedule == "" {
 		schedule = "0 3 * * *" // Daily at 3am
@@ -231,7 +288,9 @@ func runBackupSchedule(cmd *cobra.Command, args []string) error {
 		binaryPath = "sovereign" // Fallback
 	}
 
-	if err := backupPkg.SetupCron(schedule, binaryPath); err != nil {
+	err = backupPkg.NewManager(config.ConfigDir()).SetupCron(schedule, binaryPath)
+	auditLogger().Record(audit.CLIActor(), "backup.schedule", "backup/schedule", fmt.Sprintf("Scheduled automated backups: %s", schedule), err)
+	if err != nil {
 		return fmt.Errorf("failed to set up schedule: %w", err)
 	}
 
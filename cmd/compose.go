@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Achilles1089/sovereign-stack/internal/apps"
+	"github.com/Achilles1089/sovereign-stack/internal/audit"
+	"github.com/Achilles1089/sovereign-stack/internal/docker"
+	"github.com/Achilles1089/sovereign-stack/internal/rbac"
+)
+
+var composeCmd = &cobra.Command{
+	Use:   "compose",
+	Short: "Inspect and roll back compose file revisions",
+	Long: `Every change to the core docker-compose.yml and to each app's
+apps/<name>/compose.yml made by init, app install and app remove is saved
+as a revision in ~/.sovereign/compose-history.`,
+}
+
+var composeHistoryCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List saved compose revisions",
+	RunE:  runComposeHistory,
+}
+
+var composeRollbackCmd = &cobra.Command{
+	Use:   "rollback <rev>",
+	Short: "Restore an earlier compose revision and apply it",
+	Long: `Restore an earlier revision of the compose file it belongs to, recorded
+as a new revision, and recreate that project's services to match it. Rolling
+back to a revision of a removed app reinstalls it.
+
+Example:
+  sovereign compose history
+  sovereign compose rollback 3 --dry-run
+  sovereign compose rollback 3`,
+	Args: cobra.ExactArgs(1),
+	RunE: runComposeRollback,
+}
+
+var composeRollbackDryRun bool
+
+func init() {
+	composeRollbackCmd.Flags().BoolVar(&composeRollbackDryRun, "dry-run", false, "Show the changes without applying them")
+	composeCmd.AddCommand(composeHistoryCmd)
+	composeCmd.AddCommand(composeRollbackCmd)
+	rootCmd.AddCommand(composeCmd)
+}
+
+func runComposeHistory(cmd *cobra.Command, args []string) error {
+	if err := authorize(rbac.PermConfigRead, rbac.Global); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	fmt.Println("  ⚡ Sovereign Stack — Compose History")
+	fmt.Println("  ────────────────────────────────────")
+	fmt.Println()
+
+	revs, err := docker.History()
+	if err != nil {
+		return err
+	}
+	if len(revs) == 0 {
+		fmt.Println("  No revisions recorded yet.")
+		fmt.Println()
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  REV\tTIME\tPROJECT\tCHANGE\tSERVICES")
+	fmt.Fprintln(w, "  ───\t────\t───────\t──────\t────────")
+	seen := make(map[string]bool)
+	for i := len(revs) - 1; i >= 0; i-- {
+		r := revs[i]
+		rev := strconv.Itoa(r.Rev)
+		// The newest revision of each file is its current contents
+		if !seen[r.Path] && !r.Removed {
+			rev += " (current)"
+		}
+		seen[r.Path] = true
+
+		project := r.Project
+		if project == "" {
+			project = "-"
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\n", rev, r.Time.Local().Format("2006-01-02 15:04:05"), project, r.Reason, strings.Join(r.Services, ", "))
+	}
+	w.Flush()
+	fmt.Println()
+	fmt.Println("  Roll back with: sovereign compose rollback <rev>")
+	fmt.Println()
+	return nil
+}
+
+func runComposeRollback(cmd *cobra.Command, args []string) error {
+	rev, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid revision %q", args[0])
+	}
+	if err := authorize(rbac.PermConfigWrite, rbac.Global); err != nil {
+		return err
+	}
+	if err := authorize(rbac.PermServiceStart, rbac.Global); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	fmt.Println("  ⚡ Sovereign Stack — Compose Rollback")
+	fmt.Println("  ─────────────────────────────────────")
+	fmt.Println()
+
+	if composeRollbackDryRun {
+		plan, err := docker.PlanRollback(rev)
+		if err != nil {
+			return err
+		}
+		printPlan(plan)
+		printDryRunFooter()
+		return nil
+	}
+
+	plan, err := docker.Rollback(rev)
+	if err == nil {
+		// A restored app needs its env file back; it is deleted on remove
+		if app := docker.AppOf(plan.Path); app != "" {
+			err = apps.WriteCredentials(app)
+		}
+	}
+	if err != nil {
+		auditLogger().Record(audit.CLIActor(), "compose.rollback", "compose/"+args[0], fmt.Sprintf("Rolled back compose to revision %d", rev), err)
+		return err
+	}
+	printPlan(plan)
+
+	fmt.Println("  Recreating services...")
+	err = docker.ComposeUp(cmd.Context(), plan.Path, os.Stdout, os.Stderr, "--remove-orphans")
+	auditLogger().Record(audit.CLIActor(), "compose.rollback", "compose/"+args[0], fmt.Sprintf("Rolled back compose to revision %d", rev), err)
+	if err != nil {
+		return fmt.Errorf("compose file restored, but recreating services failed: %w", err)
+	}
+
+	fmt.Println()
+	fmt.Printf("  ✓ Rolled back to revision %d\n", rev)
+	fmt.Println()
+	return nil
+}
@@ -0,0 +1,295 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Achilles1089/sovereign-stack/internal/audit"
+	"github.com/Achilles1089/sovereign-stack/internal/config"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect and check the configuration",
+	Long:  `Work with ~/.sovereign/config.yaml (or the file given with --config).`,
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate [file]",
+	Short: "Check the config file for mistakes",
+	Long: `Check a config file for unknown keys, values of the wrong type, invalid
+ports and host:port addresses, and malformed cron schedules. Every problem is
+reported with its YAML path and line number.
+
+Example:
+  sovereign config validate
+  sovereign config validate ./staging.yaml`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runConfigValidate,
+}
+
+var configMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Upgrade the config file to the current schema",
+	Long: `Upgrade config.yaml written by an older release to the schema this binary
+uses. The original is kept next to it as config.yaml.v<N>.bak. Loading an
+old config migrates it automatically; use --dry-run to preview the change.`,
+	RunE: runConfigMigrate,
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show configuration values",
+	Long: `Show every config value. With --effective, values are resolved through all
+layers: defaults < config file < SOVEREIGN_* environment variables < --set
+flags. With --source, each value is listed with the layer that supplied it.
+
+Every config path has an environment variable named after it, e.g.
+ai.rag_host is SOVEREIGN_AI_RAG_HOST.
+
+Example:
+  sovereign config show --effective --source
+  SOVEREIGN_PORT=9090 sovereign config show --effective --source
+  sovereign --set ai.rag_host=10.0.0.5:8093 config show --effective`,
+	RunE: runConfigShow,
+}
+
+var (
+	configMigrateDryRun bool
+	configShowEffective bool
+	configShowSource    bool
+)
+
+func init() {
+	configMigrateCmd.Flags().BoolVar(&configMigrateDryRun, "dry-run", false, "Show the changes without writing them")
+	configShowCmd.Flags().BoolVar(&configShowEffective, "effective", false, "Apply environment and --set overrides")
+	configShowCmd.Flags().BoolVar(&configShowSource, "source", false, "Show where each value came from")
+
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configMigrateCmd)
+	configCmd.AddCommand(configShowCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	cfgPath := config.ConfigPath(GetConfigPath())
+	if len(args) == 1 {
+		cfgPath = args[0]
+	}
+
+	fmt.Println()
+	fmt.Println("  ⚡ Sovereign Stack — Config Validate")
+	fmt.Println("  ─────────────────────────────────")
+	fmt.Println()
+	fmt.Printf("  File: %s\n\n", cfgPath)
+
+	data, err := os.ReadFile(cfgPath)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	err = config.Validate(data)
+	if err == nil {
+		fmt.Println("  ✓ Config is valid")
+		fmt.Println()
+		return nil
+	}
+
+	var problems config.ValidationErrors
+	if !errors.As(err, &problems) {
+		fmt.Printf("  ✗ %v\n\n", err)
+		return fmt.Errorf("config is invalid")
+	}
+
+	for _, p := range problems {
+		if p.Line > 0 {
+			fmt.Printf("  ✗ line %-4d %s: %s\n", p.Line, p.Path, p.Message)
+		} else {
+			fmt.Printf("  ✗ %s: %s\n", p.Path, p.Message)
+		}
+	}
+	fmt.Println()
+	return fmt.Errorf("config has %d problem(s)", len(problems))
+}
+
+func runConfigMigrate(cmd *cobra.Command, args []string) error {
+	cfgPath := config.ConfigPath(GetConfigPath())
+
+	fmt.Println()
+	fmt.Println("  ⚡ Sovereign Stack — Config Migrate")
+	fmt.Println("  ─────────────────────────────────")
+	fmt.Println()
+	fmt.Printf("  File: %s\n\n", cfgPath)
+
+	data, err := os.ReadFile(cfgPath)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	migrated, applied, err := config.Migrate(data)
+	if err != nil {
+		return err
+	}
+	if len(applied) == 0 {
+		fmt.Printf("  ✓ Config is already at version %d\n", config.CurrentVersion)
+		fmt.Println()
+		return nil
+	}
+
+	for _, m := range applied {
+		fmt.Printf("  v%d → v%d  %s\n", m.From, m.From+1, m.Description)
+	}
+	fmt.Println()
+
+	if configMigrateDryRun {
+		for _, line := range lineDiff(string(data), string(migrated)) {
+			fmt.Println("  " + line)
+		}
+		fmt.Println()
+		fmt.Println("  Dry run — nothing written. Run without --dry-run to apply.")
+		fmt.Println()
+		return nil
+	}
+
+	if _, err := config.MigrateFile(cfgPath); err != nil {
+		return err
+	}
+	auditLogger().Record(audit.CLIActor(), "config.migrate", "config/"+filepath.Base(cfgPath),
+		fmt.Sprintf("Migrated config from version %d to %d", applied[0].From, config.CurrentVersion), nil)
+
+	fmt.Printf("  ✓ Migrated to version %d (backup: %s.v%d.bak)\n", config.CurrentVersion, cfgPath, applied[0].From)
+	fmt.Println()
+	return nil
+}
+
+func runConfigShow(cmd *cobra.Command, args []string) error {
+	cfgPath := config.ConfigPath(GetConfigPath())
+
+	var eff *config.Effective
+	var err error
+	if configShowEffective {
+		eff, err = resolveConfig()
+	} else {
+		// File layer only: resolve without the environment or --set
+		eff, err = config.ResolveFile(cfgPath)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Println()
+	fmt.Println("  ⚡ Sovereign Stack — Config")
+	fmt.Println("  ─────────────────────────────────")
+	fmt.Println()
+	fmt.Printf("  File: %s\n\n", cfgPath)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	if configShowSource {
+		fmt.Fprintln(w, "  KEY\tVALUE\tSOURCE")
+		fmt.Fprintln(w, "  ───\t─────\t──────")
+	} else {
+		fmt.Fprintln(w, "  KEY\tVALUE")
+		fmt.Fprintln(w, "  ───\t─────")
+	}
+	for _, path := range config.Paths() {
+		v, err := config.Get(eff.Config, path)
+		if err != nil {
+			return err
+		}
+		value := formatConfigValue(path, v)
+		if configShowSource {
+			fmt.Fprintf(w, "  %s\t%s\t%s\n", path, value, eff.Origins[path])
+		} else {
+			fmt.Fprintf(w, "  %s\t%s\n", path, value)
+		}
+	}
+	w.Flush()
+	fmt.Println()
+	return nil
+}
+
+// formatConfigValue renders a config value on one line, hiding secrets
+func formatConfigValue(path string, v interface{}) string {
+	v = config.RedactValue(path, v)
+	switch val := v.(type) {
+	case string:
+		if val == "" {
+			return `""`
+		}
+		return val
+	case int, bool:
+		return fmt.Sprint(val)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// lineDiff returns a unified-style diff of two texts: changed lines prefixed
+// with - or +, with up to two lines of unchanged context around each change
+func lineDiff(a, b string) []string {
+	x := strings.Split(strings.TrimRight(a, "\n"), "\n")
+	y := strings.Split(strings.TrimRight(b, "\n"), "\n")
+
+	// Longest common subsequence table, filled from the end
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []string
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			ops = append(ops, "  "+x[i])
+			i++
+			j++
+		case i < len(x) && (j == len(y) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, "- "+x[i])
+			i++
+		default:
+			ops = append(ops, "+ "+y[j])
+			j++
+		}
+	}
+
+	const context = 2
+	keep := make([]bool, len(ops))
+	for k, op := range ops {
+		if op[0] == ' ' {
+			continue
+		}
+		for c := max(0, k-context); c <= min(len(ops)-1, k+context); c++ {
+			keep[c] = true
+		}
+	}
+
+	var out []string
+	for k, op := range ops {
+		if keep[k] {
+			out = append(out, op)
+		} else if k > 0 && keep[k-1] {
+			out = append(out, "  ...")
+		}
+	}
+	return out
+}
@@ -23,7 +23,7 @@ var dashboardPort string
 var dashboardStaticDir string
 
 func init() {
-	dashboardCmd.Flags().StringVarP(&dashboardPort, "port", "p", "8080", "Port to serve dashboard on")
+	dashboardCmd.Flags().StringVarP(&dashboardPort, "port", "p", "", "Port to serve dashboard on (default: port from config)")
 	dashboardCmd.Flags().StringVarP(&dashboardStaticDir, "static-dir", "s", "", "Path to built dashboard frontend (default: ~/.sovereign/dashboard)")
 	rootCmd.AddCommand(dashboardCmd)
 }
@@ -34,11 +34,29 @@ func runDashboard(cmd *cobra.Command, args []string) error {
 	fmt.Println("  ──────────────────────────────")
 	fmt.Println()
 
-	cfgPath := config.ConfigPath(GetConfigPath())
-	cfg := config.LoadOrDefault(cfgPath)
+	// Reloads resolve the same layers, including --set and --port
+	resolve := func() (*config.Effective, error) {
+		eff, err := resolveConfig()
+		if err != nil {
+			return nil, err
+		}
+		if cmd.Flags().Changed("port") {
+			if err := eff.SetFlag("port", dashboardPort, "--port"); err != nil {
+				return nil, err
+			}
+		}
+		return eff, nil
+	}
+	eff, err := resolve()
+	if err != nil {
+		return err
+	}
+	cfg := eff.Config
 
-	addr := "0.0.0.0:" + dashboardPort
+	addr := fmt.Sprintf("0.0.0.0:%d", cfg.Port)
 	srv := server.New(cfg, addr)
+	srv.SetConfigSource(config.ConfigPath(GetConfigPath()), eff.Origins)
+	srv.SetConfigResolver(resolve)
 
 	// Use --static-dir flag if provided, otherwise use config directory
 	staticDir := dashboardStaticDir
@@ -1,12 +1,15 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
-	"os/exec"
+	"path/filepath"
 	"strings"
 
 	"github.com/spf13/cobra"
 
+	"github.com/Achilles1089/sovereign-stack/internal/apps"
+	"github.com/Achilles1089/sovereign-stack/internal/audit"
 	"github.com/Achilles1089/sovereign-stack/internal/config"
 	"github.com/Achilles1089/sovereign-stack/internal/docker"
 	"github.com/Achilles1089/sovereign-stack/internal/hardware"
@@ -28,7 +31,10 @@ Behavior varies by platform:
 	RunE: runInit,
 }
 
+var initDryRun bool
+
 func init() {
+	initCmd.Flags().BoolVar(&initDryRun, "dry-run", false, "Detect and plan, but write nothing")
 	rootCmd.AddCommand(initCmd)
 }
 
@@ -88,7 +94,12 @@ func runInit(cmd *cobra.Command, args []string) error {
 	}
 
 	cfgPath := config.ConfigPath(GetConfigPath())
-	if err := cfg.Save(cfgPath); err != nil {
+	if initDryRun {
+		return planInit(cfg, cfgPath)
+	}
+	err = cfg.Save(cfgPath)
+	auditLogger().Record(audit.CLIActor(), "config.init", "config/"+filepath.Base(cfgPath), "Generated initial configuration", err)
+	if err != nil {
 		return fmt.Errorf("failed to save config: %w", err)
 	}
 	fmt.Printf("         Config saved to: %s\n", cfgPath)
@@ -96,9 +107,19 @@ func runInit(cmd *cobra.Command, args []string) error {
 
 	// Step 6: Generate Docker Compose + Caddyfile (always write, even without Docker)
 	fmt.Println("  [6/8] Generating Docker Compose...")
+	// Regenerating the core file would drop apps still defined in it, so
+	// they move to their own projects first
+	if _, err := apps.SplitLegacyCompose(); err != nil {
+		return err
+	}
+	// Before the compose file, which may still hold an older install's
+	// postgres password for the secret to take over
+	if err := docker.WriteCoreEnvFiles(cfg); err != nil {
+		return err
+	}
 	compose := docker.GenerateCoreCompose(cfg)
-	composePath := config.ConfigDir() + "/docker-compose.yml"
-	if err := docker.WriteComposeFile(compose, composePath); err != nil {
+	composePath := docker.CoreComposePath()
+	if err := docker.WriteComposeFile(compose, composePath, "init"); err != nil {
 		return fmt.Errorf("failed to write compose file: %w", err)
 	}
 	fmt.Printf("         Compose: %s\n", composePath)
@@ -140,13 +161,34 @@ func preflightChecks(pinfo *platform.Info, hw *config.HardwareProfile) error {
 		fmt.Println("         ⚠ Low RAM detected (< 2 GB). Performance may be limited.")
 	}
 	if pinfo.Platform == platform.PlatformLinux && !pinfo.IsRoot {
+		if initDryRun {
+			fmt.Println("         ⚠ Not running as root; a real init needs sudo.")
+			return nil
+		}
 		return fmt.Errorf("sovereign init requires root privileges on Linux. Run with: sudo sovereign init")
 	}
 	return nil
 }
 
+// planInit shows what init would write for cfg, without writing it
+func planInit(cfg *config.Config, cfgPath string) error {
+	fmt.Printf("         Config would be saved to: %s\n", cfgPath)
+	fmt.Println()
+
+	composePath := docker.CoreComposePath()
+	current, err := docker.LoadComposeOrEmpty(composePath)
+	if err != nil {
+		return err
+	}
+	fmt.Println("  [6/8] Planning Docker Compose...")
+	fmt.Println()
+	printPlan(docker.Diff(composePath, current, docker.GenerateCoreCompose(cfg)))
+	printDryRunFooter()
+	return nil
+}
+
 func checkDocker(pinfo *platform.Info) error {
-	_, err := exec.Command("docker", "version").Output()
+	_, err := docker.Run(context.Background(), nil, nil, "version")
 	if err != nil {
 		if pinfo.NeedsDockerDesktop() {
 			fmt.Println("         ✗ Docker Desktop not found.")
@@ -5,6 +5,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/Achilles1089/sovereign-stack/internal/audit"
 	"github.com/Achilles1089/sovereign-stack/internal/mesh"
 )
 
@@ -71,6 +72,7 @@ func runMeshCreate(cmd *cobra.Command, args []string) error {
 	}
 
 	cfg, token, err := mesh.CreateNetwork(name)
+	auditLogger().LogMeshEvent(audit.CLIActor(), "create", name, err)
 	if err != nil {
 		return fmt.Errorf("failed to create network: %w", err)
 	}
@@ -98,8 +100,10 @@ func runMeshJoin(cmd *cobra.Command, args []string) error {
 
 	cfg, err := mesh.JoinNetwork(args[0])
 	if err != nil {
+		auditLogger().LogMeshEvent(audit.CLIActor(), "join", "unknown", err)
 		return fmt.Errorf("failed to join: %w", err)
 	}
+	auditLogger().LogMeshEvent(audit.CLIActor(), "join", cfg.NetworkName, nil)
 
 	fmt.Printf("  ✓ Joined mesh: %s\n", cfg.NetworkName)
 	fmt.Printf("  Your IP: %s\n", cfg.LocalPeer.MeshIP)
@@ -159,7 +163,12 @@ func runMeshLeave(cmd *cobra.Command, args []string) error {
 	fmt.Println()
 	fmt.Println("  Disconnecting from mesh...")
 
-	mesh.InterfaceDown()
+	network := "sovereign-mesh"
+	if cfg, err := mesh.LoadConfig(); err == nil {
+		network = cfg.NetworkName
+	}
+	err := mesh.InterfaceDown()
+	auditLogger().LogMeshEvent(audit.CLIActor(), "leave", network, err)
 
 	// Remove config
 	fmt.Println("  ✓ Mesh interface down")
@@ -3,12 +3,16 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"os/exec"
+	"sort"
 	"strings"
+	"text/tabwriter"
 
 	"github.com/spf13/cobra"
 
-	"github.com/Achilles1089/sovereign-stack/internal/config"
+	"github.com/Achilles1089/sovereign-stack/internal/apps"
+	"github.com/Achilles1089/sovereign-stack/internal/audit"
+	"github.com/Achilles1089/sovereign-stack/internal/docker"
+	"github.com/Achilles1089/sovereign-stack/internal/rbac"
 )
 
 var logsCmd = &cobra.Command{
@@ -25,6 +29,8 @@ Example:
 
 var logsFollow bool
 
+var updateDryRun bool
+
 var restartCmd = &cobra.Command{
 	Use:   "restart [service]",
 	Short: "Restart a service or all services",
@@ -46,49 +52,58 @@ and recreate containers with the updated images.`,
 
 func init() {
 	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Follow log output")
+	updateCmd.Flags().BoolVar(&updateDryRun, "dry-run", false, "Show the images and containers affected without updating")
 	rootCmd.AddCommand(logsCmd)
 	rootCmd.AddCommand(restartCmd)
 	rootCmd.AddCommand(updateCmd)
 }
 
-func composeFile() string {
-	return config.ConfigDir() + "/docker-compose.yml"
-}
-
 func runLogs(cmd *cobra.Command, args []string) error {
 	service := args[0]
+	if err := authorize(rbac.PermServiceLogs, serviceResource(service)); err != nil {
+		return err
+	}
 
-	composeArgs := []string{"compose", "-f", composeFile(), "logs", service}
+	composeArgs := []string{"logs", service}
 	if logsFollow {
 		composeArgs = append(composeArgs, "-f")
 	} else {
 		composeArgs = append(composeArgs, "--tail", "100")
 	}
 
-	proc := exec.Command("docker", composeArgs...)
-	proc.Stdout = os.Stdout
-	proc.Stderr = os.Stderr
-	return proc.Run()
+	_, err := docker.Compose(cmd.Context(), docker.ProjectFileFor(service), os.Stdout, os.Stderr, composeArgs...)
+	return err
 }
 
 func runRestart(cmd *cobra.Command, args []string) error {
+	res := rbac.Global
+	if len(args) > 0 {
+		res = serviceResource(args[0])
+	}
+	if err := authorize(rbac.PermServiceStart, res); err != nil {
+		return err
+	}
+
 	fmt.Println()
 	fmt.Println("  ⚡ Sovereign Stack — Restart")
 	fmt.Println("  ────────────────────────────")
 	fmt.Println()
 
-	composeArgs := []string{"compose", "-f", composeFile(), "restart"}
+	target := "all"
+	var err error
 	if len(args) > 0 {
-		composeArgs = append(composeArgs, args[0])
-		fmt.Printf("  Restarting %s...\n", args[0])
+		target = args[0]
+		fmt.Printf("  Restarting %s...\n", target)
+		_, err = docker.Compose(cmd.Context(), docker.ProjectFileFor(target), os.Stdout, os.Stderr, "restart", target)
 	} else {
 		fmt.Println("  Restarting all services...")
+		err = eachProject(func(path string) error {
+			_, err := docker.Compose(cmd.Context(), path, os.Stdout, os.Stderr, "restart")
+			return err
+		})
 	}
-
-	proc := exec.Command("docker", composeArgs...)
-	proc.Stdout = os.Stdout
-	proc.Stderr = os.Stderr
-	if err := proc.Run(); err != nil {
+	auditLogger().Record(audit.CLIActor(), "service.restart", "service/"+target, fmt.Sprintf("Restarted %s", target), err)
+	if err != nil {
 		return fmt.Errorf("restart failed: %w", err)
 	}
 
@@ -99,38 +114,54 @@ func runRestart(cmd *cobra.Command, args []string) error {
 }
 
 func runUpdate(cmd *cobra.Command, args []string) error {
+	if err := authorize(rbac.PermServiceStart, rbac.Global); err != nil {
+		return err
+	}
+
 	fmt.Println()
 	fmt.Println("  ⚡ Sovereign Stack — Update")
 	fmt.Println("  ───────────────────────────")
 	fmt.Println()
 
+	if updateDryRun {
+		return planUpdate(cmd)
+	}
+
+	// Apps from before each had its own project move out of the core file;
+	// recreating below moves their containers too
+	if moved, err := apps.SplitLegacyCompose(); err != nil {
+		return err
+	} else if len(moved) > 0 {
+		fmt.Printf("  Moved %s to their own compose projects\n\n", strings.Join(moved, ", "))
+	}
+
 	// Pull latest images
 	fmt.Println("  [1/3] Pulling latest images...")
-	pull := exec.Command("docker", "compose", "-f", composeFile(), "pull")
-	pull.Stdout = os.Stdout
-	pull.Stderr = os.Stderr
-	if err := pull.Run(); err != nil {
+	err := eachProject(func(path string) error {
+		return docker.ComposePull(cmd.Context(), path, os.Stdout, os.Stderr)
+	})
+	if err != nil {
+		auditLogger().Record(audit.CLIActor(), "service.update", "service/all", "Updated all services", err)
 		return fmt.Errorf("pull failed: %w", err)
 	}
 
-	// Recreate containers
+	// Recreate containers, core first so apps can join its network
 	fmt.Println()
 	fmt.Println("  [2/3] Recreating containers...")
-	up := exec.Command("docker", "compose", "-f", composeFile(), "up", "-d", "--remove-orphans")
-	up.Stdout = os.Stdout
-	up.Stderr = os.Stderr
-	if err := up.Run(); err != nil {
+	err = eachProject(func(path string) error {
+		return docker.ComposeUp(cmd.Context(), path, os.Stdout, os.Stderr, "--remove-orphans")
+	})
+	auditLogger().Record(audit.CLIActor(), "service.update", "service/all", "Updated all services", err)
+	if err != nil {
 		return fmt.Errorf("recreate failed: %w", err)
 	}
 
 	// Cleanup old images
 	fmt.Println()
 	fmt.Println("  [3/3] Cleaning up old images...")
-	prune := exec.Command("docker", "image", "prune", "-f")
-	out, _ := prune.Output()
-	if len(out) > 0 {
+	if res, err := docker.Run(cmd.Context(), nil, nil, "image", "prune", "-f"); err == nil {
 		// Show space reclaimed
-		for _, line := range strings.Split(string(out), "\n") {
+		for _, line := range strings.Split(string(res.Stdout), "\n") {
 			if strings.Contains(line, "reclaimed") {
 				fmt.Printf("         %s\n", strings.TrimSpace(line))
 			}
@@ -142,3 +173,73 @@ func runUpdate(cmd *cobra.Command, args []string) error {
 	fmt.Println()
 	return nil
 }
+
+// eachProject runs fn on the core compose project, then on each app's,
+// stopping at the first error
+func eachProject(fn func(path string) error) error {
+	files, err := docker.ProjectFiles()
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no compose files found; run 'sovereign init' first")
+	}
+	for _, path := range files {
+		if err := fn(path); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// planUpdate shows the images update would pull and the orphaned containers
+// it would remove
+func planUpdate(cmd *cobra.Command) error {
+	files, err := docker.ProjectFiles()
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no compose files found; run 'sovereign init' first")
+	}
+
+	var composes []*docker.ComposeFile
+	services := make(map[string]*docker.ComposeService)
+	for _, path := range files {
+		compose, err := docker.LoadComposeFile(path)
+		if err != nil {
+			return err
+		}
+		composes = append(composes, compose)
+		for name, svc := range compose.Services {
+			services[name] = svc
+		}
+	}
+
+	names := make([]string, 0, len(services))
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Println("  Images to pull:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, name := range names {
+		fmt.Fprintf(w, "    %s\t%s\n", name, services[name].Image)
+	}
+	w.Flush()
+	fmt.Println()
+
+	engine, err := docker.DefaultEngine()
+	if err != nil {
+		return err
+	}
+	plan, err := docker.PlanOrphans(cmd.Context(), engine, docker.CoreComposePath(), composes...)
+	if err != nil {
+		fmt.Printf("  ⚠ Could not check for orphaned containers: %v\n\n", err)
+	} else {
+		printPlan(plan)
+	}
+	printDryRunFooter()
+	return nil
+}
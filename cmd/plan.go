@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/Achilles1089/sovereign-stack/internal/apps"
+	"github.com/Achilles1089/sovereign-stack/internal/docker"
+)
+
+// printPlan shows the compose changes a command would make
+func printPlan(plan *docker.Plan) {
+	fmt.Printf("  Compose: %s\n\n", plan.Path)
+	if plan.Empty() {
+		fmt.Println("  No changes.")
+		fmt.Println()
+		return
+	}
+
+	marks := map[string]string{docker.ActionAdd: "+", docker.ActionChange: "~", docker.ActionRemove: "-"}
+	for _, c := range plan.Changes {
+		fmt.Printf("  %s %s %s\n", marks[c.Action], c.Kind, c.Name)
+		for _, f := range c.Fields {
+			switch {
+			case c.Action == docker.ActionAdd:
+				fmt.Printf("      %s: %s\n", f.Field, f.New)
+			case f.Old == "":
+				fmt.Printf("      + %s: %s\n", f.Field, f.New)
+			case f.New == "":
+				fmt.Printf("      - %s: %s\n", f.Field, f.Old)
+			default:
+				fmt.Printf("      ~ %s: %s → %s\n", f.Field, f.Old, f.New)
+			}
+		}
+	}
+	fmt.Println()
+	fmt.Printf("  Plan: %s\n", plan.Summary())
+	fmt.Println()
+}
+
+// printDryRunFooter ends a dry run
+func printDryRunFooter() {
+	fmt.Println("  Dry run — nothing written. Run without --dry-run to apply.")
+	fmt.Println()
+}
+
+// printPreflight shows the result of each preflight check
+func printPreflight(pre *apps.Preflight) {
+	marks := map[string]string{apps.CheckOK: "✓", apps.CheckWarn: "⚠", apps.CheckFail: "✗"}
+	fmt.Println("  Preflight:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, c := range pre.Checks {
+		fmt.Fprintf(w, "    %s %s\t%s\n", marks[c.Status], c.Name, c.Detail)
+	}
+	w.Flush()
+	fmt.Println()
+}
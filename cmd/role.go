@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Achilles1089/sovereign-stack/internal/rbac"
+)
+
+var roleCmd = &cobra.Command{
+	Use:   "role",
+	Short: "Inspect RBAC roles",
+	Long:  `List built-in and custom roles. Custom roles are defined under "roles" in ~/.sovereign/rbac.json.`,
+}
+
+var roleListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List roles and their permissions",
+	RunE:  runRoleList,
+}
+
+func init() {
+	roleCmd.AddCommand(roleListCmd)
+	rootCmd.AddCommand(roleCmd)
+}
+
+func runRoleList(cmd *cobra.Command, args []string) error {
+	cfg, err := rbac.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	descriptions := make(map[rbac.Role]string)
+	for _, d := range cfg.Roles {
+		descriptions[d.Name] = d.Description
+	}
+
+	fmt.Println()
+	fmt.Println("  ⚡ Sovereign Stack — Roles")
+	fmt.Println("  ─────────────────────────────────────")
+	fmt.Println()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  ROLE\tTYPE\tPERMISSIONS")
+	fmt.Fprintln(w, "  ────\t────\t───────────")
+
+	for _, role := range rbac.AvailableRoles() {
+		kind := "custom"
+		if rbac.IsBuiltinRole(role) {
+			kind = "built-in"
+		}
+
+		perms := rbac.GetPermissions(role)
+		names := make([]string, len(perms))
+		for i, p := range perms {
+			names[i] = string(p)
+		}
+
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", role, kind, strings.Join(names, ", "))
+		for _, g := range rbac.GetGrants(role) {
+			fmt.Fprintf(w, "  \t\t%s on %s\n", g.Permission, strings.Join(g.Resources, ", "))
+		}
+		if desc := descriptions[role]; desc != "" {
+			fmt.Fprintf(w, "  \t\t%s\n", desc)
+		}
+	}
+	w.Flush()
+
+	fmt.Println()
+	return nil
+}
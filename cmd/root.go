@@ -3,16 +3,21 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
+
+	"github.com/Achilles1089/sovereign-stack/internal/audit"
+	"github.com/Achilles1089/sovereign-stack/internal/config"
 )
 
 // Version is set at build time via ldflags
 var Version = "dev"
 
 var (
-	verbose    bool
-	configPath string
+	verbose         bool
+	configPath      string
+	configOverrides []string
 )
 
 var rootCmd = &cobra.Command{
@@ -39,7 +44,13 @@ var rootCmd = &cobra.Command{
 }
 
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	audit.ProductVersion = Version
+	err := rootCmd.Execute()
+
+	// Give configured audit sinks a moment to forward this command's events
+	audit.Flush(5 * time.Second)
+
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
@@ -48,6 +59,7 @@ func Execute() {
 func init() {
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
 	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "Config file path (default: ~/.sovereign/config.yaml)")
+	rootCmd.PersistentFlags().StringArrayVar(&configOverrides, "set", nil, "Override a config value for this run, e.g. --set ai.rag_host=10.0.0.5:8093 (repeatable)")
 
 	// Add version command
 	rootCmd.AddCommand(&cobra.Command{
@@ -68,3 +80,28 @@ func GetVerbose() bool {
 func GetConfigPath() string {
 	return configPath
 }
+
+// resolveConfig layers defaults < config file < SOVEREIGN_* env vars < --set flags
+func resolveConfig() (*config.Effective, error) {
+	return config.Resolve(config.ConfigPath(GetConfigPath()), configOverrides)
+}
+
+// loadConfig returns the effective config for subcommands
+func loadConfig() (*config.Config, error) {
+	eff, err := resolveConfig()
+	if err != nil {
+		return nil, err
+	}
+	return eff.Config, nil
+}
+
+// auditLogger returns an audit logger configured from the effective config,
+// so --config, SOVEREIGN_* env vars and --set apply to auditing too. The
+// event is still recorded, with the file's settings, if overrides are invalid.
+func auditLogger() *audit.Logger {
+	eff, err := resolveConfig()
+	if err != nil {
+		return audit.NewLoggerFromConfig(config.LoadOrDefault(config.ConfigPath(GetConfigPath())))
+	}
+	return audit.NewLoggerFromConfig(eff.Config)
+}
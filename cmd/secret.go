@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/Achilles1089/sovereign-stack/internal/audit"
+	"github.com/Achilles1089/sovereign-stack/internal/rbac"
+	"github.com/Achilles1089/sovereign-stack/internal/secrets"
+)
+
+var secretCmd = &cobra.Command{
+	Use:   "secret",
+	Short: "Manage encrypted secrets",
+	Long: `Passwords and keys are kept encrypted under ~/.sovereign/secrets instead of
+in config.yaml or the compose file. Config values refer to them as
+secret://<name>, e.g. backup.password: secret://backup/password.
+
+The store is encrypted with a random key in ~/.sovereign/secrets/.key, or
+with a key derived from $SOVEREIGN_SECRETS_PASSPHRASE when that is set.
+
+App credentials (apps/<app>/...) and the shared PostgreSQL password
+(postgres/password) are generated on install. Deployments created by older
+releases used the password "sovereign" for PostgreSQL; record it with
+'sovereign secret set postgres/password' before reinstalling apps.`,
+}
+
+var secretListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List stored secrets (names only)",
+	RunE:  runSecretList,
+}
+
+var secretSetCmd = &cobra.Command{
+	Use:   "set <name>",
+	Short: "Store a secret, read from the terminal or stdin",
+	Long: `Store a secret. The value is prompted for on a terminal, or read as one
+line from stdin.
+
+Example:
+  sovereign secret set backup/password
+  echo "$TOKEN" | sovereign secret set webhook/token`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSecretSet,
+}
+
+var secretGenerateCmd = &cobra.Command{
+	Use:   "generate <name>",
+	Short: "Store a new random secret",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runSecretGenerate,
+}
+
+var secretGetCmd = &cobra.Command{
+	Use:   "get <name>",
+	Short: "Print a secret's value",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runSecretGet,
+}
+
+var secretRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Delete a secret",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runSecretRemove,
+}
+
+var secretLength int
+
+func init() {
+	secretGenerateCmd.Flags().IntVar(&secretLength, "length", secrets.DefaultLength, "Length of the generated value")
+
+	secretCmd.AddCommand(secretListCmd)
+	secretCmd.AddCommand(secretSetCmd)
+	secretCmd.AddCommand(secretGenerateCmd)
+	secretCmd.AddCommand(secretGetCmd)
+	secretCmd.AddCommand(secretRemoveCmd)
+	rootCmd.AddCommand(secretCmd)
+}
+
+func runSecretList(cmd *cobra.Command, args []string) error {
+	if err := authorize(rbac.PermConfigRead, rbac.Global); err != nil {
+		return err
+	}
+	store := secrets.Default()
+	names, err := store.List()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println()
+	fmt.Println("  ⚡ Sovereign Stack — Secrets")
+	fmt.Println("  ────────────────────────────")
+	fmt.Println()
+
+	if len(names) == 0 {
+		fmt.Println("  No secrets stored.")
+		fmt.Println()
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "  NAME\tREFERENCE")
+	fmt.Fprintln(w, "  ────\t─────────")
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s\t%s\n", name, secrets.Ref(name))
+	}
+	w.Flush()
+	fmt.Printf("\n  Store: %s\n\n", store.Dir())
+	return nil
+}
+
+func runSecretSet(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if err := authorize(rbac.PermConfigWrite, rbac.Global); err != nil {
+		return err
+	}
+	if err := secrets.ValidateName(name); err != nil {
+		return err
+	}
+
+	value, err := readSecretValue()
+	if err != nil {
+		return err
+	}
+	if value == "" {
+		return fmt.Errorf("secret value must not be empty")
+	}
+
+	err = secrets.Default().Set(name, value)
+	auditLogger().Record(audit.CLIActor(), "secret.set", "secret/"+name, fmt.Sprintf("Stored secret %s", name), err)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\n  ✓ Stored %s — reference it as %s\n\n", name, secrets.Ref(name))
+	return nil
+}
+
+func runSecretGenerate(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if err := authorize(rbac.PermConfigWrite, rbac.Global); err != nil {
+		return err
+	}
+	if err := secrets.ValidateName(name); err != nil {
+		return err
+	}
+	if secretLength < 8 {
+		return fmt.Errorf("--length must be at least 8")
+	}
+
+	value, err := secrets.Generate(secretLength)
+	if err == nil {
+		err = secrets.Default().Set(name, value)
+	}
+	auditLogger().Record(audit.CLIActor(), "secret.generate", "secret/"+name, fmt.Sprintf("Generated secret %s", name), err)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\n  ✓ Generated %s (%d characters) — reference it as %s\n\n", name, secretLength, secrets.Ref(name))
+	return nil
+}
+
+func runSecretGet(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if err := authorize(rbac.PermConfigWrite, rbac.Global); err != nil {
+		return err
+	}
+	value, err := secrets.Default().Get(name)
+	auditLogger().Record(audit.CLIActor(), "secret.read", "secret/"+name, fmt.Sprintf("Read secret %s", name), err)
+	if err != nil {
+		return err
+	}
+	fmt.Println(value)
+	return nil
+}
+
+func runSecretRemove(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if err := authorize(rbac.PermConfigWrite, rbac.Global); err != nil {
+		return err
+	}
+	err := secrets.Default().Delete(name)
+	auditLogger().Record(audit.CLIActor(), "secret.remove", "secret/"+name, fmt.Sprintf("Removed secret %s", name), err)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\n  ✓ Removed %s\n\n", name)
+	return nil
+}
+
+// readSecretValue prompts twice on a terminal, or reads one line from stdin
+func readSecretValue() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("failed to read secret from stdin: %w", err)
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	fmt.Print("  Value: ")
+	first, err := term.ReadPassword(fd)
+	fmt.Println()
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Print("  Confirm value: ")
+	second, err := term.ReadPassword(fd)
+	fmt.Println()
+	if err != nil {
+		return "", err
+	}
+
+	if string(first) != string(second) {
+		return "", fmt.Errorf("values do not match")
+	}
+	return string(first), nil
+}
@@ -3,12 +3,10 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"text/tabwriter"
 
 	"github.com/spf13/cobra"
 
-	"github.com/Achilles1089/sovereign-stack/internal/config"
 	dockerPkg "github.com/Achilles1089/sovereign-stack/internal/docker"
 )
 
@@ -29,8 +27,10 @@ func runStatus(cmd *cobra.Command, args []string) error {
 	fmt.Println("  ───────────────────────────")
 	fmt.Println()
 
-	cfgPath := config.ConfigPath(GetConfigPath())
-	cfg := config.LoadOrDefault(cfgPath)
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
 
 	if !dockerPkg.IsDockerAvailable() {
 		fmt.Println("  ⚠  Docker is not running.")
@@ -53,15 +53,19 @@ func runStatus(cmd *cobra.Command, args []string) error {
 
 	// Core services
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "  SERVICE\tSTATUS\tIMAGE\tPORTS")
-	fmt.Fprintln(w, "  ───────\t──────\t─────\t─────")
+	fmt.Fprintln(w, "  SERVICE\tSTATUS\tHEALTH\tRESTARTS\tIMAGE\tPORTS")
+	fmt.Fprintln(w, "  ───────\t──────\t──────\t────────\t─────\t─────")
 
 	for _, s := range services {
 		status := "[DOWN] Down"
 		if s.Running {
-			status = "[UP] Up"
+			status = "[UP] Up " + s.Uptime
 		}
-		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", s.Name, status, s.Image, s.Ports)
+		health := s.Health
+		if health == "none" {
+			health = "-"
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%d\t%s\t%s\n", s.Name, status, health, s.RestartCount, s.Image, s.Ports)
 	}
 	w.Flush()
 
@@ -94,9 +98,6 @@ func runStatus(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	out, _ := exec.Command("docker", "compose", "-f", config.ConfigDir()+"/docker-compose.yml", "ps", "--format", "json").Output()
-	_ = out // For future use
-
 	fmt.Printf("  %d/%d services running\n", running, len(services))
 	fmt.Println()
 	return nil
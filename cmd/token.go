@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Achilles1089/sovereign-stack/internal/audit"
+	"github.com/Achilles1089/sovereign-stack/internal/tokens"
+)
+
+var (
+	tokenUser        string
+	tokenPermissions string
+	tokenExpires     time.Duration
+)
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manage personal API tokens",
+	Long:  `Create, list and revoke API tokens for scripts and the iOS app. Send them as "Authorization: Bearer <token>".`,
+}
+
+var tokenCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create an API token",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runTokenCreate,
+}
+
+var tokenListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List API tokens",
+	RunE:  runTokenList,
+}
+
+var tokenRevokeCmd = &cobra.Command{
+	Use:   "revoke <id>",
+	Short: "Revoke an API token",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runTokenRevoke,
+}
+
+func init() {
+	tokenCreateCmd.Flags().StringVar(&tokenUser, "user", "admin", "User the token acts as")
+	tokenCreateCmd.Flags().StringVar(&tokenPermissions, "permissions", "", "Comma-separated permissions to limit the token to (default: all of the user's)")
+	tokenCreateCmd.Flags().DurationVar(&tokenExpires, "expires", 0, "Lifetime, e.g. 720h (default: never expires)")
+	tokenListCmd.Flags().StringVar(&tokenUser, "user", "", "Only show tokens for this user")
+
+	tokenCmd.AddCommand(tokenCreateCmd)
+	tokenCmd.AddCommand(tokenListCmd)
+	tokenCmd.AddCommand(tokenRevokeCmd)
+	rootCmd.AddCommand(tokenCmd)
+}
+
+func runTokenCreate(cmd *cobra.Command, args []string) error {
+	perms, err := tokens.ParsePermissions(tokenPermissions)
+	if err != nil {
+		return err
+	}
+
+	raw, tok, err := tokens.Create(tokenUser, args[0], perms, tokenExpires)
+	if err != nil {
+		return err
+	}
+	auditLogger().LogTokenEvent(audit.CLIActor(), "create", tok.ID, tok.Name)
+
+	fmt.Println()
+	fmt.Printf("  ✓ Token %s created for %s (id %s)\n", tok.Name, tok.Username, tok.ID)
+	fmt.Println()
+	fmt.Printf("  %s\n", raw)
+	fmt.Println()
+	fmt.Println("  Copy it now — it will not be shown again.")
+	fmt.Println()
+	return nil
+}
+
+func runTokenList(cmd *cobra.Command, args []string) error {
+	list, err := tokens.List(tokenUser)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println()
+	fmt.Println("  ⚡ Sovereign Stack — API Tokens")
+	fmt.Println("  ─────────────────────────────────────")
+	fmt.Println()
+
+	if len(list) == 0 {
+		fmt.Println("  No tokens. Create one with: sovereign token create <name>")
+		fmt.Println()
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  ID\tNAME\tUSER\tSCOPE\tEXPIRES\tLAST USED")
+	fmt.Fprintln(w, "  ──\t────\t────\t─────\t───────\t─────────")
+
+	for _, t := range list {
+		scope := "all"
+		if len(t.Permissions) > 0 {
+			names := make([]string, len(t.Permissions))
+			for i, p := range t.Permissions {
+				names[i] = string(p)
+			}
+			scope = strings.Join(names, ",")
+		}
+
+		expires := "never"
+		if t.ExpiresAt != nil {
+			expires = t.ExpiresAt.Local().Format("2006-01-02 15:04")
+			if t.Expired() {
+				expires += " (expired)"
+			}
+		}
+
+		lastUsed := "never"
+		if t.LastUsedAt != nil {
+			lastUsed = t.LastUsedAt.Local().Format("2006-01-02 15:04")
+		}
+
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\t%s\n", t.ID, t.Name, t.Username, scope, expires, lastUsed)
+	}
+	w.Flush()
+
+	fmt.Println()
+	return nil
+}
+
+func runTokenRevoke(cmd *cobra.Command, args []string) error {
+	tok, err := tokens.Get(args[0])
+	if err != nil {
+		return err
+	}
+	if err := tokens.Revoke(tok.ID); err != nil {
+		return err
+	}
+	auditLogger().LogTokenEvent(audit.CLIActor(), "revoke", tok.ID, tok.Name)
+
+	fmt.Printf("\n  ✓ Token %s (%s) revoked\n\n", tok.Name, tok.ID)
+	return nil
+}
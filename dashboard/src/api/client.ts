@@ -4,8 +4,67 @@ export interface ServiceStatus {
     name: string;
     running: boolean;
     status: string;
+    health: 'healthy' | 'unhealthy' | 'starting' | 'none';
+    restart_count: number;
+    started_at?: string;
+    uptime: string;
     ports: string;
     image: string;
+    is_app: boolean;
+    app_name: string;
+}
+
+export interface ComposeFieldChange {
+    field: string;
+    old?: string;
+    new?: string;
+}
+
+export interface ComposeChange {
+    kind: 'service' | 'volume' | 'network' | 'container';
+    name: string;
+    action: 'add' | 'change' | 'remove';
+    fields?: ComposeFieldChange[];
+}
+
+export interface ComposePlan {
+    path: string;
+    changes: ComposeChange[];
+}
+
+export interface PreflightCheck {
+    name: string; // e.g. "service postgres", "port 8080/tcp", "disk"
+    status: 'ok' | 'warn' | 'fail';
+    detail: string;
+}
+
+export interface Preflight {
+    app: string;
+    checks: PreflightCheck[];
+    enable?: string[]; // disabled core services the install enables
+}
+
+export interface PortMapping {
+    container_port: number;
+    protocol: 'tcp' | 'udp';
+    default_port: number; // host port the manifest asks for
+    host_port: number; // host port actually used
+}
+
+export interface AppChangeResult {
+    ok?: boolean;
+    error?: string;
+    message?: string;
+    dry_run?: boolean;
+    plan?: ComposePlan;
+    preflight?: Preflight; // installs only
+    ports?: PortMapping[]; // installs only
+    url?: string; // web UI of the installed app
+    install?: string[]; // dry runs: apps to install, dependencies first
+    installed?: string[];
+    dependencies?: Preflight[]; // preflights of the apps installed first
+    remove?: string[]; // dry runs: apps to remove, dependents first
+    removed?: string[];
 }
 
 export interface SystemResources {
@@ -26,6 +85,7 @@ export interface AppInfo {
     category: string;
     version: string;
     installed: boolean;
+    url?: string; // web UI, installed apps only
 }
 
 export interface AIModel {
@@ -134,6 +194,89 @@ export interface AgentStatus {
     memory_messages: number;
 }
 
+export interface CurrentUser {
+    username: string;
+    role: string;
+    email?: string;
+    permissions: string[];
+    grants?: Array<{ permission: string; resources: string[] }>;
+}
+
+export interface APIToken {
+    id: string;
+    name: string;
+    username: string;
+    permissions?: string[];
+    created_at: string;
+    expires_at?: string;
+    last_used_at?: string;
+}
+
+export interface AuditEvent {
+    id: string;
+    timestamp: string;
+    action: string;
+    actor: string;
+    target: string;
+    details: string;
+    severity: 'info' | 'warning' | 'critical';
+    success: boolean;
+    source_ip?: string;
+    error?: string;
+    prev_hash?: string;
+    hash?: string;
+}
+
+export interface AuditQuery {
+    since?: string;
+    until?: string;
+    actor?: string;
+    target?: string;
+    severity?: string;
+    success?: boolean;
+    action?: string;
+    limit?: number;
+    cursor?: string;
+    order?: 'asc' | 'desc';
+}
+
+export interface AuditSinkStatus {
+    name: string;
+    queued: number;
+    dropped: number;
+    last_error?: string;
+}
+
+export type ConfigSource = 'default' | 'file' | 'env' | 'flag';
+
+export interface ConfigResponse {
+    path: string;
+    writable: boolean;
+    values: Record<string, unknown>; // keyed by YAML path, e.g. "ai.rag_host"; secrets are "********"
+    sources: Record<string, ConfigSource>;
+    live: string[]; // paths that apply without a restart
+}
+
+export interface ConfigPatchResult {
+    changed?: string[];
+    applied?: string[];
+    restart_required?: string[];
+    overridden?: string[];
+    errors?: string[];
+    error?: string;
+    problems?: { path: string; line?: number; message: string }[];
+}
+
+export interface ConfigReloadStatus {
+    time: string;
+    trigger: 'file' | 'signal';
+    success: boolean;
+    error?: string;
+    changed: string[];
+    applied: string[];
+    restart_required?: string[];
+}
+
 async function fetchJSON<T>(path: string): Promise<T> {
     const res = await fetch(API_BASE + path);
     if (!res.ok) throw new Error(`API error: ${res.status}`);
@@ -141,6 +284,41 @@ async function fetchJSON<T>(path: string): Promise<T> {
 }
 
 export const api = {
+    login: async (username: string, password: string): Promise<{ ok?: boolean; user?: CurrentUser; expires_at?: string; error?: string }> => {
+        const res = await fetch(API_BASE + '/auth/login', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ username, password }),
+        });
+        return res.json();
+    },
+    logout: () => fetch(API_BASE + '/auth/logout', { method: 'POST' }).then(r => r.json()),
+    getMe: () => fetchJSON<{ rbac_enabled: boolean; user: CurrentUser }>('/auth/me'),
+    getAuditEvents: (query: AuditQuery = {}) => {
+        const params = new URLSearchParams();
+        Object.entries(query).forEach(([k, v]) => { if (v !== undefined && v !== '') params.set(k, String(v)); });
+        return fetchJSON<{ events: AuditEvent[]; next_cursor?: string }>(`/audit?${params}`);
+    },
+    getAuditSinks: () => fetchJSON<{ sinks: AuditSinkStatus[] }>('/audit/sinks'),
+    getConfig: () => fetchJSON<ConfigResponse>('/config'),
+    getConfigStatus: () => fetchJSON<{ path: string; watching: boolean; last_reload: ConfigReloadStatus | null }>('/config/status'),
+    updateConfig: (values: Record<string, unknown>): Promise<ConfigPatchResult> => fetch(API_BASE + '/config', {
+        method: 'PATCH',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ values }),
+    }).then(r => r.json()),
+    getTokens: () => fetchJSON<{ tokens: APIToken[] }>('/tokens'),
+    createToken: (name: string, permissions: string[] = [], expiresIn = ''): Promise<{ token?: string; info?: APIToken; error?: string }> => fetch(API_BASE + '/tokens', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ name, permissions, expires_in: expiresIn }),
+    }).then(r => r.json()),
+    revokeToken: (id: string) => fetch(API_BASE + '/tokens/revoke', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ id }),
+    }).then(r => r.json()),
+
     getStatus: () => fetchJSON<{ services: ServiceStatus[] }>('/status'),
     getResources: () => fetchJSON<SystemResources>('/resources'),
     getApps: () => fetchJSON<{ apps: AppInfo[] }>('/apps'),
@@ -163,15 +341,16 @@ export const api = {
         body: JSON.stringify({ model: model || '' }),
     }).then(r => r.json()),
 
-    installApp: (name: string) => fetch(API_BASE + '/apps/install', {
+    // dryRun returns the compose plan without changing anything, for confirmation
+    installApp: (name: string, dryRun = false, allocatePorts = false): Promise<AppChangeResult> => fetch(API_BASE + '/apps/install', {
         method: 'POST',
         headers: { 'Content-Type': 'application/json' },
-        body: JSON.stringify({ name }),
+        body: JSON.stringify({ name, dry_run: dryRun, allocate_ports: allocatePorts }),
     }).then(r => r.json()),
-    removeApp: (name: string) => fetch(API_BASE + '/apps/remove', {
+    removeApp: (name: string, dryRun = false, cascade = false): Promise<AppChangeResult> => fetch(API_BASE + '/apps/remove', {
         method: 'POST',
         headers: { 'Content-Type': 'application/json' },
-        body: JSON.stringify({ name }),
+        body: JSON.stringify({ name, dry_run: dryRun, cascade }),
     }).then(r => r.json()),
 
     generateImage: async (prompt: string, width = 512, height = 512): Promise<ImageGenResponse> => {
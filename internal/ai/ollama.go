@@ -2,6 +2,7 @@ package ai
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -12,8 +13,22 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/Achilles1089/sovereign-stack/internal/config"
+	"github.com/Achilles1089/sovereign-stack/internal/runner"
 )
 
+// run runs helper commands such as pkill; SetRunner replaces it in tests
+var run = runner.Default
+
+// SetRunner replaces the runner used for helper commands and returns the
+// previous one
+func SetRunner(r runner.Runner) runner.Runner {
+	prev := run
+	run = r
+	return prev
+}
+
 // Client manages communication with the native llama-server (OpenAI-compatible API)
 type Client struct {
 	Host        string
@@ -24,27 +39,52 @@ type Client struct {
 	mu          sync.Mutex
 }
 
-// NewClient creates a llama-server API client
-func NewClient(host string) *Client {
-	modelsDir := os.Getenv("SOVEREIGN_MODELS_DIR")
-	if modelsDir == "" {
-		modelsDir = "/data/data/com.termux/files/home/models"
-	}
-	serverBin := os.Getenv("SOVEREIGN_LLAMA_BIN")
-	if serverBin == "" {
-		serverBin = "/data/data/com.termux/files/home/llama.cpp/bld/bin/llama-server"
-	}
+// Defaults for the Termux llama.cpp build on the phone node
+const (
+	DefaultHost      = "localhost:8085"
+	DefaultModelsDir = "/data/data/com.termux/files/home/models"
+	DefaultLlamaBin  = "/data/data/com.termux/files/home/llama.cpp/bld/bin/llama-server"
+)
 
+// NewClient creates a llama-server API client with the default model paths
+func NewClient(host string) *Client {
 	return &Client{
 		Host:      host,
-		ModelsDir: modelsDir,
-		ServerBin: serverBin,
+		ModelsDir: DefaultModelsDir,
+		ServerBin: DefaultLlamaBin,
 		HTTPClient: &http.Client{
 			Timeout: 0, // No timeout for streaming
 		},
 	}
 }
 
+// NewClientFromConfig creates a client from the effective config, so the
+// host, models directory and llama-server binary follow config.yaml and
+// their SOVEREIGN_AI_* overrides
+func NewClientFromConfig(cfg *config.Config) *Client {
+	host := cfg.AI.Host
+	if host == "" {
+		host = DefaultHost
+	}
+	c := NewClient(host)
+	if cfg.AI.ModelsDir != "" {
+		c.ModelsDir = cfg.AI.ModelsDir
+	}
+	if cfg.AI.LlamaBin != "" {
+		c.ServerBin = cfg.AI.LlamaBin
+	}
+	return c
+}
+
+// WithConfig returns a client for cfg that remembers the model c has loaded,
+// for applying config changes to a running server
+func (c *Client) WithConfig(cfg *config.Config) *Client {
+	n := NewClientFromConfig(cfg)
+	n.HTTPClient = c.HTTPClient
+	n.activeModel = c.ActiveModel()
+	return n
+}
+
 // Model represents an installed GGUF model
 type Model struct {
 	Name       string    `json:"name"`
@@ -461,10 +501,11 @@ func (c *Client) SwitchModel(modelName string) error {
 	}
 
 	// Kill existing llama-server
-	exec.Command("pkill", "-f", "llama-server").Run()
+	run.Run(context.Background(), runner.Command{Name: "pkill", Args: []string{"-f", "llama-server"}, Timeout: 5 * time.Second})
 	time.Sleep(1 * time.Second)
 
-	// Start new llama-server with optimized flags
+	// Start new llama-server with optimized flags. It outlives this call, so
+	// it is started directly rather than through the runner.
 	cmd := exec.Command(c.ServerBin,
 		"-m", modelPath,
 		"--host", "0.0.0.0",
@@ -111,7 +111,7 @@ func BuildServerContext(cfg *config.Config) *ServerContext {
 	ctx.Backup = BackupStatus{
 		Enabled:  cfg.Backup.Enabled,
 		Schedule: cfg.Backup.Schedule,
-		HasRepo:  backup.IsResticInstalled(),
+		HasRepo:  (&backup.Manager{Runner: run}).IsResticInstalled(),
 	}
 
 	return ctx
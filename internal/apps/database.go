@@ -0,0 +1,90 @@
+package apps
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Achilles1089/sovereign-stack/internal/docker"
+	"github.com/Achilles1089/sovereign-stack/internal/secrets"
+)
+
+// DatabaseSecret is the per-app secret holding the password of an app's
+// database user; manifests refer to it as {{secret:db_password}}
+const DatabaseSecret = "db_password"
+
+const postgresContainer = "sovereign-postgres"
+
+// databaseName matches names usable unquoted as PostgreSQL identifiers
+var databaseName = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
+
+// How long provisionDatabase waits for PostgreSQL to accept connections
+var (
+	postgresReadyTries = 30
+	postgresReadyWait  = 2 * time.Second
+)
+
+// requiredServices returns the core services app needs: Requires.Services,
+// plus postgres for an app with its own database
+func (a *AppManifest) requiredServices() []string {
+	services := a.Requires.Services
+	if a.Requires.Database != "" && !slices.Contains(services, "postgres") {
+		services = append(slices.Clip(services), "postgres")
+	}
+	return services
+}
+
+// provisionDatabase creates app's PostgreSQL database and a user of the same
+// name that owns it, with the password in the app's db_password secret. It
+// is idempotent: an existing user gets its password reset, and an existing
+// database, e.g. one an older install created under the shared superuser,
+// is handed to the user with access to its tables. Databases are kept when
+// the app is removed.
+func provisionDatabase(app *AppManifest) error {
+	name := app.Requires.Database
+	if !databaseName.MatchString(name) {
+		return fmt.Errorf("app '%s' has an invalid database name %q", app.Name, name)
+	}
+	password, err := secrets.Default().Ensure("apps/"+app.Name+"/"+DatabaseSecret, secrets.DefaultLength)
+	if err != nil {
+		return fmt.Errorf("failed to generate database password for %s: %w", app.Name, err)
+	}
+
+	ctx := context.Background()
+	if err := waitForPostgres(ctx); err != nil {
+		return err
+	}
+
+	// Sent on stdin, so the password stays out of the process list
+	sql := strings.NewReplacer("{name}", name, "{password}", strings.ReplaceAll(password, "'", "''")).Replace(`
+SELECT 'CREATE ROLE {name} LOGIN' WHERE NOT EXISTS (SELECT FROM pg_roles WHERE rolname = '{name}')\gexec
+ALTER ROLE {name} WITH LOGIN PASSWORD '{password}';
+SELECT 'CREATE DATABASE {name} OWNER {name}' WHERE NOT EXISTS (SELECT FROM pg_database WHERE datname = '{name}')\gexec
+ALTER DATABASE {name} OWNER TO {name};
+\connect {name}
+GRANT ALL ON SCHEMA public TO {name};
+GRANT ALL ON ALL TABLES IN SCHEMA public TO {name};
+GRANT ALL ON ALL SEQUENCES IN SCHEMA public TO {name};
+`)
+	_, err = docker.Exec(ctx, postgresContainer, strings.NewReader(sql), "psql", "-U", "sovereign", "-d", "sovereign", "-v", "ON_ERROR_STOP=1", "-q")
+	if err != nil {
+		return fmt.Errorf("failed to create database %s: %w", name, err)
+	}
+	return nil
+}
+
+// waitForPostgres waits until the postgres container accepts connections,
+// e.g. right after EnableServices started it
+func waitForPostgres(ctx context.Context) error {
+	var err error
+	for i := 0; i < postgresReadyTries; i++ {
+		if _, err = docker.Exec(ctx, postgresContainer, nil, "pg_isready", "-U", "sovereign"); err == nil {
+			return nil
+		}
+		time.Sleep(postgresReadyWait)
+	}
+	return fmt.Errorf("postgres is not ready: %w", err)
+}
@@ -0,0 +1,127 @@
+package apps
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrRequired is returned when removing an app other installed apps need
+var ErrRequired = errors.New("app is required by installed apps")
+
+// InstallOrder returns what installing app takes, in order: the apps it
+// needs, directly or through other apps, that are not installed yet, then
+// app itself. A dependency missing from the catalog or a cycle is an error.
+func InstallOrder(app *AppManifest) ([]*AppManifest, error) {
+	installed, err := InstalledApps()
+	if err != nil {
+		return nil, err
+	}
+
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int)
+	var order []*AppManifest
+	var visit func(a *AppManifest, path []string) error
+	visit = func(a *AppManifest, path []string) error {
+		path = append(slices.Clip(path), a.Name)
+		switch state[a.Name] {
+		case visiting:
+			return fmt.Errorf("dependency cycle: %s", strings.Join(path, " → "))
+		case done:
+			return nil
+		}
+		state[a.Name] = visiting
+		for _, name := range a.Requires.Apps {
+			if slices.Contains(installed, name) {
+				continue
+			}
+			dep := FindApp(name)
+			if dep == nil {
+				return fmt.Errorf("app '%s' requires '%s', which is not in the catalog", a.Name, name)
+			}
+			if err := visit(dep, path); err != nil {
+				return err
+			}
+		}
+		state[a.Name] = done
+		order = append(order, a)
+		return nil
+	}
+	if err := visit(app, nil); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// Dependents returns the installed apps that need appName directly
+func Dependents(appName string) ([]string, error) {
+	installed, err := InstalledApps()
+	if err != nil {
+		return nil, err
+	}
+	var dependents []string
+	for _, name := range installed {
+		if name == appName {
+			continue
+		}
+		if state, err := LoadState(name); err == nil && slices.Contains(state.Requires, appName) {
+			dependents = append(dependents, name)
+		}
+	}
+	return dependents, nil
+}
+
+func requiredBy(appName string, dependents []string) error {
+	return fmt.Errorf("%w: '%s' is needed by %s", ErrRequired, appName, strings.Join(dependents, ", "))
+}
+
+// RemoveOrder returns the apps to remove, in order, to remove appName. Unless
+// cascade is set, removing an app other apps need fails with ErrRequired;
+// with it, they and their own dependents are removed first.
+func RemoveOrder(appName string, cascade bool) ([]string, error) {
+	dependents, err := Dependents(appName)
+	if err != nil {
+		return nil, err
+	}
+	if len(dependents) > 0 && !cascade {
+		return nil, requiredBy(appName, dependents)
+	}
+
+	seen := make(map[string]bool)
+	var order []string
+	var visit func(name string) error
+	visit = func(name string) error {
+		if seen[name] {
+			return nil
+		}
+		seen[name] = true
+		dependents, err := Dependents(name)
+		if err != nil {
+			return err
+		}
+		for _, d := range dependents {
+			if err := visit(d); err != nil {
+				return err
+			}
+		}
+		order = append(order, name)
+		return nil
+	}
+	if err := visit(appName); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// Names returns the names of apps, e.g. an InstallOrder
+func Names(apps []*AppManifest) []string {
+	names := make([]string, len(apps))
+	for i, a := range apps {
+		names[i] = a.Name
+	}
+	return names
+}
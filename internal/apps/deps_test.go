@@ -0,0 +1,148 @@
+package apps
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Achilles1089/sovereign-stack/internal/config"
+	"github.com/Achilles1089/sovereign-stack/internal/docker"
+	"github.com/Achilles1089/sovereign-stack/internal/runner"
+)
+
+func TestInstallOrder(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	defer func(catalog []AppManifest) { BuiltinApps = catalog }(BuiltinApps)
+	BuiltinApps = []AppManifest{
+		{Name: "a", Requires: AppRequirements{Apps: []string{"b", "c"}}},
+		{Name: "b", Requires: AppRequirements{Apps: []string{"c"}}},
+		{Name: "c"},
+		{Name: "x", Requires: AppRequirements{Apps: []string{"y"}}},
+		{Name: "y", Requires: AppRequirements{Apps: []string{"x"}}},
+		{Name: "z", Requires: AppRequirements{Apps: []string{"missing"}}},
+	}
+
+	order, err := InstallOrder(FindApp("a"))
+	if err != nil || strings.Join(Names(order), ",") != "c,b,a" {
+		t.Errorf("InstallOrder(a) = %v, %v", Names(order), err)
+	}
+	if _, err := InstallOrder(FindApp("x")); err == nil || !strings.Contains(err.Error(), "x → y → x") {
+		t.Errorf("a cycle should fail, got %v", err)
+	}
+	if _, err := InstallOrder(FindApp("z")); err == nil || !strings.Contains(err.Error(), "not in the catalog") {
+		t.Errorf("an unknown dependency should fail, got %v", err)
+	}
+}
+
+func TestInstallAllAndCascade(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := config.DefaultConfig()
+	cfg.Port = 9090
+	if err := docker.WriteComposeFile(docker.GenerateCoreCompose(cfg), docker.CoreComposePath(), "init"); err != nil {
+		t.Fatal(err)
+	}
+	defer docker.SetRunner(docker.SetRunner(runner.NewFake()))
+	stubHost(t, 16384, 100)
+
+	immich := FindApp("immich")
+	if _, err := InstallApp(cfg, immich); !errors.Is(err, ErrPreflight) || !strings.Contains(err.Error(), "app redis: not installed") {
+		t.Errorf("installing without redis should fail its preflight, got %v", err)
+	}
+
+	order, err := InstallOrder(immich)
+	if err != nil || strings.Join(Names(order), ",") != "redis,immich" {
+		t.Fatalf("InstallOrder = %v, %v", Names(order), err)
+	}
+	pres, err := InstallAll(cfg, order)
+	if err != nil {
+		t.Fatalf("InstallAll failed: %v", err)
+	}
+	if checkStatus(pres[1], "app redis") != CheckOK || checkStatus(pres[1], "database immich") != CheckOK {
+		t.Errorf("unexpected immich preflight: %+v", pres[1].Checks)
+	}
+	if installed, _ := InstalledApps(); strings.Join(installed, ",") != "immich,redis" {
+		t.Errorf("InstalledApps = %v", installed)
+	}
+	if order, _ := InstallOrder(immich); len(order) != 1 {
+		t.Errorf("installed dependencies should be skipped, got %v", Names(order))
+	}
+
+	if err := RemoveApp("redis"); !errors.Is(err, ErrRequired) || !strings.Contains(err.Error(), "immich") {
+		t.Errorf("removing redis should be blocked by immich, got %v", err)
+	}
+	if _, err := RemoveOrder("redis", false); !errors.Is(err, ErrRequired) {
+		t.Errorf("RemoveOrder without cascade should fail, got %v", err)
+	}
+	removal, err := RemoveOrder("redis", true)
+	if err != nil || strings.Join(removal, ",") != "immich,redis" {
+		t.Fatalf("RemoveOrder = %v, %v", removal, err)
+	}
+	for _, name := range removal {
+		if err := RemoveApp(name); err != nil {
+			t.Fatalf("RemoveApp(%s) failed: %v", name, err)
+		}
+	}
+	if installed, _ := InstalledApps(); len(installed) != 0 {
+		t.Errorf("InstalledApps after cascade = %v", installed)
+	}
+}
+
+func TestInstallAllCountsEarlierApps(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := config.DefaultConfig()
+	cfg.Port = 9090
+	cfg.Hardware.RAMTotalMB = 3072
+	cfg.Apps.PortRange = "20000-20009"
+	if err := docker.WriteComposeFile(docker.GenerateCoreCompose(cfg), docker.CoreComposePath(), "init"); err != nil {
+		t.Fatal(err)
+	}
+	fake := runner.NewFake()
+	defer docker.SetRunner(docker.SetRunner(fake))
+	stubHost(t, 16384, 100)
+	defer func(catalog []AppManifest) { BuiltinApps = catalog }(BuiltinApps)
+	BuiltinApps = []AppManifest{
+		{Name: "base", Requires: AppRequirements{MinRAMMB: 2048}, Compose: AppCompose{Image: "x", Ports: []string{"9000:80"}}},
+		{Name: "top", Requires: AppRequirements{Apps: []string{"base"}, MinRAMMB: 1024}, Compose: AppCompose{Image: "y", Ports: []string{"9000:8080"}}},
+	}
+
+	order, err := InstallOrder(FindApp("top"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pres, err := InstallAll(cfg, order)
+	if !errors.Is(err, ErrPreflight) || pres[0].Err() != nil {
+		t.Fatalf("only top should fail its preflight, got %v", err)
+	}
+	if got := pres[1].Checks; checkStatus(pres[1], "port 9000/tcp") != CheckFail || checkStatus(pres[1], "memory reservations") == CheckFail {
+		t.Errorf("top's port should clash with base's: %+v", got)
+	}
+	if len(fake.Lines()) != 0 {
+		t.Errorf("nothing should be installed: %v", fake.Lines())
+	}
+
+	// Ports move off those of the apps installed first; reservations add up
+	for i := range order {
+		if order[i], err = AllocatePorts(cfg, order[i], order[:i]...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := strings.Join(order[1].Compose.Ports, ","); got != "20000:8080" {
+		t.Errorf("top's ports = %s", got)
+	}
+	budget, err := CheckMemory(cfg, order[1], order[:1]...)
+	if err != nil || budget.ReservedMB != 2048 {
+		t.Errorf("base's reservation should count: %+v, %v", budget, err)
+	}
+	cfg.Hardware.RAMTotalMB = 2560
+	if _, err := InstallAll(cfg, order); !errors.Is(err, ErrPreflight) || !strings.Contains(err.Error(), "memory reservations") {
+		t.Errorf("top should not fit next to base, got %v", err)
+	}
+
+	cfg.Hardware.RAMTotalMB = 3072
+	if _, err := InstallAll(cfg, order); err != nil {
+		t.Fatalf("InstallAll failed: %v", err)
+	}
+	if installed, _ := InstalledApps(); strings.Join(installed, ",") != "base,top" {
+		t.Errorf("InstalledApps = %v", installed)
+	}
+}
@@ -1,7 +1,15 @@
 package apps
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/Achilles1089/sovereign-stack/internal/config"
+	"github.com/Achilles1089/sovereign-stack/internal/docker"
+	"github.com/Achilles1089/sovereign-stack/internal/runner"
+	"github.com/Achilles1089/sovereign-stack/internal/secrets"
 )
 
 func TestBuiltinApps(t *testing.T) {
@@ -30,6 +38,12 @@ func TestBuiltinApps(t *testing.T) {
 		if app.Version == "" {
 			t.Errorf("app %q has no version", app.Name)
 		}
+		if !docker.ValidRestart(app.restartPolicy()) {
+			t.Errorf("app %q has an invalid restart policy %q", app.Name, app.Compose.Restart)
+		}
+		if res := app.EffectiveResources(); res.MemoryReservationMB > res.MemoryLimitMB {
+			t.Errorf("app %q reserves more memory than its limit", app.Name)
+		}
 
 		// Unique names
 		if names[app.Name] {
@@ -74,3 +88,155 @@ func TestAppCategories(t *testing.T) {
 		t.Errorf("expected at least 3 categories, got %d", len(categories))
 	}
 }
+
+func TestNoHardcodedCredentials(t *testing.T) {
+	for _, app := range BuiltinApps {
+		for _, kv := range app.Compose.Environment {
+			key, value, _ := strings.Cut(kv, "=")
+			sensitive := strings.Contains(key, "PASS") || strings.Contains(key, "SECRET") || strings.HasSuffix(key, "_KEY") || key == "DATABASE_URL"
+			if sensitive && !secrets.HasPlaceholder(value) {
+				t.Errorf("app %q sets %s without a {{secret:...}} placeholder", app.Name, key)
+			}
+		}
+	}
+}
+
+func TestInstallApp(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	composePath := filepath.Join(config.ConfigDir(), "docker-compose.yml")
+	os.MkdirAll(config.ConfigDir(), 0700)
+	if err := docker.WriteComposeFile(docker.GenerateCoreCompose(config.DefaultConfig()), composePath, "init"); err != nil {
+		t.Fatal(err)
+	}
+	fake := runner.NewFake()
+	defer docker.SetRunner(docker.SetRunner(fake))
+
+	stubHost(t, 16384, 100)
+	cfg := config.DefaultConfig()
+	cfg.Port = 9090 // nextcloud publishes 8080
+	if _, err := InstallApp(cfg, FindApp("nextcloud")); err != nil {
+		t.Fatalf("InstallApp failed: %v", err)
+	}
+	appPath := docker.AppComposePath("nextcloud")
+	want := []string{
+		"docker exec sovereign-postgres pg_isready -U sovereign",
+		"docker exec -i sovereign-postgres psql -U sovereign -d sovereign -v ON_ERROR_STOP=1 -q",
+		"docker compose -f " + appPath + " up -d",
+	}
+	if got := strings.Join(fake.Lines(), "|"); got != strings.Join(want, "|") {
+		t.Errorf("unexpected docker calls: %s", got)
+	}
+	if sql := fake.Calls()[1].Input; !strings.Contains(sql, "CREATE DATABASE nextcloud OWNER nextcloud") {
+		t.Errorf("nextcloud's database not provisioned:\n%s", sql)
+	}
+
+	core, _ := docker.LoadComposeFile(composePath)
+	if _, ok := core.Services["nextcloud"]; ok {
+		t.Error("apps should not be added to the core compose file")
+	}
+	compose, err := docker.LoadComposeFile(appPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if compose.Name != "sovereign-nextcloud" || compose.Volumes["nextcloud_data"] == nil || compose.Networks["sovereign"] == nil {
+		t.Errorf("unexpected app project: %+v", compose)
+	}
+	svc := compose.Services["nextcloud"]
+	if svc == nil || len(svc.EnvFile) != 1 {
+		t.Fatalf("nextcloud service missing or without an env file: %+v", svc)
+	}
+	env, err := os.ReadFile(svc.EnvFile[0])
+	if err != nil || strings.Contains(string(env), "{{secret:") {
+		t.Errorf("credentials not filled in: %v\n%s", err, env)
+	}
+
+	if _, err := InstallApp(cfg, FindApp("nextcloud")); err == nil {
+		t.Error("installing twice should fail")
+	}
+	if installed, _ := InstalledApps(); strings.Join(installed, ",") != "nextcloud" {
+		t.Errorf("InstalledApps = %v", installed)
+	}
+
+	if err := RemoveApp("nextcloud"); err != nil {
+		t.Fatalf("RemoveApp failed: %v", err)
+	}
+	if got := fake.Lines()[3]; got != "docker compose -f "+appPath+" down" {
+		t.Errorf("remove should stop only the app's project, got %s", got)
+	}
+	if _, err := os.Stat(filepath.Dir(appPath)); !os.IsNotExist(err) {
+		t.Error("the app's compose project should be deleted")
+	}
+	if installed, _ := InstalledApps(); len(installed) != 0 {
+		t.Errorf("InstalledApps after remove = %v", installed)
+	}
+}
+
+func TestSplitLegacyCompose(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	legacy := docker.GenerateCoreCompose(config.DefaultConfig())
+	docker.AddAppToCompose(legacy, "jellyfin", &docker.ComposeService{
+		Image:   "jellyfin/jellyfin:10.9",
+		Volumes: []string{"jellyfin_data:/config"},
+	})
+	legacy.Volumes["jellyfin_data"] = nil
+	if err := docker.WriteComposeFile(legacy, docker.CoreComposePath(), "init"); err != nil {
+		t.Fatal(err)
+	}
+	if installed, _ := InstalledApps(); strings.Join(installed, ",") != "jellyfin" {
+		t.Errorf("apps in the core file should count as installed: %v", installed)
+	}
+
+	moved, err := SplitLegacyCompose()
+	if err != nil || strings.Join(moved, ",") != "jellyfin" {
+		t.Fatalf("SplitLegacyCompose = %v, %v", moved, err)
+	}
+
+	core, _ := docker.LoadComposeFile(docker.CoreComposePath())
+	if _, ok := core.Services["jellyfin"]; ok || core.Volumes["jellyfin_data"] != nil || core.Services["postgres"] == nil {
+		t.Errorf("unexpected core file after split: %+v", core)
+	}
+	app, err := docker.LoadComposeFile(docker.AppComposePath("jellyfin"))
+	if err != nil || app.Services["jellyfin"] == nil {
+		t.Fatalf("jellyfin project not written: %v", err)
+	}
+	// The volume keeps the name it had in the core project
+	if vol, _ := app.Volumes["jellyfin_data"].(map[string]interface{}); vol["name"] != "sovereign_jellyfin_data" {
+		t.Errorf("volume = %v", app.Volumes["jellyfin_data"])
+	}
+
+	if moved, _ := SplitLegacyCompose(); len(moved) != 0 {
+		t.Errorf("second split moved %v", moved)
+	}
+}
+
+func TestPlanInstallWritesNothing(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	composePath := filepath.Join(config.ConfigDir(), "docker-compose.yml")
+	os.MkdirAll(config.ConfigDir(), 0700)
+	if err := docker.WriteComposeFile(docker.GenerateCoreCompose(config.DefaultConfig()), composePath, "init"); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := os.ReadFile(composePath)
+
+	plan, err := PlanInstall(config.DefaultConfig(), FindApp("nextcloud"))
+	if err != nil {
+		t.Fatalf("PlanInstall failed: %v", err)
+	}
+	if len(plan.Changes) == 0 || plan.Changes[0].Name != "nextcloud" || plan.Changes[0].Action != docker.ActionAdd {
+		t.Errorf("unexpected plan: %+v", plan.Changes)
+	}
+
+	after, _ := os.ReadFile(composePath)
+	if string(before) != string(after) {
+		t.Error("PlanInstall modified the compose file")
+	}
+	if _, err := os.Stat(docker.EnvFilePath("nextcloud")); !os.IsNotExist(err) {
+		t.Error("PlanInstall wrote an env file")
+	}
+	if _, err := os.Stat(docker.AppComposePath("nextcloud")); !os.IsNotExist(err) {
+		t.Error("PlanInstall wrote the app's compose file")
+	}
+	if names, _ := secrets.Default().List(); len(names) != 0 {
+		t.Errorf("PlanInstall generated secrets: %v", names)
+	}
+}
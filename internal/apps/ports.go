@@ -0,0 +1,103 @@
+package apps
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/Achilles1089/sovereign-stack/internal/config"
+	"github.com/Achilles1089/sovereign-stack/internal/docker"
+)
+
+// PortMapping records where one of an app's container ports is published
+type PortMapping struct {
+	ContainerPort int    `json:"container_port"`
+	Protocol      string `json:"protocol"`
+	DefaultPort   int    `json:"default_port"` // host port the manifest asks for
+	HostPort      int    `json:"host_port"`    // host port actually used
+}
+
+// Remapped reports whether the app was moved off its default host port
+func (m PortMapping) Remapped() bool {
+	return m.HostPort != m.DefaultPort
+}
+
+// AllocatePorts returns a copy of app whose taken host ports are moved to
+// free ones from cfg.Apps.PortRange, lowest first; its PortMappings show
+// where each port went. A port is taken if another app or core service
+// publishes it, a planned app installed first in the same InstallOrder will,
+// the dashboard listens on it, or something else holds it on the host. Free
+// default ports are kept, and CaddyRoute follows a remapped port.
+func AllocatePorts(cfg *config.Config, app *AppManifest, planned ...*AppManifest) (*AppManifest, error) {
+	low, high, err := config.ParsePortRange(cfg.Apps.PortRange)
+	if err != nil {
+		return nil, fmt.Errorf("apps.port_range: %w", err)
+	}
+
+	claimed := claimedPorts(app.Name, planned)
+	taken := func(port int, protocol string) bool {
+		return claimed[strconv.Itoa(port)+"/"+protocol] != "" ||
+			(protocol == "tcp" && port == cfg.Port) ||
+			portInUse(port, protocol)
+	}
+
+	allocated := *app
+	allocated.Compose.Ports = make([]string, 0, len(app.Compose.Ports))
+	var mappings []PortMapping
+	next := low
+	for _, spec := range app.Compose.Ports {
+		port, err := docker.ParsePort(spec)
+		if err != nil {
+			return nil, fmt.Errorf("app '%s': %w", app.Name, err)
+		}
+		if port.HostPort == 0 {
+			allocated.Compose.Ports = append(allocated.Compose.Ports, spec)
+			continue
+		}
+
+		mapping := PortMapping{ContainerPort: port.ContainerPort, Protocol: port.Protocol, DefaultPort: port.HostPort, HostPort: port.HostPort}
+		if taken(port.HostPort, port.Protocol) {
+			for next <= high && taken(next, port.Protocol) {
+				next++
+			}
+			if next > high {
+				return nil, fmt.Errorf("no free %s port left in %s for %s's port %d", port.Protocol, cfg.Apps.PortRange, app.Name, port.HostPort)
+			}
+			mapping.HostPort = next
+			port.HostPort = next
+			next++
+		}
+		// Later ports of this app must not reuse the one just chosen
+		claimed[strconv.Itoa(port.HostPort)+"/"+port.Protocol] = app.Name
+
+		allocated.Compose.Ports = append(allocated.Compose.Ports, port.String())
+		mappings = append(mappings, mapping)
+	}
+
+	if app.CaddyRoute != nil {
+		route := *app.CaddyRoute
+		for _, m := range mappings {
+			if m.Protocol == "tcp" && m.DefaultPort == route.Port {
+				route.Port = m.HostPort
+				break
+			}
+		}
+		allocated.CaddyRoute = &route
+	}
+	allocated.ports = mappings
+	return &allocated, nil
+}
+
+// PortMappings lists app's published host ports: those chosen by
+// AllocatePorts, or the manifest's own
+func (a *AppManifest) PortMappings() []PortMapping {
+	if a.ports != nil {
+		return a.ports
+	}
+	var mappings []PortMapping
+	for _, spec := range a.Compose.Ports {
+		if port, err := docker.ParsePort(spec); err == nil && port.HostPort != 0 {
+			mappings = append(mappings, PortMapping{ContainerPort: port.ContainerPort, Protocol: port.Protocol, DefaultPort: port.HostPort, HostPort: port.HostPort})
+		}
+	}
+	return mappings
+}
@@ -0,0 +1,94 @@
+package apps
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Achilles1089/sovereign-stack/internal/config"
+	"github.com/Achilles1089/sovereign-stack/internal/docker"
+	"github.com/Achilles1089/sovereign-stack/internal/runner"
+)
+
+func TestAllocatePorts(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := config.DefaultConfig() // the dashboard listens on 8080, like nextcloud
+	cfg.Apps.PortRange = "20000-20002"
+	if err := docker.WriteComposeFile(docker.GenerateCoreCompose(cfg), docker.CoreComposePath(), "init"); err != nil {
+		t.Fatal(err)
+	}
+	defer docker.SetRunner(docker.SetRunner(runner.NewFake()))
+	stubHost(t, 16384, 100, 20000)
+
+	catalog := FindApp("nextcloud")
+	app, err := AllocatePorts(cfg, catalog)
+	if err != nil {
+		t.Fatalf("AllocatePorts failed: %v", err)
+	}
+	if got := strings.Join(app.Compose.Ports, ","); got != "20001:80" {
+		t.Errorf("ports = %s, want 20001:80", got)
+	}
+	if app.CaddyRoute.Port != 20001 || catalog.CaddyRoute.Port != 8080 || catalog.Compose.Ports[0] != "8080:80" {
+		t.Errorf("the copy's route should follow the port and the catalog stay as is: %d, %+v", app.CaddyRoute.Port, catalog)
+	}
+
+	if _, err := InstallApp(cfg, app); err != nil {
+		t.Fatalf("InstallApp failed: %v", err)
+	}
+	compose, _ := docker.LoadComposeFile(docker.AppComposePath("nextcloud"))
+	if got := compose.Services["nextcloud"].Ports; len(got) != 1 || got[0] != "20001:80" {
+		t.Errorf("compose ports = %v", got)
+	}
+	state, err := LoadState("nextcloud")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Ports) != 1 || state.Ports[0].DefaultPort != 8080 || state.Ports[0].HostPort != 20001 || state.InstalledAt.IsZero() {
+		t.Errorf("state = %+v", state)
+	}
+	if got := state.URL(cfg); got != "http://localhost:20001" {
+		t.Errorf("URL = %s", got)
+	}
+
+	// Free default ports are kept; clashing ones skip the port nextcloud took
+	clash := &AppManifest{Name: "clash", Compose: AppCompose{Image: "x", Ports: []string{"8000:80", "20001:443", "127.0.0.1:8000:8000/udp"}}}
+	moved, err := AllocatePorts(cfg, clash)
+	if err != nil {
+		t.Fatalf("AllocatePorts failed: %v", err)
+	}
+	if got := strings.Join(moved.Compose.Ports, ","); got != "8000:80,20002:443,127.0.0.1:8000:8000/udp" {
+		t.Errorf("ports = %s", got)
+	}
+
+	full := &AppManifest{Name: "full", Compose: AppCompose{Image: "x", Ports: []string{"8080:80", "20001:81"}}}
+	if _, err := AllocatePorts(cfg, full); err == nil || !strings.Contains(err.Error(), "no free tcp port") {
+		t.Errorf("an exhausted range should fail, got %v", err)
+	}
+
+	if err := RemoveApp("nextcloud"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(StatePath("nextcloud")); !os.IsNotExist(err) {
+		t.Errorf("removing the app should remove its state: %v", err)
+	}
+}
+
+func TestLoadStateWithoutFile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	fragment := docker.NewAppCompose("jellyfin")
+	fragment.Services["jellyfin"] = &docker.ComposeService{Image: "jellyfin/jellyfin:10.9", Ports: []string{"20005:8096"}}
+	if err := docker.WriteComposeFile(fragment, docker.AppComposePath("jellyfin"), "rollback"); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := LoadState("jellyfin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.WebPort != 20005 || state.Ports[0].HostPort != 20005 || !state.Ports[0].Remapped() {
+		t.Errorf("state should follow the compose file: %+v", state)
+	}
+	if _, err := LoadState("unknown"); err == nil {
+		t.Error("an app outside the catalog without state should fail")
+	}
+}
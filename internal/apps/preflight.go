@@ -0,0 +1,315 @@
+package apps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Achilles1089/sovereign-stack/internal/config"
+	"github.com/Achilles1089/sovereign-stack/internal/docker"
+	"github.com/Achilles1089/sovereign-stack/internal/hardware"
+)
+
+// Preflight check statuses
+const (
+	CheckOK   = "ok"
+	CheckWarn = "warn"
+	CheckFail = "fail"
+)
+
+// Check is the result of one preflight check
+type Check struct {
+	Name   string `json:"name"`   // e.g. "service postgres", "port 8080/tcp", "disk"
+	Status string `json:"status"` // ok, warn or fail
+	Detail string `json:"detail"`
+}
+
+// Preflight is the outcome of checking whether an app can be installed
+type Preflight struct {
+	App    string   `json:"app"`
+	Checks []Check  `json:"checks"`
+	Enable []string `json:"enable,omitempty"` // disabled core services the install enables
+}
+
+// ErrPreflight is returned when an app fails its preflight checks
+var ErrPreflight = errors.New("preflight checks failed")
+
+// Failures returns the failed checks
+func (p *Preflight) Failures() []Check {
+	var failed []Check
+	for _, c := range p.Checks {
+		if c.Status == CheckFail {
+			failed = append(failed, c)
+		}
+	}
+	return failed
+}
+
+// Err reports every failed check in one error, or nil if none failed
+func (p *Preflight) Err() error {
+	failed := p.Failures()
+	if len(failed) == 0 {
+		return nil
+	}
+	details := make([]string, len(failed))
+	for i, c := range failed {
+		details[i] = c.Name + ": " + c.Detail
+	}
+	return fmt.Errorf("%w for %s: %s", ErrPreflight, p.App, strings.Join(details, "; "))
+}
+
+func (p *Preflight) add(name, status, detail string) {
+	p.Checks = append(p.Checks, Check{Name: name, Status: status, Detail: detail})
+}
+
+// Host probes used by the preflight; tests replace them
+var (
+	availableRAMMB = hardware.AvailableRAMMB
+	freeDiskGB     = hardware.FreeDiskGB
+	portInUse      = listening
+)
+
+// dockerDataDir is where Docker keeps volumes and images on Linux
+const dockerDataDir = "/var/lib/docker"
+
+// RunPreflight checks that app can be installed: its required core services
+// exist, the apps it needs are installed, its host ports are free, and there
+// is enough memory and disk. Apps in planned, those installed earlier in the
+// same InstallOrder, count as installed: their ports are taken and their
+// memory reserved. It runs every check, so all problems are reported at
+// once, and changes nothing.
+func RunPreflight(cfg *config.Config, app *AppManifest, planned ...*AppManifest) *Preflight {
+	pre := &Preflight{App: app.Name, Checks: []Check{}}
+	checkServices(pre, cfg, app, planned)
+	checkApps(pre, app, planned)
+	checkDatabase(pre, app)
+	checkPorts(pre, cfg, app, planned)
+	checkMemory(pre, cfg, app, planned)
+	checkDisk(pre, app)
+	return pre
+}
+
+// checkServices resolves the core services app needs; disabled ones are
+// enabled by the install, or by that of a planned app needing them too
+func checkServices(pre *Preflight, cfg *config.Config, app *AppManifest, planned []*AppManifest) {
+	for _, name := range app.requiredServices() {
+		enabled, err := config.Get(cfg, "services."+name)
+		on, isFlag := enabled.(bool)
+		enabler := slices.IndexFunc(planned, func(a *AppManifest) bool { return slices.Contains(a.requiredServices(), name) })
+		switch {
+		case err != nil || !isFlag:
+			pre.add("service "+name, CheckFail, "unknown core service")
+		case on:
+			pre.add("service "+name, CheckOK, "enabled")
+		case enabler >= 0:
+			pre.add("service "+name, CheckOK, "enabled with "+planned[enabler].Name)
+		default:
+			pre.add("service "+name, CheckOK, "disabled; will be enabled")
+			pre.Enable = append(pre.Enable, name)
+		}
+	}
+}
+
+// checkApps looks for the apps app needs
+func checkApps(pre *Preflight, app *AppManifest, planned []*AppManifest) {
+	if len(app.Requires.Apps) == 0 {
+		return
+	}
+	installed, _ := InstalledApps()
+	for _, name := range app.Requires.Apps {
+		switch {
+		case slices.Contains(installed, name):
+			pre.add("app "+name, CheckOK, "installed")
+		case slices.Contains(Names(planned), name):
+			pre.add("app "+name, CheckOK, "installed first")
+		case FindApp(name) == nil:
+			pre.add("app "+name, CheckFail, "not in the catalog")
+		default:
+			pre.add("app "+name, CheckFail, "not installed")
+		}
+	}
+}
+
+// checkDatabase validates the name of app's own database
+func checkDatabase(pre *Preflight, app *AppManifest) {
+	name := app.Requires.Database
+	switch {
+	case name == "":
+	case !databaseName.MatchString(name):
+		pre.add("database "+name, CheckFail, "invalid name; use lowercase letters, digits and _")
+	default:
+		pre.add("database "+name, CheckOK, "created with user "+name+" if missing")
+	}
+}
+
+// checkPorts looks for another claim on each of app's host ports: an
+// installed or planned app, a core service, the dashboard, or any listening
+// socket
+func checkPorts(pre *Preflight, cfg *config.Config, app *AppManifest, planned []*AppManifest) {
+	claimed := claimedPorts(app.Name, planned)
+	for _, spec := range app.Compose.Ports {
+		port, err := docker.ParsePort(spec)
+		if err != nil {
+			pre.add("port "+spec, CheckFail, err.Error())
+			continue
+		}
+		if port.HostPort == 0 {
+			continue
+		}
+
+		name := fmt.Sprintf("port %d/%s", port.HostPort, port.Protocol)
+		key := strconv.Itoa(port.HostPort) + "/" + port.Protocol
+		switch {
+		case claimed[key] != "":
+			pre.add(name, CheckFail, "already used by "+claimed[key])
+		case port.Protocol == "tcp" && port.HostPort == cfg.Port:
+			pre.add(name, CheckFail, "already used by the dashboard")
+		case portInUse(port.HostPort, port.Protocol):
+			pre.add(name, CheckFail, "already in use by another process")
+		default:
+			pre.add(name, CheckOK, "free")
+		}
+	}
+}
+
+// claimedPorts maps "port/protocol" to the service publishing it, across
+// the core and app compose projects and the planned apps, skipping app itself
+func claimedPorts(skip string, planned []*AppManifest) map[string]string {
+	claimed := make(map[string]string)
+	files, _ := docker.ProjectFiles()
+	for _, path := range files {
+		compose, err := docker.LoadComposeFile(path)
+		if err != nil {
+			continue
+		}
+		for name, svc := range compose.Services {
+			if name == skip {
+				continue
+			}
+			for _, spec := range svc.Ports {
+				if p, err := docker.ParsePort(spec); err == nil && p.HostPort != 0 {
+					claimed[strconv.Itoa(p.HostPort)+"/"+p.Protocol] = name
+				}
+			}
+		}
+	}
+	for _, a := range planned {
+		if a.Name == skip {
+			continue
+		}
+		for _, m := range a.PortMappings() {
+			claimed[strconv.Itoa(m.HostPort)+"/"+m.Protocol] = a.Name
+		}
+	}
+	return claimed
+}
+
+// listening reports whether something already holds port on the host
+func listening(port int, protocol string) bool {
+	addr := ":" + strconv.Itoa(port)
+	if protocol == "udp" {
+		conn, err := net.ListenPacket("udp", addr)
+		if err != nil {
+			return true
+		}
+		conn.Close()
+		return false
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return true
+	}
+	ln.Close()
+	return false
+}
+
+// checkMemory compares MinRAMMB with free memory, and the app's reservation
+// with what installed and planned apps already reserve
+func checkMemory(pre *Preflight, cfg *config.Config, app *AppManifest, planned []*AppManifest) {
+	if app.Requires.MinRAMMB > 0 {
+		free, err := availableRAMMB()
+		switch {
+		case err != nil:
+			pre.add("memory", CheckWarn, "could not read free memory: "+err.Error())
+		case free < app.Requires.MinRAMMB:
+			pre.add("memory", CheckFail, fmt.Sprintf("needs %d MB, %d MB free", app.Requires.MinRAMMB, free))
+		default:
+			pre.add("memory", CheckOK, fmt.Sprintf("needs %d MB, %d MB free", app.Requires.MinRAMMB, free))
+		}
+	}
+
+	budget, err := CheckMemory(cfg, app, planned...)
+	switch {
+	case errors.Is(err, ErrInsufficientMemory):
+		pre.add("memory reservations", CheckFail, strings.TrimPrefix(err.Error(), ErrInsufficientMemory.Error()+": "))
+	case err != nil:
+		pre.add("memory reservations", CheckWarn, "could not check: "+err.Error())
+	case budget.Warning() != "":
+		pre.add("memory reservations", CheckWarn, budget.Warning())
+	default:
+		pre.add("memory reservations", CheckOK, fmt.Sprintf("%d MB reserved of %d MB", budget.ReservedMB+budget.AppReservedMB, budget.TotalMB))
+	}
+}
+
+// checkDisk compares MinDiskGB with free space where Docker keeps volumes
+func checkDisk(pre *Preflight, app *AppManifest) {
+	if app.Requires.MinDiskGB == 0 {
+		return
+	}
+	path := dockerDataDir
+	if _, err := os.Stat(path); err != nil {
+		path = "/"
+	}
+	free, err := freeDiskGB(path)
+	switch {
+	case err != nil:
+		pre.add("disk", CheckWarn, "could not read free disk space: "+err.Error())
+	case free < app.Requires.MinDiskGB:
+		pre.add("disk", CheckFail, fmt.Sprintf("needs %d GB, %d GB free on %s", app.Requires.MinDiskGB, free, path))
+	default:
+		pre.add("disk", CheckOK, fmt.Sprintf("needs %d GB, %d GB free on %s", app.Requires.MinDiskGB, free, path))
+	}
+}
+
+// EnableServices turns on the named core services in cfg, adds them to the
+// core compose file and starts them. The caller saves cfg.
+func EnableServices(cfg *config.Config, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	for _, name := range names {
+		if err := config.SetValue(cfg, "services."+name, true); err != nil {
+			return fmt.Errorf("failed to enable %s: %w", name, err)
+		}
+	}
+
+	generated := docker.GenerateCoreCompose(cfg)
+	_, err := docker.UpdateCompose(docker.CoreComposePath(), "enable "+strings.Join(names, ", "), func(core *docker.ComposeFile) error {
+		for _, name := range names {
+			svc, ok := generated.Services[name]
+			if !ok {
+				return fmt.Errorf("core service %s has no container to start", name)
+			}
+			core.Services[name] = svc
+			for _, v := range svc.Volumes {
+				vol := strings.Split(v, ":")[0]
+				if def, ok := generated.Volumes[vol]; ok {
+					core.Volumes[vol] = def
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := docker.WriteCoreEnvFiles(cfg); err != nil {
+		return err
+	}
+	return docker.ComposeUp(context.Background(), docker.CoreComposePath(), os.Stdout, os.Stderr, names...)
+}
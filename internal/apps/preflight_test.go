@@ -0,0 +1,124 @@
+package apps
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Achilles1089/sovereign-stack/internal/config"
+	"github.com/Achilles1089/sovereign-stack/internal/docker"
+	"github.com/Achilles1089/sovereign-stack/internal/runner"
+)
+
+// stubHost replaces the host probes with a host that has ramMB of free
+// memory, diskGB of free disk and something listening on busy ports
+func stubHost(t *testing.T, ramMB, diskGB int, busy ...int) {
+	prevRAM, prevDisk, prevPort := availableRAMMB, freeDiskGB, portInUse
+	availableRAMMB = func() (int, error) { return ramMB, nil }
+	freeDiskGB = func(string) (int, error) { return diskGB, nil }
+	portInUse = func(port int, _ string) bool { return slices.Contains(busy, port) }
+	t.Cleanup(func() { availableRAMMB, freeDiskGB, portInUse = prevRAM, prevDisk, prevPort })
+}
+
+func checkStatus(pre *Preflight, name string) string {
+	for _, c := range pre.Checks {
+		if c.Name == name {
+			return c.Status
+		}
+	}
+	return ""
+}
+
+func TestPreflightReportsAllFailures(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := config.DefaultConfig() // the dashboard listens on 8080, like nextcloud
+	if err := docker.WriteComposeFile(docker.GenerateCoreCompose(cfg), docker.CoreComposePath(), "init"); err != nil {
+		t.Fatal(err)
+	}
+	stubHost(t, 256, 5)
+
+	pre, err := InstallApp(cfg, FindApp("nextcloud"))
+	if !errors.Is(err, ErrPreflight) {
+		t.Fatalf("expected ErrPreflight, got %v", err)
+	}
+	for _, name := range []string{"port 8080/tcp", "memory", "disk"} {
+		if checkStatus(pre, name) != CheckFail || !strings.Contains(err.Error(), name+":") {
+			t.Errorf("%s should fail and be reported: %v", name, err)
+		}
+	}
+	if _, statErr := docker.LoadComposeFile(docker.AppComposePath("nextcloud")); statErr == nil {
+		t.Error("a failed preflight should not write the app's compose file")
+	}
+}
+
+func TestPreflightPorts(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := config.DefaultConfig()
+	cfg.Port = 9090
+	if err := docker.WriteComposeFile(docker.GenerateCoreCompose(cfg), docker.CoreComposePath(), "init"); err != nil {
+		t.Fatal(err)
+	}
+	defer docker.SetRunner(docker.SetRunner(runner.NewFake()))
+	stubHost(t, 16384, 100, 7575)
+
+	if _, err := InstallApp(cfg, FindApp("jellyfin")); err != nil {
+		t.Fatalf("InstallApp failed: %v", err)
+	}
+
+	clash := &AppManifest{Name: "clash", Compose: AppCompose{Image: "x", Ports: []string{"8096:80", "443:443", "8000:80", "53:53/udp"}}}
+	pre := RunPreflight(cfg, clash)
+	want := map[string]string{
+		"port 8096/tcp": CheckFail, // jellyfin
+		"port 443/tcp":  CheckFail, // caddy
+		"port 8000/tcp": CheckOK,
+		"port 53/udp":   CheckOK,
+	}
+	for name, status := range want {
+		if got := checkStatus(pre, name); got != status {
+			t.Errorf("%s = %q, want %q", name, got, status)
+		}
+	}
+
+	if got := checkStatus(RunPreflight(cfg, FindApp("homarr")), "port 7575/tcp"); got != CheckFail {
+		t.Errorf("a listening socket should fail the port check, got %q", got)
+	}
+}
+
+func TestPreflightEnablesServices(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := config.DefaultConfig()
+	cfg.Port = 9090
+	cfg.Services.Postgres = false
+	if err := docker.WriteComposeFile(docker.GenerateCoreCompose(cfg), docker.CoreComposePath(), "init"); err != nil {
+		t.Fatal(err)
+	}
+	fake := runner.NewFake()
+	defer docker.SetRunner(docker.SetRunner(fake))
+	stubHost(t, 16384, 100)
+
+	pre, err := InstallApp(cfg, FindApp("nextcloud"))
+	if err != nil {
+		t.Fatalf("InstallApp failed: %v", err)
+	}
+	if strings.Join(pre.Enable, ",") != "postgres" || !cfg.Services.Postgres {
+		t.Errorf("postgres should be enabled: %v, %v", pre.Enable, cfg.Services.Postgres)
+	}
+	core, _ := docker.LoadComposeFile(docker.CoreComposePath())
+	if core.Services["postgres"] == nil || !hasKey(core.Volumes, "postgres_data") {
+		t.Errorf("postgres not added to the core compose file: %+v", core)
+	}
+	if got := fake.Lines()[0]; got != "docker compose -f "+docker.CoreComposePath()+" up -d postgres" {
+		t.Errorf("postgres should be started first, got %s", got)
+	}
+
+	bad := &AppManifest{Name: "bad", Requires: AppRequirements{Services: []string{"mongodb"}}, Compose: AppCompose{Image: "x"}}
+	if got := checkStatus(RunPreflight(cfg, bad), "service mongodb"); got != CheckFail {
+		t.Errorf("unknown services should fail, got %q", got)
+	}
+}
+
+func hasKey(m map[string]interface{}, key string) bool {
+	_, ok := m[key]
+	return ok
+}
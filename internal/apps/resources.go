@@ -0,0 +1,157 @@
+package apps
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/Achilles1089/sovereign-stack/internal/config"
+	"github.com/Achilles1089/sovereign-stack/internal/docker"
+)
+
+// AppResources sets the resources an app's container may use. Zero values
+// get defaults; see EffectiveResources.
+type AppResources struct {
+	MemoryLimitMB       int     `yaml:"memory_limit_mb"`
+	MemoryReservationMB int     `yaml:"memory_reservation_mb"`
+	CPULimit            float64 `yaml:"cpu_limit"` // cores, e.g. 1.5; 0 is unlimited
+	CPUReservation      float64 `yaml:"cpu_reservation"`
+	PIDsLimit           int     `yaml:"pids_limit"`
+}
+
+// Resource defaults for apps that do not set them
+const (
+	defaultMemoryReservationMB = 256
+	minMemoryLimitMB           = 1024
+	defaultPIDsLimit           = 2048
+	defaultRestart             = "unless-stopped"
+)
+
+// restartPolicy returns app's compose restart policy
+func (a *AppManifest) restartPolicy() string {
+	if a.Compose.Restart == "" {
+		return defaultRestart
+	}
+	return a.Compose.Restart
+}
+
+// EffectiveResources returns app's resource settings with defaults filled
+// in. Memory reserves Requires.MinRAMMB and is limited to four times that,
+// at least 1 GB.
+func (a *AppManifest) EffectiveResources() AppResources {
+	res := a.Resources
+	if res.MemoryReservationMB == 0 {
+		res.MemoryReservationMB = a.Requires.MinRAMMB
+	}
+	if res.MemoryReservationMB == 0 {
+		res.MemoryReservationMB = defaultMemoryReservationMB
+	}
+	if res.MemoryLimitMB == 0 {
+		res.MemoryLimitMB = max(4*res.MemoryReservationMB, minMemoryLimitMB)
+	}
+	if res.PIDsLimit == 0 {
+		res.PIDsLimit = defaultPIDsLimit
+	}
+	return res
+}
+
+// deploy turns the settings into the service's deploy section
+func (r AppResources) deploy() *docker.DeployConfig {
+	limits := &docker.Limits{Memory: docker.MemoryMB(r.MemoryLimitMB), PIDs: r.PIDsLimit}
+	reservations := &docker.Reservations{Memory: docker.MemoryMB(r.MemoryReservationMB)}
+	if r.CPULimit > 0 {
+		limits.CPUs = strconv.FormatFloat(r.CPULimit, 'f', -1, 64)
+	}
+	if r.CPUReservation > 0 {
+		reservations.CPUs = strconv.FormatFloat(r.CPUReservation, 'f', -1, 64)
+	}
+	return &docker.DeployConfig{Resources: &docker.Resources{Limits: limits, Reservations: reservations}}
+}
+
+// ErrInsufficientMemory is returned when an app's memory reservation does
+// not fit next to the installed apps'
+var ErrInsufficientMemory = errors.New("not enough memory")
+
+// MemoryBudget compares the memory apps reserve and may use with the host's RAM
+type MemoryBudget struct {
+	TotalMB       int `json:"total_mb"`        // host RAM, 0 if unknown
+	ReservedMB    int `json:"reserved_mb"`     // reserved by installed and planned apps
+	LimitMB       int `json:"limit_mb"`        // sum of installed and planned apps' limits
+	AppReservedMB int `json:"app_reserved_mb"` // reserved by the app being installed
+	AppLimitMB    int `json:"app_limit_mb"`    // its memory limit
+}
+
+// Fits reports whether the app's reservation fits in RAM next to the
+// installed apps'
+func (b *MemoryBudget) Fits() bool {
+	return b.TotalMB == 0 || b.ReservedMB+b.AppReservedMB <= b.TotalMB
+}
+
+// Warning describes memory overcommit: the apps' limits adding up to more
+// than RAM, so they can only all reach them by swapping. Empty if none.
+func (b *MemoryBudget) Warning() string {
+	if b.TotalMB == 0 || b.LimitMB+b.AppLimitMB <= b.TotalMB {
+		return ""
+	}
+	return fmt.Sprintf("apps may use up to %d MB together, more than the %d MB of RAM", b.LimitMB+b.AppLimitMB, b.TotalMB)
+}
+
+// CheckMemory works out the memory budget for installing app after the
+// planned apps, as RunPreflight does. It returns ErrInsufficientMemory if the
+// reservations of the installed and planned apps and app add up to more than
+// cfg.Hardware.RAMTotalMB.
+func CheckMemory(cfg *config.Config, app *AppManifest, planned ...*AppManifest) (*MemoryBudget, error) {
+	res := app.EffectiveResources()
+	budget := &MemoryBudget{TotalMB: cfg.Hardware.RAMTotalMB, AppReservedMB: res.MemoryReservationMB, AppLimitMB: res.MemoryLimitMB}
+
+	installed, err := InstalledApps()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range installed {
+		if name == app.Name {
+			continue
+		}
+		reserve, limit := installedMemory(name)
+		budget.ReservedMB += reserve
+		budget.LimitMB += limit
+	}
+	for _, a := range planned {
+		if a.Name == app.Name || slices.Contains(installed, a.Name) {
+			continue
+		}
+		res := a.EffectiveResources()
+		budget.ReservedMB += res.MemoryReservationMB
+		budget.LimitMB += res.MemoryLimitMB
+	}
+
+	if !budget.Fits() {
+		return budget, fmt.Errorf("%w: %s reserves %d MB, but installed apps already reserve %d MB of %d MB RAM",
+			ErrInsufficientMemory, app.Name, budget.AppReservedMB, budget.ReservedMB, budget.TotalMB)
+	}
+	return budget, nil
+}
+
+// installedMemory returns the memory reservation and limit of an installed
+// app, from its compose file, or the catalog's defaults for an app
+// installed before they were written there
+func installedMemory(name string) (reserveMB, limitMB int) {
+	if compose, err := docker.LoadComposeFile(docker.ProjectFileFor(name)); err == nil {
+		if svc := compose.Services[name]; svc != nil && svc.Deploy != nil && svc.Deploy.Resources != nil {
+			r := svc.Deploy.Resources
+			if r.Reservations != nil && r.Reservations.Memory != "" {
+				reserveMB, _ = docker.ParseMemoryMB(r.Reservations.Memory)
+			}
+			if r.Limits != nil && r.Limits.Memory != "" {
+				limitMB, _ = docker.ParseMemoryMB(r.Limits.Memory)
+			}
+			return reserveMB, limitMB
+		}
+	}
+	if app := FindApp(name); app != nil {
+		res := app.EffectiveResources()
+		return res.MemoryReservationMB, res.MemoryLimitMB
+	}
+	return 0, 0
+}
@@ -0,0 +1,66 @@
+package apps
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Achilles1089/sovereign-stack/internal/config"
+	"github.com/Achilles1089/sovereign-stack/internal/docker"
+	"github.com/Achilles1089/sovereign-stack/internal/runner"
+)
+
+func TestEffectiveResources(t *testing.T) {
+	res := FindApp("immich").EffectiveResources()
+	if res.MemoryReservationMB != 2048 || res.MemoryLimitMB != 8192 || res.PIDsLimit != defaultPIDsLimit {
+		t.Errorf("defaults not derived from MinRAMMB: %+v", res)
+	}
+
+	res = (&AppManifest{}).EffectiveResources()
+	if res.MemoryReservationMB != defaultMemoryReservationMB || res.MemoryLimitMB != minMemoryLimitMB {
+		t.Errorf("unexpected defaults without requirements: %+v", res)
+	}
+
+	app := &AppManifest{Resources: AppResources{MemoryLimitMB: 300, CPULimit: 1.5, CPUReservation: 0.25}}
+	deploy := app.EffectiveResources().deploy().Resources
+	if deploy.Limits.Memory != "300M" || deploy.Limits.CPUs != "1.5" || deploy.Reservations.CPUs != "0.25" || deploy.Reservations.Memory != "256M" {
+		t.Errorf("unexpected deploy resources: %+v %+v", deploy.Limits, deploy.Reservations)
+	}
+}
+
+func TestCheckMemory(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := config.DefaultConfig()
+	if err := docker.WriteComposeFile(docker.GenerateCoreCompose(cfg), docker.CoreComposePath(), "init"); err != nil {
+		t.Fatal(err)
+	}
+	defer docker.SetRunner(docker.SetRunner(runner.NewFake()))
+	stubHost(t, 16384, 100)
+
+	cfg.Hardware.RAMTotalMB = 3000
+	if _, err := InstallApp(cfg, FindApp("photoprism")); err != nil {
+		t.Fatalf("InstallApp failed: %v", err)
+	}
+
+	// photoprism reserves 2048 MB; nextcloud's 512 MB still fits, code-server's
+	// 1024 MB does not
+	budget, err := CheckMemory(cfg, FindApp("nextcloud"))
+	if err != nil || budget.ReservedMB != 2048 || budget.AppReservedMB != 512 {
+		t.Fatalf("CheckMemory = %+v, %v", budget, err)
+	}
+	if budget.Warning() == "" {
+		t.Error("limits beyond RAM should warn")
+	}
+	if _, err := CheckMemory(cfg, FindApp("code-server")); !errors.Is(err, ErrInsufficientMemory) {
+		t.Errorf("expected ErrInsufficientMemory, got %v", err)
+	}
+	if _, err := InstallApp(cfg, FindApp("code-server")); !errors.Is(err, ErrPreflight) || !strings.Contains(err.Error(), "memory reservations") {
+		t.Errorf("install should fail its preflight, got %v", err)
+	}
+
+	// Unknown RAM skips the check
+	cfg.Hardware.RAMTotalMB = 0
+	if _, err := CheckMemory(cfg, FindApp("code-server")); err != nil {
+		t.Errorf("unexpected error with unknown RAM: %v", err)
+	}
+}
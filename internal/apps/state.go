@@ -0,0 +1,108 @@
+package apps
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Achilles1089/sovereign-stack/internal/config"
+	"github.com/Achilles1089/sovereign-stack/internal/docker"
+)
+
+// AppState records how an installed app was set up. It lives next to the
+// app's compose file and goes when the app is removed.
+type AppState struct {
+	Name        string        `json:"name"`
+	Version     string        `json:"version"`
+	InstalledAt time.Time     `json:"installed_at"`
+	Requires    []string      `json:"requires,omitempty"` // apps it needs; see Dependents
+	Ports       []PortMapping `json:"ports,omitempty"`
+	WebPort     int           `json:"web_port,omitempty"` // host port serving the web UI, from CaddyRoute
+}
+
+// StatePath returns the state file of an installed app
+func StatePath(appName string) string {
+	return filepath.Join(docker.AppsDir(), appName, "state.json")
+}
+
+// LoadState reads an installed app's state. Apps installed before state was
+// recorded, restored by a rollback, or still in the core compose file have
+// none; their state is rebuilt from the catalog and their compose file.
+func LoadState(appName string) (*AppState, error) {
+	data, err := os.ReadFile(StatePath(appName))
+	if os.IsNotExist(err) {
+		return rebuildState(appName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state AppState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", StatePath(appName), err)
+	}
+	return &state, nil
+}
+
+// newState describes app as it is about to be installed
+func newState(app *AppManifest) *AppState {
+	state := &AppState{Name: app.Name, Version: app.Version, Requires: app.Requires.Apps, Ports: app.PortMappings()}
+	if app.CaddyRoute != nil {
+		state.WebPort = app.CaddyRoute.Port
+	}
+	return state
+}
+
+// rebuildState works out a catalog app's state from the host ports its
+// compose service publishes
+func rebuildState(appName string) (*AppState, error) {
+	app := FindApp(appName)
+	if app == nil {
+		return nil, fmt.Errorf("no state recorded for app '%s'", appName)
+	}
+	state := newState(app)
+	compose, err := docker.LoadComposeFile(docker.ProjectFileFor(appName))
+	if err != nil || compose.Services[appName] == nil {
+		return state, nil
+	}
+	for _, spec := range compose.Services[appName].Ports {
+		port, err := docker.ParsePort(spec)
+		if err != nil || port.HostPort == 0 {
+			continue
+		}
+		for i, m := range state.Ports {
+			if m.ContainerPort == port.ContainerPort && m.Protocol == port.Protocol {
+				if state.WebPort == m.DefaultPort && m.Protocol == "tcp" {
+					state.WebPort = port.HostPort
+				}
+				state.Ports[i].HostPort = port.HostPort
+			}
+		}
+	}
+	return state, nil
+}
+
+// saveState writes an app's state file
+func saveState(state *AppState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(StatePath(state.Name), data, 0644); err != nil {
+		return fmt.Errorf("failed to save state of %s: %w", state.Name, err)
+	}
+	return nil
+}
+
+// URL returns the address of the app's web UI, or "" if it has none
+func (s *AppState) URL(cfg *config.Config) string {
+	if s.WebPort == 0 {
+		return ""
+	}
+	host := cfg.Domain
+	if host == "" {
+		host = "localhost"
+	}
+	return fmt.Sprintf("http://%s:%d", host, s.WebPort)
+}
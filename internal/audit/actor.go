@@ -0,0 +1,29 @@
+package audit
+
+import (
+	"os"
+	"os/user"
+)
+
+// Actor identifies who performed an audited action
+type Actor struct {
+	Name     string
+	SourceIP string
+}
+
+// SystemActor is used for actions the stack takes on its own
+var SystemActor = Actor{Name: "system"}
+
+// CLIActor identifies whoever runs the CLI: $SOVEREIGN_ACTOR (set to "cron"
+// by scheduled jobs), then $SOVEREIGN_USER, then the OS login name
+func CLIActor() Actor {
+	for _, env := range []string{"SOVEREIGN_ACTOR", "SOVEREIGN_USER"} {
+		if name := os.Getenv(env); name != "" {
+			return Actor{Name: name}
+		}
+	}
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return Actor{Name: u.Username}
+	}
+	return Actor{Name: "unknown"}
+}
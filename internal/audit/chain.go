@@ -0,0 +1,281 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ComputeHash returns the chain hash of an event: SHA-256 over the previous
+// hash and the event's canonical JSON with the hash field cleared
+func ComputeHash(ev Event) string {
+	ev.Hash = ""
+	data, _ := json.Marshal(ev)
+
+	h := sha256.New()
+	h.Write([]byte(ev.PrevHash))
+	h.Write([]byte("\n"))
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ChainBreakAction is the action of the event Log writes when the newest
+// event is unreadable: it starts a new chain, and Verify reports the break
+const ChainBreakAction = "audit.chain_break"
+
+// ChainBreak describes the first event that fails verification
+type ChainBreak struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	EventID string `json:"event_id,omitempty"`
+	Reason  string `json:"reason"`
+}
+
+// VerifyResult summarises a walk of the whole audit history
+type VerifyResult struct {
+	Files     int         `json:"files"`
+	Events    int         `json:"events"`
+	Unchained int         `json:"unchained"` // events written before hash chaining existed
+	Pruned    int         `json:"pruned"`    // segments removed by retention before this history starts
+	Head      string      `json:"head"`      // hash of the newest event
+	Break     *ChainBreak `json:"break,omitempty"`
+}
+
+// OK reports whether the chain verified end to end
+func (r *VerifyResult) OK() bool {
+	return r.Break == nil
+}
+
+// Verify walks rotated segments oldest-first and then the current log,
+// checking every event's hash and its link to the previous event. When
+// retention has pruned old segments the chain is checked from the anchor on.
+func (l *Logger) Verify() (*VerifyResult, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	anchor, err := l.loadAnchor()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &VerifyResult{Pruned: anchor.Segments}
+	prev := anchor.PrevHash
+	chained := prev != ""
+	malformed := false // Break is a malformed line; look at what follows it
+	current := l.currentLogPath()
+
+	for _, path := range l.segments() {
+		// Segments the anchor already covers were pruned but not yet deleted
+		if anchor.Through != "" && path != current && !segmentLess(anchor.Through, filepath.Base(path)) {
+			continue
+		}
+
+		f, err := openSegment(path)
+		if err != nil {
+			return nil, err
+		}
+		result.Files++
+
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		line := 0
+		for scanner.Scan() {
+			line++
+			raw := bytes.TrimSpace(scanner.Bytes())
+			if len(raw) == 0 {
+				continue
+			}
+
+			brk := func(id, reason string) {
+				result.Break = &ChainBreak{File: filepath.Base(path), Line: line, EventID: id, Reason: reason}
+			}
+
+			var ev Event
+			jsonErr := json.Unmarshal(raw, &ev)
+			if malformed {
+				// Only note whether logging carried on in a new chain
+				if jsonErr == nil && ev.Action == ChainBreakAction && ev.PrevHash == "" {
+					result.Break.Reason += fmt.Sprintf("; a new chain starts at %s line %d", filepath.Base(path), line)
+				}
+				malformed = false
+				break
+			}
+			if jsonErr != nil {
+				brk("", "malformed JSON")
+				malformed = true
+				continue
+			}
+			result.Events++
+
+			if ev.Hash == "" {
+				// Legacy events may only precede the start of the chain
+				if chained {
+					brk(ev.ID, "missing hash")
+					break
+				}
+				result.Unchained++
+				continue
+			}
+			chained = true
+
+			if ev.PrevHash != prev {
+				brk(ev.ID, fmt.Sprintf("prev_hash %s does not match previous event %s", short(ev.PrevHash), short(prev)))
+				break
+			}
+			if want := ComputeHash(ev); ev.Hash != want {
+				brk(ev.ID, "event contents do not match its hash")
+				break
+			}
+			prev = ev.Hash
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		if result.Break != nil && !malformed {
+			return result, nil
+		}
+	}
+	if result.Break != nil {
+		return result, nil
+	}
+
+	result.Head = prev
+	return result, nil
+}
+
+// segments returns rotated log files oldest-first followed by the current log
+func (l *Logger) segments() []string {
+	current := l.currentLogPath()
+	files := l.rotatedSegments()
+
+	if _, err := os.Stat(current); err == nil {
+		files = append(files, current)
+	}
+	return files
+}
+
+// lastHash returns the hash of the newest event, looking back into the most
+// recent rotated segment when the current log is empty. If that event cannot
+// be parsed, e.g. after a write was cut short, corrupt names its segment.
+func (l *Logger) lastHash() (hash, corrupt string, err error) {
+	files := l.segments()
+	for i := len(files) - 1; i >= 0; i-- {
+		line, err := lastLine(files[i])
+		if err != nil {
+			return "", "", err
+		}
+		if line == nil {
+			continue
+		}
+		var ev Event
+		if err := json.Unmarshal(line, &ev); err != nil {
+			return "", filepath.Base(files[i]), nil
+		}
+		return ev.Hash, "", nil
+	}
+	return "", "", nil
+}
+
+// chainBreak is the event that starts a new chain after a corrupt one
+func chainBreak(at time.Time, id, segment string) Event {
+	ev := Event{
+		ID:        id + "-break",
+		Timestamp: at,
+		Action:    ChainBreakAction,
+		Actor:     "system",
+		Target:    "audit/" + segment,
+		Details:   fmt.Sprintf("Last event in %s is corrupt; starting a new hash chain", segment),
+		Severity:  "critical",
+		Success:   true,
+	}
+	ev.Hash = ComputeHash(ev)
+	return ev
+}
+
+// lastLine reads the final non-empty line of a file without loading all of it.
+// Compressed segments have to be streamed to the end.
+func lastLine(path string) ([]byte, error) {
+	if strings.HasSuffix(path, ".gz") {
+		return lastLineCompressed(path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := info.Size()
+
+	for window := int64(4096); ; window *= 2 {
+		if window > size {
+			window = size
+		}
+		buf := make([]byte, window)
+		if _, err := f.ReadAt(buf, size-window); err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		buf = bytes.TrimRight(buf, "\n")
+		if i := bytes.LastIndexByte(buf, '\n'); i >= 0 {
+			return buf[i+1:], nil
+		}
+		if window == size {
+			if len(buf) == 0 {
+				return nil, nil
+			}
+			return buf, nil
+		}
+	}
+}
+
+func lastLineCompressed(path string) ([]byte, error) {
+	r, err := openSegment(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer r.Close()
+
+	var last []byte
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if line := bytes.TrimSpace(scanner.Bytes()); len(line) > 0 {
+			last = append(last[:0], line...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("audit log %s: %w", filepath.Base(path), err)
+	}
+	return last, nil
+}
+
+func short(hash string) string {
+	if hash == "" {
+		return "(none)"
+	}
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
@@ -0,0 +1,118 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Achilles1089/sovereign-stack/internal/config"
+)
+
+func newTestLogger(t *testing.T) *Logger {
+	t.Helper()
+	logger := NewLoggerFromConfig(config.DefaultConfig())
+	logger.logDir = filepath.Join(t.TempDir(), "audit")
+	return logger
+}
+
+func TestChainVerifies(t *testing.T) {
+	logger := newTestLogger(t)
+
+	logger.LogAppInstall(testActor, "nextcloud", nil)
+	logger.LogBackup(testActor, "create", "daily", nil)
+	logger.LogAuthEvent(Actor{Name: "admin"}, nil)
+
+	result, err := logger.Verify()
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if !result.OK() || result.Events != 3 {
+		t.Fatalf("expected intact chain of 3 events, got %+v", result)
+	}
+
+	events, _ := logger.Query("", 0)
+	if events[0].PrevHash != "" || events[1].PrevHash != events[0].Hash {
+		t.Error("events are not linked")
+	}
+}
+
+func TestChainDetectsTampering(t *testing.T) {
+	logger := newTestLogger(t)
+
+	logger.LogAppInstall(testActor, "nextcloud", nil)
+	logger.LogAppInstall(testActor, "grafana", nil)
+	logger.LogAppInstall(testActor, "gitea", nil)
+
+	path := logger.currentLogPath()
+	data, _ := os.ReadFile(path)
+	os.WriteFile(path, []byte(strings.Replace(string(data), "grafana", "grafanx", 1)), 0600)
+
+	result, err := logger.Verify()
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if result.OK() {
+		t.Fatal("expected tampering to be detected")
+	}
+	if result.Break.Line != 2 {
+		t.Errorf("expected break at line 2, got %d (%s)", result.Break.Line, result.Break.Reason)
+	}
+}
+
+func TestChainAcrossRotation(t *testing.T) {
+	logger := newTestLogger(t)
+	logger.maxSize = 1 // rotate before every write
+
+	logger.LogAppInstall(testActor, "nextcloud", nil)
+	logger.LogAppInstall(testActor, "grafana", nil)
+	logger.LogAppInstall(testActor, "gitea", nil)
+
+	segments := logger.segments()
+	if len(segments) != 3 {
+		t.Fatalf("expected 3 segments, got %d", len(segments))
+	}
+
+	result, _ := logger.Verify()
+	if !result.OK() || result.Events != 3 {
+		t.Fatalf("expected chain to span rotated files, got %+v", result.Break)
+	}
+
+	// Deleting a whole rotated segment breaks the next link
+	os.Remove(segments[1])
+	result, _ = logger.Verify()
+	if result.OK() {
+		t.Error("expected missing segment to be detected")
+	}
+}
+
+func TestChainBreakAfterTornWrite(t *testing.T) {
+	logger := newTestLogger(t)
+
+	logger.LogAppInstall(testActor, "nextcloud", nil)
+	logger.LogAppInstall(testActor, "grafana", nil)
+
+	// A write cut short leaves half an event and no newline
+	path := logger.currentLogPath()
+	data, _ := os.ReadFile(path)
+	lines := strings.SplitAfter(string(data), "\n")
+	os.WriteFile(path, []byte(lines[0]+lines[1][:len(lines[1])/2]), 0600)
+
+	if err := logger.Log(newEvent(testActor, "app.install", "app/gitea", "Installed app: gitea", "info", nil)); err != nil {
+		t.Fatalf("logging after a torn write failed: %v", err)
+	}
+	logger.LogAppInstall(testActor, "jellyfin", nil)
+
+	events, _ := logger.Query("", 0)
+	if len(events) != 4 || events[1].Action != ChainBreakAction || events[1].PrevHash != "" || events[2].PrevHash != events[1].Hash {
+		t.Fatalf("expected a chain break before the new events, got %+v", events)
+	}
+
+	result, err := logger.Verify()
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if result.OK() || result.Break.Line != 2 || !strings.Contains(result.Break.Reason, "new chain starts at audit.jsonl line 3") {
+		t.Errorf("expected the torn line and the restart to be reported, got %+v", result.Break)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/Achilles1089/sovereign-stack/internal/config"
+	"github.com/Achilles1089/sovereign-stack/internal/filelock"
 )
 
 // Event represents a single audit log entry
@@ -21,24 +22,58 @@ type Event struct {
 	Details   string    `json:"details"`  // human-readable description
 	Severity  string    `json:"severity"` // "info", "warning", "critical"
 	Success   bool      `json:"success"`
+	SourceIP  string    `json:"source_ip,omitempty"` // API caller address; empty for CLI and cron
+	Error     string    `json:"error,omitempty"`     // failure reason when Success is false
+
+	// Hash chain — see chain.go. New fields must be omitempty so older events still verify.
+	PrevHash string `json:"prev_hash,omitempty"`
+	Hash     string `json:"hash,omitempty"`
 }
 
 // Logger writes audit events to a JSONL log file
 type Logger struct {
-	mu      sync.Mutex
-	logDir  string
-	maxSize int64 // max log file size before rotation (bytes)
+	mu        sync.Mutex
+	logDir    string
+	maxSize   int64 // max log file size before rotation (bytes)
+	retention Retention
+	sinks     []*asyncSink
 }
 
-// NewLogger creates an audit logger
+// NewLogger creates an audit logger using the rotation and retention
+// settings from the default config file and SOVEREIGN_AUDIT_* overrides
 func NewLogger() *Logger {
-	return &Logger{
-		logDir:  filepath.Join(config.ConfigDir(), "audit"),
-		maxSize: 10 * 1024 * 1024, // 10MB
+	eff, err := config.Resolve(config.ConfigPath(""), nil)
+	if err != nil {
+		return NewLoggerFromConfig(config.LoadOrDefault(config.ConfigPath("")))
+	}
+	return NewLoggerFromConfig(eff.Config)
+}
+
+// NewLoggerFromConfig creates an audit logger using cfg's audit settings
+func NewLoggerFromConfig(cfg *config.Config) *Logger {
+	maxSize := int64(cfg.Audit.MaxSizeMB) * 1024 * 1024
+	if maxSize <= 0 {
+		maxSize = 10 * 1024 * 1024 // 10MB
+	}
+	l := &Logger{
+		logDir:    filepath.Join(config.ConfigDir(), "audit"),
+		maxSize:   maxSize,
+		retention: RetentionFromConfig(cfg.Audit),
+	}
+
+	// A misconfigured sink must not stop local auditing
+	for _, sc := range cfg.Audit.Sinks {
+		sink, err := startSink(sc, filepath.Join(l.logDir, "spool"))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "audit: ignoring %s sink: %v\n", sc.Type, err)
+			continue
+		}
+		l.sinks = append(l.sinks, sink)
 	}
+	return l
 }
 
-// Log records an audit event
+// Log records an audit event, chaining it to the previous event's hash
 func (l *Logger) Log(event Event) error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
@@ -46,6 +81,7 @@ func (l *Logger) Log(event Event) error {
 	if event.Timestamp.IsZero() {
 		event.Timestamp = time.Now()
 	}
+	event.Timestamp = event.Timestamp.UTC()
 	if event.ID == "" {
 		event.ID = fmt.Sprintf("%d", event.Timestamp.UnixNano())
 	}
@@ -55,11 +91,25 @@ func (l *Logger) Log(event Event) error {
 		return err
 	}
 
+	// The CLI, cron and the dashboard all append — serialise across processes
+	lock, err := filelock.Acquire(filepath.Join(l.logDir, "audit.lock"))
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	prev, corrupt, err := l.lastHash()
+	if err != nil {
+		return err
+	}
+
 	logPath := l.currentLogPath()
 
-	// Rotate if needed
+	// Rotate if needed. Retention failures must not stop the event being
+	// written; the next rotation or `sovereign audit prune` retries them.
 	if info, err := os.Stat(logPath); err == nil && info.Size() > l.maxSize {
 		l.rotate(logPath)
+		l.applyRetention(time.Now())
 	}
 
 	f, err := os.OpenFile(logPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
@@ -68,122 +118,147 @@ func (l *Logger) Log(event Event) error {
 	}
 	defer f.Close()
 
-	data, err := json.Marshal(event)
-	if err != nil {
-		return err
+	var events []Event
+	if corrupt != "" {
+		// An unreadable last event must not stop auditing: start a new chain
+		// with an explicit break, which Verify reports. The newline ends a
+		// line a cut-short write may have left open.
+		brk := chainBreak(event.Timestamp, event.ID, corrupt)
+		if _, err := f.WriteString("\n"); err != nil {
+			return err
+		}
+		events = append(events, brk)
+		prev = brk.Hash
+	}
+	event.PrevHash = prev
+	event.Hash = ComputeHash(event)
+	events = append(events, event)
+
+	for _, ev := range events {
+		data, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(f, "%s\n", data); err != nil {
+			return err
+		}
 	}
 
-	_, err = fmt.Fprintf(f, "%s\n", data)
-	return err
+	// Forward only what was recorded locally; sinks queue and never block
+	for _, sink := range l.sinks {
+		for _, ev := range events {
+			sink.enqueue(ev)
+		}
+	}
+	return nil
 }
 
-// Query returns recent audit events matching the filter
+// Query returns the most recent audit events with an exact action match,
+// oldest first. Use Search for richer filters and pagination.
 func (l *Logger) Query(action string, limit int) ([]Event, error) {
-	logPath := l.currentLogPath()
-	data, err := os.ReadFile(logPath)
+	opts := QueryOptions{Limit: limit, Descending: true}
+	if action != "" {
+		opts.Action = escapeGlob(action)
+	}
+
+	result, err := l.Search(opts)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, nil
-		}
 		return nil, err
 	}
 
-	var events []Event
-	for _, line := range splitLines(string(data)) {
-		if line == "" {
-			continue
-		}
-		var ev Event
-		if err := json.Unmarshal([]byte(line), &ev); err != nil {
-			continue
-		}
-		if action == "" || ev.Action == action {
-			events = append(events, ev)
-		}
+	events := result.Events
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
 	}
+	return events, nil
+}
 
-	// Return last N events (most recent)
-	if limit > 0 && len(events) > limit {
-		events = events[len(events)-limit:]
-	}
+// Record logs the outcome of an action. A non-nil err marks the event failed,
+// stores the error text and raises the severity to at least warning. An event
+// that cannot be written is reported on stderr; the action it describes has
+// already happened, so callers carry on.
+func (l *Logger) Record(actor Actor, action, target, details string, err error) {
+	l.record(newEvent(actor, action, target, details, "info", err))
+}
 
-	return events, nil
+// record logs ev, warning on stderr when it cannot be written
+func (l *Logger) record(ev Event) {
+	if err := l.Log(ev); err != nil {
+		fmt.Fprintf(os.Stderr, "audit: failed to record %s: %v\n", ev.Action, err)
+	}
 }
 
 // LogAppInstall records an app installation
-func (l *Logger) LogAppInstall(appName string, success bool) {
-	l.Log(Event{
-		Action:   "app.install",
-		Actor:    "admin",
-		Target:   appName,
-		Details:  fmt.Sprintf("Installed app: %s", appName),
-		Severity: "info",
-		Success:  success,
-	})
+func (l *Logger) LogAppInstall(actor Actor, appName string, err error) {
+	l.Record(actor, "app.install", "app/"+appName, fmt.Sprintf("Installed app: %s", appName), err)
 }
 
 // LogAppRemove records an app removal
-func (l *Logger) LogAppRemove(appName string) {
-	l.Log(Event{
-		Action:   "app.remove",
-		Actor:    "admin",
-		Target:   appName,
-		Details:  fmt.Sprintf("Removed app: %s", appName),
-		Severity: "info",
-		Success:  true,
-	})
-}
-
-// LogBackup records a backup event
-func (l *Logger) LogBackup(tag string, success bool) {
-	l.Log(Event{
-		Action:   "backup.create",
-		Actor:    "admin",
-		Target:   "backup/" + tag,
-		Details:  fmt.Sprintf("Created backup snapshot (tag: %s)", tag),
-		Severity: "info",
-		Success:  success,
-	})
+func (l *Logger) LogAppRemove(actor Actor, appName string, err error) {
+	l.record(newEvent(actor, "app.remove", "app/"+appName, fmt.Sprintf("Removed app: %s", appName), "warning", err))
+}
+
+// LogBackup records a backup event — action is create, restore, prune or init
+func (l *Logger) LogBackup(actor Actor, action, target string, err error) {
+	sev := "info"
+	if action == "restore" || action == "prune" {
+		sev = "warning"
+	}
+	l.record(newEvent(actor, "backup."+action, "backup/"+target, fmt.Sprintf("Backup %s: %s", action, target), sev, err))
 }
 
 // LogConfigChange records a config modification
-func (l *Logger) LogConfigChange(field string, oldVal, newVal string) {
-	l.Log(Event{
-		Action:   "config.change",
-		Actor:    "admin",
-		Target:   "config/" + field,
-		Details:  fmt.Sprintf("Changed %s: %s → %s", field, oldVal, newVal),
-		Severity: "warning",
-		Success:  true,
-	})
+func (l *Logger) LogConfigChange(actor Actor, field string, oldVal, newVal string) {
+	l.record(newEvent(actor, "config.change", "config/"+field, fmt.Sprintf("Changed %s: %s → %s", field, oldVal, newVal), "warning", nil))
 }
 
 // LogMeshEvent records a mesh networking event
-func (l *Logger) LogMeshEvent(action string, peerName string) {
-	l.Log(Event{
-		Action:   "mesh." + action,
-		Actor:    "admin",
-		Target:   "mesh/" + peerName,
-		Details:  fmt.Sprintf("Mesh %s: %s", action, peerName),
-		Severity: "info",
-		Success:  true,
-	})
-}
-
-// LogAuthEvent records an authentication event
-func (l *Logger) LogAuthEvent(username string, success bool) {
-	sev := "info"
-	if !success {
-		sev = "warning"
+func (l *Logger) LogMeshEvent(actor Actor, action string, network string, err error) {
+	l.Record(actor, "mesh."+action, "mesh/"+network, fmt.Sprintf("Mesh %s: %s", action, network), err)
+}
+
+// LogModelEvent records an AI model pull, delete or switch
+func (l *Logger) LogModelEvent(actor Actor, action, model string, err error) {
+	l.Record(actor, "ai."+action, "model/"+model, fmt.Sprintf("Model %s: %s", action, model), err)
+}
+
+// LogPhoneEvent records a phone LLM start or model switch
+func (l *Logger) LogPhoneEvent(actor Actor, action, model string, err error) {
+	l.Record(actor, "phone."+action, "phone/"+model, fmt.Sprintf("Phone %s: %s", action, model), err)
+}
+
+// LogAuthEvent records a login attempt
+func (l *Logger) LogAuthEvent(actor Actor, err error) {
+	l.Record(actor, "auth.login", "dashboard", fmt.Sprintf("Login attempt by %s", actor.Name), err)
+}
+
+// LogLogout records a dashboard logout
+func (l *Logger) LogLogout(actor Actor) {
+	l.Record(actor, "auth.logout", "dashboard", fmt.Sprintf("Logout by %s", actor.Name), nil)
+}
+
+// LogTokenEvent records an API token being created or revoked
+func (l *Logger) LogTokenEvent(actor Actor, action, tokenID, tokenName string) {
+	l.record(newEvent(actor, "token."+action, "token/"+tokenID, fmt.Sprintf("API token %q %sd", tokenName, action), "warning", nil))
+}
+
+func newEvent(actor Actor, action, target, details, severity string, err error) Event {
+	ev := Event{
+		Action:   action,
+		Actor:    actor.Name,
+		SourceIP: actor.SourceIP,
+		Target:   target,
+		Details:  details,
+		Severity: severity,
+		Success:  err == nil,
+	}
+	if err != nil {
+		ev.Error = err.Error()
+		if severity == "info" {
+			ev.Severity = "warning"
+		}
 	}
-	l.Log(Event{
-		Action:   "auth.login",
-		Actor:    username,
-		Target:   "dashboard",
-		Details:  fmt.Sprintf("Login attempt by %s", username),
-		Severity: sev,
-		Success:  success,
-	})
+	return ev
 }
 
 func (l *Logger) currentLogPath() string {
@@ -191,21 +266,29 @@ func (l *Logger) currentLogPath() string {
 }
 
 func (l *Logger) rotate(path string) {
-	rotated := fmt.Sprintf("%s.%s", path, time.Now().Format("20060102-150405"))
-	os.Rename(path, rotated)
-}
+	// A new segment must sort after every earlier one, including segments
+	// retention has already deleted — reusing a name would reorder the chain
+	newest := ""
+	if files := l.rotatedSegments(); len(files) > 0 {
+		newest = filepath.Base(files[len(files)-1])
+	}
+	if anchor, err := l.loadAnchor(); err == nil && anchor.Through != "" && (newest == "" || segmentLess(newest, anchor.Through)) {
+		newest = anchor.Through
+	}
 
-func splitLines(s string) []string {
-	var lines []string
-	start := 0
-	for i := 0; i < len(s); i++ {
-		if s[i] == '\n' {
-			lines = append(lines, s[start:i])
-			start = i + 1
-		}
+	stamp := time.Now().Format("20060102-150405")
+	if m := segmentPattern.FindStringSubmatch(newest); m != nil && m[1] > stamp {
+		stamp = m[1] // clock went backwards
 	}
-	if start < len(s) {
-		lines = append(lines, s[start:])
+
+	rotated := fmt.Sprintf("%s.%s", path, stamp)
+	for i := 1; exists(rotated) || exists(rotated+".gz") || (newest != "" && !segmentLess(newest, filepath.Base(rotated))); i++ {
+		rotated = fmt.Sprintf("%s.%s-%d", path, stamp, i)
 	}
-	return lines
+	os.Rename(path, rotated)
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
 }
@@ -1,11 +1,14 @@
 package audit
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
 )
 
+var testActor = Actor{Name: "tester"}
+
 func TestLogAndQuery(t *testing.T) {
 	tmpDir := t.TempDir()
 	os.Setenv("HOME", tmpDir)
@@ -16,10 +19,10 @@ func TestLogAndQuery(t *testing.T) {
 	logger.logDir = filepath.Join(tmpDir, "audit")
 
 	// Log some events
-	logger.LogAppInstall("nextcloud", true)
-	logger.LogAppInstall("grafana", true)
-	logger.LogBackup("daily", true)
-	logger.LogConfigChange("domain", "localhost", "myserver.com")
+	logger.LogAppInstall(testActor, "nextcloud", nil)
+	logger.LogAppInstall(testActor, "grafana", nil)
+	logger.LogBackup(testActor, "create", "daily", nil)
+	logger.LogConfigChange(testActor, "domain", "localhost", "myserver.com")
 
 	// Query all
 	events, err := logger.Query("", 0)
@@ -54,8 +57,8 @@ func TestEventSeverity(t *testing.T) {
 	logger := NewLogger()
 	logger.logDir = filepath.Join(tmpDir, "audit")
 
-	logger.LogAuthEvent("admin", true)
-	logger.LogAuthEvent("hacker", false)
+	logger.LogAuthEvent(Actor{Name: "admin"}, nil)
+	logger.LogAuthEvent(Actor{Name: "hacker", SourceIP: "10.0.0.66"}, errors.New("invalid username or password"))
 
 	events, _ := logger.Query("auth.login", 0)
 	if len(events) != 2 {
@@ -72,3 +75,30 @@ func TestEventSeverity(t *testing.T) {
 		t.Errorf("failed login should be warning, got %s", events[1].Severity)
 	}
 }
+
+func TestEventOutcome(t *testing.T) {
+	logger := newTestLogger(t)
+
+	logger.LogAppRemove(Actor{Name: "alice", SourceIP: "192.168.1.20"}, "grafana", errors.New("container not found"))
+
+	events, _ := logger.Query("app.remove", 0)
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(events))
+	}
+	ev := events[0]
+	if ev.Actor != "alice" || ev.SourceIP != "192.168.1.20" {
+		t.Errorf("actor not recorded: %+v", ev)
+	}
+	if ev.Success || ev.Error != "container not found" {
+		t.Errorf("failure not recorded: %+v", ev)
+	}
+}
+
+func TestCLIActor(t *testing.T) {
+	os.Setenv("SOVEREIGN_ACTOR", "cron")
+	defer os.Unsetenv("SOVEREIGN_ACTOR")
+
+	if a := CLIActor(); a.Name != "cron" || a.SourceIP != "" {
+		t.Errorf("expected cron actor, got %+v", a)
+	}
+}
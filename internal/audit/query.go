@@ -0,0 +1,245 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// QueryOptions filters and paginates audit events. Zero values match everything.
+type QueryOptions struct {
+	Since        time.Time // inclusive
+	Until        time.Time // exclusive
+	Actor        string
+	TargetPrefix string
+	Severity     string
+	Success      *bool
+	Action       string // glob, e.g. "app.*"
+	Limit        int    // 0 = no limit
+	Cursor       string // NextCursor from a previous page
+	Descending   bool   // newest first
+}
+
+// QueryResult is one page of events
+type QueryResult struct {
+	Events     []Event `json:"events"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+// position orders events by timestamp, then ID
+type position struct {
+	ts time.Time
+	id string
+}
+
+func (p position) before(o position) bool {
+	if !p.ts.Equal(o.ts) {
+		return p.ts.Before(o.ts)
+	}
+	return p.id < o.id
+}
+
+func encodeCursor(ev Event) string {
+	raw := ev.Timestamp.UTC().Format(time.RFC3339Nano) + "|" + ev.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(cursor string) (position, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return position{}, fmt.Errorf("invalid cursor")
+	}
+	ts, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return position{}, fmt.Errorf("invalid cursor")
+	}
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return position{}, fmt.Errorf("invalid cursor")
+	}
+	return position{ts: t, id: id}, nil
+}
+
+// matches applies every filter except pagination
+func (o *QueryOptions) matches(ev *Event) bool {
+	if !o.Since.IsZero() && ev.Timestamp.Before(o.Since) {
+		return false
+	}
+	if !o.Until.IsZero() && !ev.Timestamp.Before(o.Until) {
+		return false
+	}
+	if o.Actor != "" && ev.Actor != o.Actor {
+		return false
+	}
+	if o.TargetPrefix != "" && !strings.HasPrefix(ev.Target, o.TargetPrefix) {
+		return false
+	}
+	if o.Severity != "" && ev.Severity != o.Severity {
+		return false
+	}
+	if o.Success != nil && ev.Success != *o.Success {
+		return false
+	}
+	if o.Action != "" {
+		if ok, _ := path.Match(o.Action, ev.Action); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Search streams events across rotated segments and the current log in time
+// order, applying filters and cursor pagination. Memory use is bounded by Limit.
+func (l *Logger) Search(opts QueryOptions) (*QueryResult, error) {
+	if opts.Action != "" {
+		if _, err := path.Match(opts.Action, ""); err != nil {
+			return nil, fmt.Errorf("invalid action pattern %q", opts.Action)
+		}
+	}
+
+	var cursor *position
+	if opts.Cursor != "" {
+		pos, err := decodeCursor(opts.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		cursor = &pos
+	}
+
+	files := l.segments()
+	result := &QueryResult{Events: []Event{}}
+
+	if !opts.Descending {
+		// Oldest first: stop as soon as the page is full, peeking one more
+		// event to know whether another page exists
+		done := false
+		for _, file := range files {
+			err := scanSegment(file, func(ev Event) bool {
+				pos := position{ev.Timestamp, ev.ID}
+				if cursor != nil && !cursor.before(pos) {
+					return true
+				}
+				if !opts.matches(&ev) {
+					return true
+				}
+				if opts.Limit > 0 && len(result.Events) == opts.Limit {
+					result.NextCursor = encodeCursor(result.Events[len(result.Events)-1])
+					done = true
+					return false
+				}
+				result.Events = append(result.Events, ev)
+				return true
+			})
+			if err != nil {
+				return nil, err
+			}
+			if done {
+				break
+			}
+		}
+		return result, nil
+	}
+
+	// Newest first: walk segments newest-first, keeping only the last
+	// Limit+1 matches of each segment in a ring
+	var collected []Event // newest first
+	for i := len(files) - 1; i >= 0; i-- {
+		want := 0
+		if opts.Limit > 0 {
+			want = opts.Limit + 1 - len(collected)
+		}
+
+		var ring []Event
+		err := scanSegment(files[i], func(ev Event) bool {
+			pos := position{ev.Timestamp, ev.ID}
+			if cursor != nil && !pos.before(*cursor) {
+				return true
+			}
+			if !opts.matches(&ev) {
+				return true
+			}
+			ring = append(ring, ev)
+			if want > 0 && len(ring) > want {
+				ring = ring[1:]
+			}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for j := len(ring) - 1; j >= 0; j-- {
+			collected = append(collected, ring[j])
+		}
+		if opts.Limit > 0 && len(collected) > opts.Limit {
+			break
+		}
+	}
+
+	if opts.Limit > 0 && len(collected) > opts.Limit {
+		collected = collected[:opts.Limit]
+		result.NextCursor = encodeCursor(collected[len(collected)-1])
+	}
+	result.Events = collected
+	return result, nil
+}
+
+// ParseTime accepts RFC 3339, a date (2006-01-02) or a duration relative to
+// now ("24h" means 24 hours ago)
+func ParseTime(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (use RFC 3339, YYYY-MM-DD or a duration like 24h)", s)
+}
+
+// escapeGlob quotes glob metacharacters so a literal action matches exactly
+func escapeGlob(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`)
+	return r.Replace(s)
+}
+
+// scanSegment decodes a log file line by line, calling fn until it returns false.
+// Compressed segments are read transparently; malformed lines are skipped.
+func scanSegment(file string, fn func(Event) bool) error {
+	f, err := openSegment(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var ev Event
+		if err := json.Unmarshal(line, &ev); err != nil {
+			continue
+		}
+		if !fn(ev) {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
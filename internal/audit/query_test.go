@@ -0,0 +1,109 @@
+package audit
+
+import (
+	"testing"
+	"time"
+)
+
+func seedEvents(t *testing.T, logger *Logger) time.Time {
+	t.Helper()
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	events := []Event{
+		{Action: "app.install", Actor: "alice", Target: "app/nextcloud", Severity: "info", Success: true},
+		{Action: "app.remove", Actor: "bob", Target: "app/grafana", Severity: "warning", Success: true},
+		{Action: "backup.create", Actor: "cron", Target: "backup/auto", Severity: "info", Success: false},
+		{Action: "app.install", Actor: "alice", Target: "app/gitea", Severity: "info", Success: true},
+		{Action: "auth.login", Actor: "mallory", Target: "dashboard", Severity: "warning", Success: false},
+	}
+	for i, ev := range events {
+		ev.Timestamp = base.Add(time.Duration(i) * time.Minute)
+		if err := logger.Log(ev); err != nil {
+			t.Fatalf("Log failed: %v", err)
+		}
+	}
+	return base
+}
+
+func TestSearchFilters(t *testing.T) {
+	logger := newTestLogger(t)
+	logger.maxSize = 300 // force events across several rotated segments
+	base := seedEvents(t, logger)
+
+	if len(logger.segments()) < 2 {
+		t.Fatal("expected rotated segments")
+	}
+
+	failed := false
+	tests := []struct {
+		name     string
+		opts     QueryOptions
+		expected int
+	}{
+		{"all", QueryOptions{}, 5},
+		{"action glob", QueryOptions{Action: "app.*"}, 3},
+		{"actor", QueryOptions{Actor: "alice"}, 2},
+		{"target prefix", QueryOptions{TargetPrefix: "app/g"}, 2},
+		{"severity", QueryOptions{Severity: "warning"}, 2},
+		{"failures", QueryOptions{Success: &failed}, 2},
+		{"since", QueryOptions{Since: base.Add(2 * time.Minute)}, 3},
+		{"until", QueryOptions{Until: base.Add(2 * time.Minute)}, 2},
+		{"combined", QueryOptions{Action: "app.install", Since: base.Add(time.Minute)}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := logger.Search(tt.opts)
+			if err != nil {
+				t.Fatalf("Search failed: %v", err)
+			}
+			if len(result.Events) != tt.expected {
+				t.Errorf("expected %d events, got %d", tt.expected, len(result.Events))
+			}
+		})
+	}
+}
+
+func TestSearchPagination(t *testing.T) {
+	for _, desc := range []bool{false, true} {
+		logger := newTestLogger(t)
+		logger.maxSize = 300
+		seedEvents(t, logger)
+
+		var seen []string
+		cursor := ""
+		for pages := 0; pages < 10; pages++ {
+			result, err := logger.Search(QueryOptions{Limit: 2, Cursor: cursor, Descending: desc})
+			if err != nil {
+				t.Fatalf("Search failed: %v", err)
+			}
+			for _, ev := range result.Events {
+				seen = append(seen, ev.Target)
+			}
+			if result.NextCursor == "" {
+				break
+			}
+			cursor = result.NextCursor
+		}
+
+		if len(seen) != 5 {
+			t.Fatalf("descending=%v: expected 5 events over all pages, got %d", desc, len(seen))
+		}
+		first, last := "app/nextcloud", "dashboard"
+		if desc {
+			first, last = last, first
+		}
+		if seen[0] != first || seen[4] != last {
+			t.Errorf("descending=%v: wrong order %v", desc, seen)
+		}
+	}
+}
+
+func TestSearchInvalidInput(t *testing.T) {
+	logger := newTestLogger(t)
+	if _, err := logger.Search(QueryOptions{Action: "app.["}); err == nil {
+		t.Error("expected error for invalid glob")
+	}
+	if _, err := logger.Search(QueryOptions{Cursor: "!!"}); err == nil {
+		t.Error("expected error for invalid cursor")
+	}
+}
@@ -0,0 +1,298 @@
+package audit
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Achilles1089/sovereign-stack/internal/config"
+	"github.com/Achilles1089/sovereign-stack/internal/filelock"
+)
+
+// Retention limits how much rotated audit history is kept on disk.
+// Zero values disable the corresponding limit.
+type Retention struct {
+	MaxAge       time.Duration // rotated segments last written before this are deleted
+	MaxTotalSize int64         // bytes across all segments, including the current log
+	MaxFiles     int           // rotated segments, not counting the current log
+	Compress     bool          // gzip segments when they are rotated
+}
+
+// RetentionFromConfig converts the audit section of config.yaml
+func RetentionFromConfig(c config.AuditConfig) Retention {
+	return Retention{
+		MaxAge:       time.Duration(c.MaxAgeDays) * 24 * time.Hour,
+		MaxTotalSize: int64(c.MaxTotalMB) * 1024 * 1024,
+		MaxFiles:     c.MaxFiles,
+		Compress:     c.Compress,
+	}
+}
+
+// Anchor records where the retained history starts once older segments have
+// been pruned, so the hash chain can still be verified from that point on
+type Anchor struct {
+	PrevHash  string    `json:"prev_hash"` // hash of the newest pruned event
+	Through   string    `json:"through"`   // newest pruned segment
+	Segments  int       `json:"segments"`  // segments pruned so far
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// RetentionResult reports what a retention pass changed
+type RetentionResult struct {
+	Compressed []string `json:"compressed"`
+	Removed    []string `json:"removed"`
+	FreedBytes int64    `json:"freed_bytes"`
+}
+
+// ApplyRetention compresses any uncompressed rotated segments (when enabled)
+// and deletes the oldest segments that exceed the retention limits
+func (l *Logger) ApplyRetention() (*RetentionResult, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lock, err := filelock.Acquire(filepath.Join(l.logDir, "audit.lock"))
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Release()
+
+	return l.applyRetention(time.Now())
+}
+
+// applyRetention does the work of ApplyRetention; callers hold the locks
+func (l *Logger) applyRetention(now time.Time) (*RetentionResult, error) {
+	result := &RetentionResult{Compressed: []string{}, Removed: []string{}}
+
+	if l.retention.Compress {
+		for _, path := range l.rotatedSegments() {
+			if strings.HasSuffix(path, ".gz") {
+				continue
+			}
+			if err := compressSegment(path); err != nil {
+				return result, err
+			}
+			result.Compressed = append(result.Compressed, filepath.Base(path)+".gz")
+		}
+	}
+
+	files := l.rotatedSegments()
+	infos := make([]os.FileInfo, len(files))
+	var total int64
+	for i, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			return result, err
+		}
+		infos[i] = info
+		total += info.Size()
+	}
+	if info, err := os.Stat(l.currentLogPath()); err == nil {
+		total += info.Size()
+	}
+
+	// Oldest segments go first; stop at the first one every limit allows
+	prune := 0
+	for i, info := range infos {
+		expired := l.retention.MaxAge > 0 && now.Sub(info.ModTime()) > l.retention.MaxAge
+		tooMany := l.retention.MaxFiles > 0 && len(files)-i > l.retention.MaxFiles
+		tooBig := l.retention.MaxTotalSize > 0 && total > l.retention.MaxTotalSize
+		if !expired && !tooMany && !tooBig {
+			break
+		}
+		total -= info.Size()
+		prune++
+	}
+	if prune == 0 {
+		return result, nil
+	}
+
+	// Move the anchor forward before deleting anything, so an interrupted
+	// prune leaves a history that still verifies
+	anchor, err := l.loadAnchor()
+	if err != nil {
+		return result, err
+	}
+	for i := prune - 1; i >= 0; i-- {
+		line, err := lastLine(files[i])
+		if err != nil {
+			return result, err
+		}
+		if line == nil {
+			continue
+		}
+		var ev Event
+		if err := json.Unmarshal(line, &ev); err != nil {
+			return result, fmt.Errorf("audit log %s: last event is corrupt: %w", filepath.Base(files[i]), err)
+		}
+		anchor.PrevHash = ev.Hash
+		break
+	}
+	anchor.Through = filepath.Base(files[prune-1])
+	anchor.Segments += prune
+	anchor.UpdatedAt = now.UTC()
+	if err := l.saveAnchor(anchor); err != nil {
+		return result, err
+	}
+
+	for i := 0; i < prune; i++ {
+		if err := os.Remove(files[i]); err != nil && !os.IsNotExist(err) {
+			return result, err
+		}
+		result.Removed = append(result.Removed, filepath.Base(files[i]))
+		result.FreedBytes += infos[i].Size()
+	}
+	return result, nil
+}
+
+func (l *Logger) anchorPath() string {
+	return filepath.Join(l.logDir, "audit.anchor")
+}
+
+// loadAnchor returns the retention anchor, or an empty one if nothing has been pruned
+func (l *Logger) loadAnchor() (*Anchor, error) {
+	data, err := os.ReadFile(l.anchorPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Anchor{}, nil
+		}
+		return nil, err
+	}
+	var anchor Anchor
+	if err := json.Unmarshal(data, &anchor); err != nil {
+		return nil, fmt.Errorf("failed to parse audit anchor: %w", err)
+	}
+	return &anchor, nil
+}
+
+func (l *Logger) saveAnchor(anchor *Anchor) error {
+	data, err := json.MarshalIndent(anchor, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := l.anchorPath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, l.anchorPath())
+}
+
+// segmentPattern matches rotated segment names: audit.jsonl.<stamp>[-N][.gz]
+var segmentPattern = regexp.MustCompile(`^audit\.jsonl\.(\d{8}-\d{6})(?:-(\d+))?(\.gz)?$`)
+
+// rotatedSegments returns rotated segment paths oldest-first, compressed or not
+func (l *Logger) rotatedSegments() []string {
+	entries, err := os.ReadDir(l.logDir)
+	if err != nil {
+		return nil
+	}
+
+	var files []string
+	for _, e := range entries {
+		if !e.IsDir() && segmentPattern.MatchString(e.Name()) {
+			files = append(files, filepath.Join(l.logDir, e.Name()))
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return segmentLess(filepath.Base(files[i]), filepath.Base(files[j]))
+	})
+	return files
+}
+
+// segmentLess orders segment names by rotation time, then collision suffix
+func segmentLess(a, b string) bool {
+	ma, mb := segmentPattern.FindStringSubmatch(a), segmentPattern.FindStringSubmatch(b)
+	if ma == nil || mb == nil {
+		return a < b
+	}
+	if ma[1] != mb[1] {
+		return ma[1] < mb[1]
+	}
+	na, _ := strconv.Atoi(ma[2])
+	nb, _ := strconv.Atoi(mb[2])
+	return na < nb
+}
+
+// compressSegment gzips a rotated segment in place, keeping its modification
+// time so age-based retention still sees when it was last written
+func compressSegment(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	dst := path + ".gz"
+	if exists(dst) {
+		return fmt.Errorf("failed to compress %s: %s already exists", filepath.Base(path), filepath.Base(dst))
+	}
+	tmp := dst + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(out)
+	gz.Name = filepath.Base(path)
+	gz.ModTime = info.ModTime()
+	_, err = io.Copy(gz, src)
+	if err == nil {
+		err = gz.Close()
+	}
+	if err == nil {
+		err = out.Sync()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to compress %s: %w", filepath.Base(path), err)
+	}
+
+	if err := os.Rename(tmp, dst); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	os.Chtimes(dst, info.ModTime(), info.ModTime())
+	return os.Remove(path)
+}
+
+// openSegment opens a segment for reading, decompressing .gz files transparently
+func openSegment(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		return f, nil
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("audit log %s: %w", filepath.Base(path), err)
+	}
+	return &gzipSegment{Reader: gz, file: f}, nil
+}
+
+type gzipSegment struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (g *gzipSegment) Close() error {
+	g.Reader.Close()
+	return g.file.Close()
+}
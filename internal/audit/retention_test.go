@@ -0,0 +1,111 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotatedSegmentsCompressed(t *testing.T) {
+	logger := newTestLogger(t)
+	logger.maxSize = 1 // rotate before every write
+	logger.retention = Retention{Compress: true}
+
+	for _, name := range []string{"nextcloud", "grafana", "gitea", "jellyfin"} {
+		logger.LogAppInstall(testActor, name, nil)
+	}
+
+	rotated := logger.rotatedSegments()
+	if len(rotated) != 3 {
+		t.Fatalf("expected 3 rotated segments, got %d", len(rotated))
+	}
+	for _, path := range rotated {
+		if !strings.HasSuffix(path, ".gz") {
+			t.Errorf("segment %s was not compressed", filepath.Base(path))
+		}
+	}
+
+	events, err := logger.Query("", 0)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(events) != 4 || events[0].Target != "app/nextcloud" || events[3].Target != "app/jellyfin" {
+		t.Fatalf("expected 4 events in order across compressed segments, got %+v", events)
+	}
+
+	result, err := logger.Verify()
+	if err != nil || !result.OK() || result.Events != 4 {
+		t.Fatalf("expected chain to verify through compressed segments, got %+v (%v)", result, err)
+	}
+}
+
+func TestRetentionMaxFiles(t *testing.T) {
+	logger := newTestLogger(t)
+	logger.maxSize = 1
+	logger.retention = Retention{MaxFiles: 2, Compress: true}
+
+	for i := 0; i < 6; i++ {
+		logger.LogAppInstall(testActor, "app", nil)
+	}
+
+	if n := len(logger.rotatedSegments()); n != 2 {
+		t.Fatalf("expected 2 rotated segments to be kept, got %d", n)
+	}
+
+	result, err := logger.Verify()
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if !result.OK() {
+		t.Fatalf("pruned history should still verify: %+v", result.Break)
+	}
+	if result.Pruned != 3 || result.Events != 3 {
+		t.Errorf("expected 3 pruned segments and 3 remaining events, got %+v", result)
+	}
+}
+
+func TestRetentionMaxAge(t *testing.T) {
+	logger := newTestLogger(t)
+	logger.maxSize = 1
+
+	for i := 0; i < 3; i++ {
+		logger.LogAppInstall(testActor, "app", nil)
+	}
+
+	old := time.Now().Add(-48 * time.Hour)
+	oldest := logger.rotatedSegments()[0]
+	os.Chtimes(oldest, old, old)
+
+	logger.retention = Retention{MaxAge: 24 * time.Hour}
+	result, err := logger.ApplyRetention()
+	if err != nil {
+		t.Fatalf("ApplyRetention failed: %v", err)
+	}
+	if len(result.Removed) != 1 || result.Removed[0] != filepath.Base(oldest) {
+		t.Fatalf("expected only the expired segment to be removed, got %v", result.Removed)
+	}
+
+	if v, _ := logger.Verify(); !v.OK() || v.Events != 2 {
+		t.Errorf("expected 2 verified events after pruning, got %+v", v)
+	}
+}
+
+func TestSegmentOrder(t *testing.T) {
+	names := []string{
+		"audit.jsonl.20260101-120000-10.gz",
+		"audit.jsonl.20260101-120000",
+		"audit.jsonl.20251231-235959.gz",
+		"audit.jsonl.20260101-120000-2",
+	}
+	want := []string{names[2], names[1], names[3], names[0]}
+
+	for i := range want {
+		for j := range want {
+			if got := segmentLess(want[i], want[j]); got != (i < j) {
+				t.Errorf("segmentLess(%s, %s) = %v", want[i], want[j], got)
+			}
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Achilles1089/sovereign-stack/internal/config"
+)
+
+// ProductVersion is reported in CEF headers; the CLI sets it from its build version
+var ProductVersion = "dev"
+
+// sinkQueueSize bounds how many events may wait for a slow sink before new
+// ones are spooled or dropped
+const sinkQueueSize = 1024
+
+// Sink receives a copy of every audit event after it has been written to the
+// local log. Send is only ever called from one goroutine per sink.
+type Sink interface {
+	Name() string
+	Send(ev Event) error
+	Close() error
+}
+
+// spooler is implemented by sinks that can park events on disk when their
+// receiver is unavailable
+type spooler interface {
+	Spool(ev Event) error
+}
+
+// NewSink builds a sink from its config.yaml entry. spoolDir holds on-disk
+// queues for sinks that need them.
+func NewSink(c config.AuditSinkConfig, spoolDir string) (Sink, error) {
+	switch c.Format {
+	case "", "json", "cef":
+	default:
+		return nil, fmt.Errorf("unknown audit sink format %q (use json or cef)", c.Format)
+	}
+
+	switch c.Type {
+	case "syslog":
+		return newSyslogSink(c)
+	case "webhook":
+		return newWebhookSink(c, spoolDir)
+	case "file":
+		if c.Path == "" {
+			return nil, fmt.Errorf("file audit sink needs a path")
+		}
+		return &fileSink{path: c.Path, format: c.Format}, nil
+	default:
+		return nil, fmt.Errorf("unknown audit sink type %q (use syslog, webhook or file)", c.Type)
+	}
+}
+
+// asyncSink feeds a sink from a buffered queue so a slow or unreachable
+// receiver never holds up Logger.Log
+type asyncSink struct {
+	sink    Sink
+	queue   chan Event
+	pending sync.WaitGroup
+
+	mu      sync.Mutex
+	lastErr error
+	dropped int
+}
+
+// activeSinks holds every sink started in this process, keyed by its config,
+// so loggers built from the same config share one queue and connection
+var activeSinks struct {
+	sync.Mutex
+	byKey map[string]*asyncSink
+	list  []*asyncSink
+}
+
+// startSink returns the running sink for a config entry, starting it on first use
+func startSink(c config.AuditSinkConfig, spoolDir string) (*asyncSink, error) {
+	key := fmt.Sprintf("%s|%+v", spoolDir, c)
+
+	activeSinks.Lock()
+	defer activeSinks.Unlock()
+	if a, ok := activeSinks.byKey[key]; ok {
+		return a, nil
+	}
+
+	s, err := NewSink(c, spoolDir)
+	if err != nil {
+		return nil, err
+	}
+	a := &asyncSink{sink: s, queue: make(chan Event, sinkQueueSize)}
+	go a.run()
+
+	if activeSinks.byKey == nil {
+		activeSinks.byKey = make(map[string]*asyncSink)
+	}
+	activeSinks.byKey[key] = a
+	activeSinks.list = append(activeSinks.list, a)
+	return a, nil
+}
+
+func (a *asyncSink) run() {
+	for ev := range a.queue {
+		if err := a.sink.Send(ev); err != nil {
+			a.fail(err)
+		}
+		a.pending.Done()
+	}
+}
+
+// enqueue hands an event to the sink without blocking
+func (a *asyncSink) enqueue(ev Event) {
+	a.pending.Add(1)
+	select {
+	case a.queue <- ev:
+	default:
+		a.pending.Done()
+		a.overflow(ev)
+	}
+}
+
+// overflow parks an event the queue had no room for
+func (a *asyncSink) overflow(ev Event) {
+	if sp, ok := a.sink.(spooler); ok {
+		if err := sp.Spool(ev); err == nil {
+			return
+		}
+	}
+	a.fail(fmt.Errorf("queue full, dropped event %s", ev.ID))
+}
+
+func (a *asyncSink) fail(err error) {
+	a.mu.Lock()
+	a.lastErr = err
+	a.dropped++
+	a.mu.Unlock()
+	fmt.Fprintf(os.Stderr, "audit: %s sink: %v\n", a.sink.Name(), err)
+}
+
+// flush waits up to timeout for queued events to be delivered. Events still
+// queued afterwards are spooled when the sink supports it.
+func (a *asyncSink) flush(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		a.pending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+	}
+
+	for {
+		select {
+		case ev := <-a.queue:
+			a.overflow(ev)
+			a.pending.Done()
+		default:
+			return false
+		}
+	}
+}
+
+// Flush waits up to timeout for every sink started in this process to drain.
+// Short-lived commands call this before exiting so forwarded events are not lost.
+func Flush(timeout time.Duration) bool {
+	activeSinks.Lock()
+	list := append([]*asyncSink(nil), activeSinks.list...)
+	activeSinks.Unlock()
+
+	deadline := time.Now().Add(timeout)
+	ok := true
+	for _, a := range list {
+		if !a.flush(time.Until(deadline)) {
+			ok = false
+		}
+	}
+	return ok
+}
+
+// SinkStatus reports the health of one forwarding sink
+type SinkStatus struct {
+	Name      string `json:"name"`
+	Queued    int    `json:"queued"`
+	Dropped   int    `json:"dropped"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// SinkStatus reports queue depth and failures for the logger's sinks
+func (l *Logger) SinkStatus() []SinkStatus {
+	status := make([]SinkStatus, 0, len(l.sinks))
+	for _, a := range l.sinks {
+		a.mu.Lock()
+		st := SinkStatus{Name: a.sink.Name(), Queued: len(a.queue), Dropped: a.dropped}
+		if a.lastErr != nil {
+			st.LastError = a.lastErr.Error()
+		}
+		a.mu.Unlock()
+		status = append(status, st)
+	}
+	return status
+}
+
+// fileSink appends events to a local JSONL (or CEF) file, e.g. one picked up
+// by a log shipper. The file is reopened for every event so external
+// rotation is safe.
+type fileSink struct {
+	path   string
+	format string
+}
+
+func (s *fileSink) Name() string { return "file:" + s.path }
+
+func (s *fileSink) Send(ev Event) error {
+	line, err := formatEvent(s.format, ev)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (s *fileSink) Close() error { return nil }
+
+// formatEvent renders an event as a single JSON object or CEF line
+func formatEvent(format string, ev Event) ([]byte, error) {
+	if format == "cef" {
+		return []byte(formatCEF(ev)), nil
+	}
+	return json.Marshal(ev)
+}
+
+// formatCEF renders an event in ArcSight Common Event Format
+func formatCEF(ev Event) string {
+	header := strings.NewReplacer(`\`, `\\`, "|", `\|`, "\n", " ", "\r", " ")
+	ext := strings.NewReplacer(`\`, `\\`, "=", `\=`, "\n", `\n`, "\r", `\r`)
+
+	outcome := "success"
+	if !ev.Success {
+		outcome = "failure"
+	}
+
+	fields := [][2]string{
+		{"rt", strconv.FormatInt(ev.Timestamp.UnixMilli(), 10)},
+		{"act", ev.Action},
+		{"suser", ev.Actor},
+		{"src", ev.SourceIP},
+		{"outcome", outcome},
+		{"cs1Label", "target"},
+		{"cs1", ev.Target},
+		{"externalId", ev.ID},
+		{"msg", ev.Details},
+		{"reason", ev.Error},
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "CEF:0|Sovereign Stack|sovereign|%s|%s|%s|%d|",
+		header.Replace(ProductVersion), header.Replace(ev.Action), header.Replace(ev.Details), cefSeverity(ev.Severity))
+	first := true
+	for _, f := range fields {
+		if f[1] == "" {
+			continue
+		}
+		if !first {
+			b.WriteByte(' ')
+		}
+		first = false
+		b.WriteString(f[0])
+		b.WriteByte('=')
+		b.WriteString(ext.Replace(f[1]))
+	}
+	return b.String()
+}
+
+func cefSeverity(severity string) int {
+	switch severity {
+	case "critical":
+		return 9
+	case "warning":
+		return 6
+	default:
+		return 3
+	}
+}
@@ -0,0 +1,184 @@
+package audit
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Achilles1089/sovereign-stack/internal/config"
+)
+
+// newSinkLogger builds a logger in a temp home with the given sinks configured
+func newSinkLogger(t *testing.T, sinks ...config.AuditSinkConfig) *Logger {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+
+	cfg := config.DefaultConfig()
+	cfg.Audit.Sinks = sinks
+	logger := NewLoggerFromConfig(cfg)
+	if len(logger.sinks) != len(sinks) {
+		t.Fatalf("expected %d sinks, got %d", len(sinks), len(logger.sinks))
+	}
+	return logger
+}
+
+func TestSyslogSinkUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("udp unavailable: %v", err)
+	}
+	defer pc.Close()
+
+	logger := newSinkLogger(t, config.AuditSinkConfig{Type: "syslog", Network: "udp", Address: pc.LocalAddr().String()})
+	logger.LogAppInstall(Actor{Name: "alice", SourceIP: "192.168.1.20"}, "gitea", nil)
+	Flush(5 * time.Second)
+
+	buf := make([]byte, 8192)
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("no syslog message received: %v", err)
+	}
+	msg := string(buf[:n])
+
+	// local0 (16) * 8 + informational (6)
+	if !strings.HasPrefix(msg, "<134>1 ") {
+		t.Errorf("unexpected PRI/version: %q", msg)
+	}
+	for _, want := range []string{" sovereign ", " app.install ", `actor="alice"`, `src="192.168.1.20"`, `"target":"app/gitea"`} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("syslog message missing %s: %q", want, msg)
+		}
+	}
+}
+
+func TestWebhookSinkSpoolsWhileDown(t *testing.T) {
+	webhookBackoff = time.Millisecond
+	defer func() { webhookBackoff = time.Second }()
+
+	var (
+		mu       sync.Mutex
+		down     = true
+		received []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if down {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var ev Event
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &ev)
+		received = append(received, ev.Target)
+	}))
+	defer srv.Close()
+
+	logger := newSinkLogger(t, config.AuditSinkConfig{Type: "webhook", URL: srv.URL})
+	logger.LogAppInstall(testActor, "nextcloud", nil)
+	logger.LogAppInstall(testActor, "grafana", nil)
+	Flush(5 * time.Second)
+
+	spool := logger.sinks[0].sink.(*webhookSink).spool
+	if _, err := os.Stat(spool); err != nil {
+		t.Fatalf("expected undelivered events to be spooled: %v", err)
+	}
+
+	mu.Lock()
+	down = false
+	mu.Unlock()
+
+	logger.LogAppInstall(testActor, "gitea", nil)
+	Flush(5 * time.Second)
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"app/nextcloud", "app/grafana", "app/gitea"}
+	if strings.Join(received, ",") != strings.Join(want, ",") {
+		t.Errorf("expected spooled events replayed in order, got %v", received)
+	}
+	if _, err := os.Stat(spool); !os.IsNotExist(err) {
+		t.Error("spool should be removed once drained")
+	}
+}
+
+func TestFileSinkCEF(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "forward", "audit.cef")
+	logger := newSinkLogger(t, config.AuditSinkConfig{Type: "file", Format: "cef", Path: out})
+
+	logger.LogAppRemove(Actor{Name: "bob"}, "a|b=c", os.ErrPermission)
+	Flush(5 * time.Second)
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("file sink wrote nothing: %v", err)
+	}
+	line := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(line, "CEF:0|Sovereign Stack|sovereign|") {
+		t.Errorf("unexpected CEF header: %q", line)
+	}
+	for _, want := range []string{"|app.remove|Removed app: a\\|b=c|6|", "suser=bob", "outcome=failure", "cs1=app/a|b\\=c"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("CEF line missing %s: %q", want, line)
+		}
+	}
+}
+
+func TestSlowSinkNeverBlocksLog(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+
+	logger := newTestLogger(t)
+	logger.sinks = []*asyncSink{{sink: blockingSink{block}, queue: make(chan Event, 4)}}
+	go logger.sinks[0].run()
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 20; i++ {
+			logger.LogAppInstall(testActor, "app", nil)
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Log blocked on a stalled sink")
+	}
+	if st := logger.SinkStatus()[0]; st.Dropped == 0 {
+		t.Errorf("expected overflowing events to be counted as dropped, got %+v", st)
+	}
+}
+
+func TestNewSinkInvalid(t *testing.T) {
+	for _, c := range []config.AuditSinkConfig{
+		{Type: "kafka"},
+		{Type: "syslog", Network: "udp"},
+		{Type: "syslog", Address: "localhost:514", Facility: "mail2"},
+		{Type: "webhook", URL: "ftp://example.com"},
+		{Type: "file"},
+		{Type: "file", Path: "/tmp/x", Format: "xml"},
+	} {
+		if _, err := NewSink(c, t.TempDir()); err == nil {
+			t.Errorf("expected %+v to be rejected", c)
+		}
+	}
+}
+
+type blockingSink struct{ block chan struct{} }
+
+func (b blockingSink) Name() string { return "blocking" }
+func (b blockingSink) Send(Event) error {
+	<-b.block
+	return nil
+}
+func (b blockingSink) Close() error { return nil }
@@ -0,0 +1,197 @@
+package audit
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Achilles1089/sovereign-stack/internal/config"
+)
+
+// syslogFacilities maps facility names to their RFC 5424 codes
+var syslogFacilities = map[string]int{
+	"user": 1, "daemon": 3, "auth": 4, "authpriv": 10,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// syslogSDID is the structured-data ID for event fields. 32473 is the
+// enterprise number RFC 5612 reserves for examples and private use.
+const syslogSDID = "sovereign@32473"
+
+// syslogSink forwards events as RFC 5424 messages over UDP, TCP or a unix socket
+type syslogSink struct {
+	network  string
+	address  string
+	facility int
+	format   string
+	hostname string
+	conn     net.Conn
+	stream   bool // TCP-style transport needing octet-counting framing
+}
+
+func newSyslogSink(c config.AuditSinkConfig) (*syslogSink, error) {
+	network := c.Network
+	if network == "" {
+		network = "udp"
+	}
+	if network != "udp" && network != "tcp" && network != "unix" {
+		return nil, fmt.Errorf("unknown syslog network %q (use udp, tcp or unix)", network)
+	}
+
+	address := c.Address
+	if address == "" {
+		if network == "unix" {
+			address = "/dev/log"
+		} else {
+			return nil, fmt.Errorf("syslog audit sink needs an address")
+		}
+	}
+
+	name := c.Facility
+	if name == "" {
+		name = "local0"
+	}
+	facility, ok := syslogFacilities[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown syslog facility %q", name)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+
+	return &syslogSink{
+		network:  network,
+		address:  address,
+		facility: facility,
+		format:   c.Format,
+		hostname: hostname,
+	}, nil
+}
+
+func (s *syslogSink) Name() string { return "syslog:" + s.network + "://" + s.address }
+
+func (s *syslogSink) Send(ev Event) error {
+	msg, err := s.message(ev)
+	if err != nil {
+		return err
+	}
+
+	// One reconnect covers a receiver that restarted since the last event
+	for attempt := 0; ; attempt++ {
+		if s.conn == nil {
+			if err = s.dial(); err != nil {
+				return err
+			}
+		}
+
+		frame := msg
+		if s.stream {
+			frame = fmt.Sprintf("%d %s", len(msg), msg) // RFC 6587 octet counting
+		}
+		s.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		if _, err = s.conn.Write([]byte(frame)); err == nil {
+			return nil
+		}
+
+		s.conn.Close()
+		s.conn = nil
+		if attempt == 1 {
+			return err
+		}
+	}
+}
+
+func (s *syslogSink) dial() error {
+	if s.network == "unix" {
+		// Local daemons (/dev/log) usually listen on a datagram socket
+		if conn, err := net.DialTimeout("unixgram", s.address, 5*time.Second); err == nil {
+			s.conn, s.stream = conn, false
+			return nil
+		}
+	}
+	conn, err := net.DialTimeout(s.network, s.address, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	s.conn, s.stream = conn, s.network != "udp"
+	return nil
+}
+
+func (s *syslogSink) Close() error {
+	if s.conn != nil {
+		return s.conn.Close()
+	}
+	return nil
+}
+
+// message renders an RFC 5424 syslog line:
+// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [SD] MSG
+func (s *syslogSink) message(ev Event) (string, error) {
+	body, err := formatEvent(s.format, ev)
+	if err != nil {
+		return "", err
+	}
+
+	pri := s.facility*8 + syslogSeverity(ev.Severity)
+	return fmt.Sprintf("<%d>1 %s %s sovereign %d %s %s %s",
+		pri,
+		ev.Timestamp.UTC().Format("2006-01-02T15:04:05.000000Z"),
+		s.hostname,
+		os.Getpid(),
+		syslogMsgID(ev.Action),
+		structuredData(ev),
+		body,
+	), nil
+}
+
+func structuredData(ev Event) string {
+	esc := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "]", `\]`)
+
+	var b strings.Builder
+	b.WriteString("[" + syslogSDID)
+	param := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, ` %s="%s"`, name, esc.Replace(value))
+		}
+	}
+	param("id", ev.ID)
+	param("actor", ev.Actor)
+	param("target", ev.Target)
+	param("src", ev.SourceIP)
+	param("success", fmt.Sprintf("%t", ev.Success))
+	b.WriteString("]")
+	return b.String()
+}
+
+// syslogMsgID makes an action usable as a MSGID: printable ASCII, no spaces, max 32
+func syslogMsgID(action string) string {
+	id := strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return '_'
+		}
+		return r
+	}, action)
+	if id == "" {
+		return "-"
+	}
+	if len(id) > 32 {
+		id = id[:32]
+	}
+	return id
+}
+
+func syslogSeverity(severity string) int {
+	switch severity {
+	case "critical":
+		return 2
+	case "warning":
+		return 4
+	default:
+		return 6 // informational
+	}
+}
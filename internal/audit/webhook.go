@@ -0,0 +1,242 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Achilles1089/sovereign-stack/internal/config"
+	"github.com/Achilles1089/sovereign-stack/internal/filelock"
+)
+
+// maxSpoolSize caps the on-disk queue of a webhook whose receiver is down
+const maxSpoolSize = 16 * 1024 * 1024
+
+// webhookRetries is how many times delivery is retried before spooling;
+// the backoff doubles after each attempt
+var (
+	webhookRetries = 3
+	webhookBackoff = time.Second
+)
+
+// webhookSink POSTs each event to an HTTP endpoint. Events that cannot be
+// delivered are appended to a spool file and replayed, in order, before the
+// next event once the receiver is back.
+type webhookSink struct {
+	url     string
+	headers map[string]string
+	format  string
+	spool   string
+	client  *http.Client
+}
+
+// permanentError marks a rejection that retrying will not fix
+type permanentError struct{ error }
+
+func newWebhookSink(c config.AuditSinkConfig, spoolDir string) (*webhookSink, error) {
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("webhook audit sink needs an http(s) url, got %q", c.URL)
+	}
+
+	sum := sha256.Sum256([]byte(c.URL))
+	return &webhookSink{
+		url:     c.URL,
+		headers: c.Headers,
+		format:  c.Format,
+		spool:   filepath.Join(spoolDir, "webhook-"+hex.EncodeToString(sum[:4])+".jsonl"),
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (s *webhookSink) Name() string { return "webhook:" + s.url }
+
+func (s *webhookSink) Send(ev Event) error {
+	// Older events go first; while they cannot be delivered new ones queue behind them
+	if err := s.replay(); err != nil {
+		return s.Spool(ev)
+	}
+
+	err := s.deliver(ev)
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(permanentError); ok {
+		return err
+	}
+	return s.Spool(ev)
+}
+
+func (s *webhookSink) Close() error { return nil }
+
+// Spool appends an event to the on-disk queue
+func (s *webhookSink) Spool(ev Event) error {
+	lock, err := filelock.Acquire(s.spool + ".lock")
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	if info, err := os.Stat(s.spool); err == nil && info.Size() > maxSpoolSize {
+		return fmt.Errorf("spool full, dropped event %s", ev.ID)
+	}
+
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.spool, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// replay delivers spooled events oldest-first, keeping whatever is left when
+// the receiver fails again. The spool lock is only held while reading and
+// trimming the file, so Spool never waits on the network. Delivery is at
+// least once: two processes replaying together may both send an event.
+func (s *webhookSink) replay() error {
+	if _, err := os.Stat(s.spool); os.IsNotExist(err) {
+		return nil
+	}
+
+	data, err := s.readSpool()
+	if err != nil || len(data) == 0 {
+		return err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	delivered := 0
+	var sendErr error
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		var ev Event
+		if len(bytes.TrimSpace(line)) > 0 && json.Unmarshal(line, &ev) == nil {
+			if err := s.deliver(ev); err != nil {
+				if _, ok := err.(permanentError); !ok {
+					sendErr = err
+					break
+				}
+			}
+		}
+		delivered += len(line) + 1
+	}
+
+	if err := s.trimSpool(delivered); err != nil {
+		return err
+	}
+	return sendErr
+}
+
+func (s *webhookSink) readSpool() ([]byte, error) {
+	lock, err := filelock.Acquire(s.spool + ".lock")
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Release()
+
+	data, err := os.ReadFile(s.spool)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// trimSpool drops the first n delivered bytes, keeping anything spooled since
+func (s *webhookSink) trimSpool(n int) error {
+	if n == 0 {
+		return nil
+	}
+
+	lock, err := filelock.Acquire(s.spool + ".lock")
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	data, err := os.ReadFile(s.spool)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if n >= len(data) {
+		return os.Remove(s.spool)
+	}
+
+	tmp := s.spool + ".tmp"
+	if err := os.WriteFile(tmp, data[n:], 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.spool)
+}
+
+// deliver POSTs one event, retrying with backoff on network errors, 429 and 5xx
+func (s *webhookSink) deliver(ev Event) error {
+	body, err := formatEvent(s.format, ev)
+	if err != nil {
+		return permanentError{err}
+	}
+
+	contentType := "application/json"
+	if s.format == "cef" {
+		contentType = "text/plain; charset=utf-8"
+	}
+
+	backoff := webhookBackoff
+	for attempt := 1; ; attempt++ {
+		err = s.post(body, contentType)
+		if err == nil {
+			return nil
+		}
+		if _, ok := err.(permanentError); ok || attempt >= webhookRetries {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (s *webhookSink) post(body []byte, contentType string) error {
+	req, err := http.NewRequest("POST", s.url, bytes.NewReader(body))
+	if err != nil {
+		return permanentError{err}
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", "sovereign-audit/"+ProductVersion)
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	default:
+		return permanentError{fmt.Errorf("webhook rejected event: %s", resp.Status)}
+	}
+}
@@ -1,28 +1,40 @@
 package backup
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"os/exec"
 	"runtime"
 	"strings"
+	"time"
+
+	"github.com/Achilles1089/sovereign-stack/internal/runner"
 )
 
+// crontab runs crontab with args
+func (m *Manager) crontab(args ...string) ([]byte, error) {
+	res, err := m.runner().Run(context.Background(), runner.Command{Name: "crontab", Args: args, Timeout: 10 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	return res.Stdout, nil
+}
+
 // SetupCron installs a cron job for automated backups
-func SetupCron(schedule string, binaryPath string) error {
+func (m *Manager) SetupCron(schedule string, binaryPath string) error {
 	if runtime.GOOS == "linux" {
-		return setupLinuxCron(schedule, binaryPath)
+		return m.setupLinuxCron(schedule, binaryPath)
 	}
 	if runtime.GOOS == "darwin" {
-		return setupMacOSCron(schedule, binaryPath)
+		return m.setupMacOSCron(schedule, binaryPath)
 	}
 	return fmt.Errorf("cron not supported on %s", runtime.GOOS)
 }
 
 // RemoveCron removes the sovereign backup cron job
-func RemoveCron() error {
+func (m *Manager) RemoveCron() error {
 	// Get existing crontab
-	out, err := exec.Command("crontab", "-l").Output()
+	out, err := m.crontab("-l")
 	if err != nil {
 		return nil // No crontab, nothing to remove
 	}
@@ -34,41 +46,42 @@ func RemoveCron() error {
 		}
 	}
 
-	return writeCrontab(strings.Join(newLines, "\n"))
+	return m.writeCrontab(strings.Join(newLines, "\n"))
 }
 
 // IsCronInstalled checks if the sovereign backup cron job exists
-func IsCronInstalled() bool {
-	out, err := exec.Command("crontab", "-l").Output()
+func (m *Manager) IsCronInstalled() bool {
+	out, err := m.crontab("-l")
 	if err != nil {
 		return false
 	}
 	return strings.Contains(string(out), "sovereign backup")
 }
 
-func setupLinuxCron(schedule string, binaryPath string) error {
-	return installCronEntry(schedule, binaryPath)
+func (m *Manager) setupLinuxCron(schedule string, binaryPath string) error {
+	return m.installCronEntry(schedule, binaryPath)
 }
 
-func setupMacOSCron(schedule string, binaryPath string) error {
-	return installCronEntry(schedule, binaryPath)
+func (m *Manager) setupMacOSCron(schedule string, binaryPath string) error {
+	return m.installCronEntry(schedule, binaryPath)
 }
 
-func installCronEntry(schedule string, binaryPath string) error {
+func (m *Manager) installCronEntry(schedule string, binaryPath string) error {
 	// Remove existing entries first
-	RemoveCron()
+	m.RemoveCron()
 
 	// Get existing crontab
-	existing, _ := exec.Command("crontab", "-l").Output()
+	existing, _ := m.crontab("-l")
 
-	entry := fmt.Sprintf("%s %s backup --tag auto 2>&1 | logger -t sovereign-backup\n",
+	// SOVEREIGN_ACTOR attributes scheduled runs to "cron" in the audit log
+	entry := fmt.Sprintf("%s SOVEREIGN_ACTOR=cron %s backup --tag auto 2>&1 | logger -t sovereign-backup\n",
 		schedule, binaryPath)
 
 	newCrontab := string(existing) + entry
-	return writeCrontab(newCrontab)
+	return m.writeCrontab(newCrontab)
 }
 
-func writeCrontab(content string) error {
+func (m *Manager) writeCrontab(content string) error {
 	// Write to temp file and install
 	tmpFile, err := os.CreateTemp("", "sovereign-cron-*")
 	if err != nil {
@@ -81,5 +94,6 @@ func writeCrontab(content string) error {
 	}
 	tmpFile.Close()
 
-	return exec.Command("crontab", tmpFile.Name()).Run()
+	_, err = m.crontab(tmpFile.Name())
+	return err
 }
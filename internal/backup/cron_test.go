@@ -0,0 +1,29 @@
+package backup
+
+import (
+	"testing"
+
+	"github.com/Achilles1089/sovereign-stack/internal/runner"
+)
+
+func TestCronUsesManagerRunner(t *testing.T) {
+	m, fake := newTestManager(t)
+	fake.On("crontab -l", runner.Response{Stdout: "0 3 * * * SOVEREIGN_ACTOR=cron /usr/bin/sovereign backup --tag auto\n"})
+	if !m.IsCronInstalled() {
+		t.Errorf("IsCronInstalled = false, calls %v", fake.Lines())
+	}
+	if len(fake.Calls()) != 1 {
+		t.Errorf("expected crontab to run through the manager's runner, got %v", fake.Lines())
+	}
+}
+
+func TestIsResticInstalled(t *testing.T) {
+	m, fake := newTestManager(t)
+	if !m.IsResticInstalled() {
+		t.Error("IsResticInstalled = false with restic on the fake path")
+	}
+	fake.Missing("restic")
+	if m.IsResticInstalled() {
+		t.Error("IsResticInstalled = true with restic missing")
+	}
+}
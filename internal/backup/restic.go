@@ -1,12 +1,16 @@
 package backup
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"time"
+
+	"github.com/Achilles1089/sovereign-stack/internal/runner"
+	"github.com/Achilles1089/sovereign-stack/internal/secrets"
 )
 
 // Snapshot represents a Restic backup snapshot
@@ -24,6 +28,7 @@ type Manager struct {
 	Password  string
 	DataDir   string
 	ConfigDir string
+	Runner    runner.Runner // runs restic and crontab; runner.Default if nil
 }
 
 // NewManager creates a new backup manager
@@ -32,6 +37,7 @@ func NewManager(configDir string) *Manager {
 		RepoPath:  filepath.Join(configDir, "backups"),
 		DataDir:   filepath.Join(configDir, "data"),
 		ConfigDir: configDir,
+		Runner:    runner.Default,
 	}
 }
 
@@ -40,9 +46,23 @@ func (m *Manager) SetPassword(password string) {
 	m.Password = password
 }
 
+// runner returns the runner for restic and crontab
+func (m *Manager) runner() runner.Runner {
+	if m.Runner == nil {
+		return runner.Default
+	}
+	return m.Runner
+}
+
 // IsResticInstalled checks if restic is available
-func IsResticInstalled() bool {
-	_, err := exec.LookPath("restic")
+func (m *Manager) IsResticInstalled() bool {
+	_, err := m.runner().LookPath("restic")
+	return err == nil
+}
+
+// RepoExists reports whether the repository has been initialized
+func (m *Manager) RepoExists() bool {
+	_, err := os.Stat(filepath.Join(m.RepoPath, "config"))
 	return err == nil
 }
 
@@ -53,15 +73,14 @@ func (m *Manager) InitRepo() error {
 	}
 
 	// Check if already initialized
-	cmd := m.resticCmd("cat", "config")
-	if err := cmd.Run(); err == nil {
+	if _, err := m.restic(false, "cat", "config"); err == nil {
 		return nil // Already initialized
+	} else if errors.Is(err, ErrNoPassword) {
+		return err
 	}
 
-	cmd = m.resticCmd("init")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	_, err := m.restic(true, "init")
+	return err
 }
 
 // Backup creates a new backup snapshot
@@ -71,6 +90,10 @@ func (m *Manager) Backup(tags ...string) error {
 	}
 
 	args := []string{"backup", m.DataDir, m.ConfigDir + "/config.yaml", m.ConfigDir + "/docker-compose.yml"}
+	// Each app's compose project lives under apps/
+	if _, err := os.Stat(m.ConfigDir + "/apps"); err == nil {
+		args = append(args, m.ConfigDir+"/apps")
+	}
 	for _, tag := range tags {
 		args = append(args, "--tag", tag)
 	}
@@ -79,22 +102,19 @@ func (m *Manager) Backup(tags ...string) error {
 	args = append(args, "--exclude", m.RepoPath)
 	args = append(args, "--exclude", "*.log")
 
-	cmd := m.resticCmd(args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	_, err := m.restic(true, args...)
+	return err
 }
 
 // ListSnapshots returns all backup snapshots
 func (m *Manager) ListSnapshots() ([]Snapshot, error) {
-	cmd := m.resticCmd("snapshots", "--json")
-	out, err := cmd.Output()
+	res, err := m.restic(false, "snapshots", "--json")
 	if err != nil {
 		return nil, fmt.Errorf("failed to list snapshots: %w", err)
 	}
 
 	var snapshots []Snapshot
-	if err := json.Unmarshal(out, &snapshots); err != nil {
+	if err := json.Unmarshal(res.Stdout, &snapshots); err != nil {
 		return nil, fmt.Errorf("failed to parse snapshots: %w", err)
 	}
 	return snapshots, nil
@@ -106,10 +126,8 @@ func (m *Manager) Restore(snapshotID string, target string) error {
 		target = m.ConfigDir
 	}
 
-	cmd := m.resticCmd("restore", snapshotID, "--target", target)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	_, err := m.restic(true, "restore", snapshotID, "--target", target)
+	return err
 }
 
 // Prune removes old snapshots based on retention policy
@@ -121,35 +139,65 @@ func (m *Manager) Prune(keepLast int, keepDaily int, keepWeekly int) error {
 		"--keep-weekly", fmt.Sprintf("%d", keepWeekly),
 	}
 
-	cmd := m.resticCmd(args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	_, err := m.restic(true, args...)
+	return err
 }
 
 // Stats returns repository statistics
 func (m *Manager) Stats() (string, error) {
-	cmd := m.resticCmd("stats")
-	out, err := cmd.Output()
+	res, err := m.restic(false, "stats")
 	if err != nil {
 		return "", err
 	}
-	return string(out), nil
+	return string(res.Stdout), nil
 }
 
-func (m *Manager) resticCmd(args ...string) *exec.Cmd {
-	cmd := exec.Command("restic", args...)
-	cmd.Env = append(os.Environ(),
-		"RESTIC_REPOSITORY="+m.RepoPath,
-		"RESTIC_PASSWORD="+m.password(),
-	)
-	return cmd
+// ErrNoPassword is returned when no repository password has been configured.
+// There is deliberately no fallback: a well-known default would let anyone
+// with a copy of the repository read it.
+var ErrNoPassword = errors.New("no backup password configured")
+
+// LegacyPassword is the built-in password older releases created every
+// repository with
+const LegacyPassword = "sovereign-default-key"
+
+// LoadPassword sets the repository password from secret name in store,
+// creating the secret if it is missing: a new repository gets a generated
+// password, and an existing one, created by an older release, keeps
+// LegacyPassword. migrated reports the latter; the caller should have the
+// user rotate it.
+func (m *Manager) LoadPassword(store *secrets.Store, name string) (migrated bool, err error) {
+	password, err := store.Get(name)
+	if errors.Is(err, secrets.ErrNotFound) {
+		if m.RepoExists() {
+			password, migrated, err = LegacyPassword, true, store.Set(name, LegacyPassword)
+		} else {
+			password, err = store.Ensure(name, secrets.DefaultLength)
+		}
+	}
+	if err != nil {
+		return false, err
+	}
+	m.SetPassword(password)
+	return migrated, nil
 }
 
-func (m *Manager) password() string {
-	if m.Password != "" {
-		return m.Password
+// restic runs a restic subcommand against the repository, streaming its
+// output to the terminal when stream is set
+func (m *Manager) restic(stream bool, args ...string) (*runner.Result, error) {
+	if m.Password == "" {
+		return nil, ErrNoPassword
+	}
+	cmd := runner.Command{
+		Name: "restic",
+		Args: args,
+		Env: []string{
+			"RESTIC_REPOSITORY=" + m.RepoPath,
+			"RESTIC_PASSWORD=" + m.Password,
+		},
+	}
+	if stream {
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
 	}
-	// Default password (user should change this)
-	return "sovereign-default-key"
+	return m.runner().Run(context.Background(), cmd)
 }
@@ -0,0 +1,108 @@
+package backup
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Achilles1089/sovereign-stack/internal/runner"
+	"github.com/Achilles1089/sovereign-stack/internal/secrets"
+)
+
+func newTestManager(t *testing.T) (*Manager, *runner.Fake) {
+	t.Helper()
+	fake := runner.NewFake()
+	m := NewManager(t.TempDir())
+	m.Runner = fake
+	m.SetPassword("hunter22")
+	return m, fake
+}
+
+func TestBackupCommand(t *testing.T) {
+	m, fake := newTestManager(t)
+	if err := m.Backup("auto"); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+
+	calls := fake.Calls()
+	if len(calls) != 1 {
+		t.Fatalf("expected one restic call, got %v", fake.Lines())
+	}
+	line := calls[0].String()
+	if !strings.HasPrefix(line, "restic backup "+m.DataDir) || !strings.Contains(line, "--tag auto") || !strings.Contains(line, "--exclude "+m.RepoPath) {
+		t.Errorf("unexpected command: %s", line)
+	}
+	env := strings.Join(calls[0].Env, " ")
+	if !strings.Contains(env, "RESTIC_REPOSITORY="+m.RepoPath) || !strings.Contains(env, "RESTIC_PASSWORD=hunter22") {
+		t.Errorf("repository not passed through the environment: %v", calls[0].Env)
+	}
+}
+
+func TestListSnapshots(t *testing.T) {
+	m, fake := newTestManager(t)
+	fake.On("restic snapshots --json", runner.Response{Stdout: `[{"short_id": "4f2a", "time": "2026-10-17T03:00:00Z", "hostname": "box", "tags": ["auto"]}]`})
+
+	snapshots, err := m.ListSnapshots()
+	if err != nil {
+		t.Fatalf("ListSnapshots failed: %v", err)
+	}
+	if len(snapshots) != 1 || snapshots[0].ID != "4f2a" || snapshots[0].Tags[0] != "auto" {
+		t.Errorf("unexpected snapshots: %+v", snapshots)
+	}
+
+	fake.On("restic snapshots", runner.Response{Stderr: "wrong password or no key found", ExitCode: 1})
+	if _, err := m.ListSnapshots(); err == nil || !strings.Contains(err.Error(), "wrong password") {
+		t.Errorf("expected restic's error, got %v", err)
+	}
+}
+
+func TestInitRepo(t *testing.T) {
+	m, fake := newTestManager(t)
+	fake.On("restic cat config", runner.Response{ExitCode: 1})
+	if err := m.InitRepo(); err != nil {
+		t.Fatalf("InitRepo failed: %v", err)
+	}
+	if got := strings.Join(fake.Lines(), "|"); got != "restic cat config|restic init" {
+		t.Errorf("unexpected calls: %s", got)
+	}
+}
+
+func TestNoPassword(t *testing.T) {
+	m, fake := newTestManager(t)
+	m.SetPassword("")
+	if err := m.Backup(); !errors.Is(err, ErrNoPassword) {
+		t.Errorf("expected ErrNoPassword, got %v", err)
+	}
+	if len(fake.Calls()) != 0 {
+		t.Errorf("restic should not run without a password: %v", fake.Lines())
+	}
+}
+
+func TestLoadPassword(t *testing.T) {
+	store := secrets.NewStore(t.TempDir())
+	t.Setenv(secrets.PassphraseEnv, "")
+
+	// A repository from an older release, before passwords were secrets
+	m, _ := newTestManager(t)
+	os.MkdirAll(m.RepoPath, 0700)
+	os.WriteFile(filepath.Join(m.RepoPath, "config"), []byte("x"), 0600)
+	migrated, err := m.LoadPassword(store, "backup/password")
+	if err != nil || !migrated || m.Password != LegacyPassword {
+		t.Fatalf("expected the legacy password to be kept, got %q, %v, %v", m.Password, migrated, err)
+	}
+	if stored, _ := store.Get("backup/password"); stored != LegacyPassword {
+		t.Errorf("the legacy password should be stored once, got %q", stored)
+	}
+	if migrated, _ := m.LoadPassword(store, "backup/password"); migrated {
+		t.Error("a stored password should not be migrated again")
+	}
+
+	// A new repository gets a generated password
+	fresh, _ := newTestManager(t)
+	migrated, err = fresh.LoadPassword(store, "backup/other")
+	if err != nil || migrated || len(fresh.Password) != secrets.DefaultLength {
+		t.Errorf("expected a generated password, got %q, %v, %v", fresh.Password, migrated, err)
+	}
+}
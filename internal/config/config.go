@@ -10,6 +10,9 @@ import (
 
 // Config represents the main Sovereign Stack configuration
 type Config struct {
+	// Schema version — see migrate.go
+	Version int `yaml:"version"`
+
 	// Platform info
 	Platform string `yaml:"platform"` // "linux", "darwin", "wsl2"
 	Mode     string `yaml:"mode"`     // "server" (Linux), "personal" (macOS), "wsl2"
@@ -27,6 +30,15 @@ type Config struct {
 	// Backup configuration
 	Backup BackupConfig `yaml:"backup"`
 
+	// Audit log retention
+	Audit AuditConfig `yaml:"audit"`
+
+	// App installs
+	Apps AppsConfig `yaml:"apps"`
+
+	// Dashboard API access
+	Dashboard DashboardConfig `yaml:"dashboard"`
+
 	// Hardware profile (populated during init)
 	Hardware HardwareProfile `yaml:"hardware"`
 }
@@ -43,9 +55,10 @@ type ServicesConfig struct {
 type AIConfig struct {
 	Enabled      bool   `yaml:"enabled"`
 	DefaultModel string `yaml:"default_model"`  // e.g., "rwkv7-2.9B"
-	Host         string `yaml:"ollama_host"`    // "localhost:8085" — phone llama-server via ADB forward
+	Host         string `yaml:"llama_host"`     // "localhost:8085" — phone llama-server via ADB forward
 	PhoneHost    string `yaml:"phone_host"`     // "localhost:8085" via ADB forward
 	ModelsDir    string `yaml:"models_dir"`     // local GGUF models directory
+	LlamaBin     string `yaml:"llama_bin"`      // llama-server binary used to switch models
 	ImageGenHost string `yaml:"image_gen_host"` // "10.0.0.2:8090" — Envy media node
 	VoiceHost    string `yaml:"voice_host"`     // "localhost:8088" — Brain Net voice server (Whisper+Piper)
 	MusicGenHost string `yaml:"music_gen_host"` // "10.0.0.2:8091" — Envy music gen server
@@ -58,7 +71,45 @@ type BackupConfig struct {
 	Enabled     bool   `yaml:"enabled"`
 	Destination string `yaml:"destination"` // Local path or S3 URL
 	Schedule    string `yaml:"schedule"`    // Cron expression
-	Password    string `yaml:"password"`    // Restic repo password
+	Password    string `yaml:"password"`    // Restic repo password, normally a secret:// reference
+}
+
+// AuditConfig controls audit log rotation and retention. Zero limits disable
+// that check; the current log is never deleted.
+type AuditConfig struct {
+	MaxSizeMB  int  `yaml:"max_size_mb"`  // rotate the current log past this size
+	MaxAgeDays int  `yaml:"max_age_days"` // delete rotated segments older than this
+	MaxTotalMB int  `yaml:"max_total_mb"` // cap on all segments together
+	MaxFiles   int  `yaml:"max_files"`    // cap on the number of rotated segments
+	Compress   bool `yaml:"compress"`     // gzip segments as they are rotated
+
+	// Sinks receive a copy of every event in addition to the local log
+	Sinks []AuditSinkConfig `yaml:"sinks,omitempty"`
+}
+
+// AppsConfig holds app install settings
+type AppsConfig struct {
+	AllocatePorts bool   `yaml:"allocate_ports"` // move taken host ports into PortRange
+	PortRange     string `yaml:"port_range"`     // e.g. "20000-20999"
+}
+
+// DashboardConfig controls who may reach the dashboard API through a proxy
+// or from another site
+type DashboardConfig struct {
+	TrustedProxies []string `yaml:"trusted_proxies,omitempty"` // IPs or CIDRs whose X-Forwarded-For is believed, e.g. Caddy's
+	AllowedOrigins []string `yaml:"allowed_origins,omitempty"` // cross-site origins allowed to call the API, e.g. "https://admin.example.com"
+}
+
+// AuditSinkConfig configures one audit forwarding destination
+type AuditSinkConfig struct {
+	Type     string            `yaml:"type"`               // "syslog", "webhook" or "file"
+	Format   string            `yaml:"format,omitempty"`   // "json" (default) or "cef"
+	Network  string            `yaml:"network,omitempty"`  // syslog: "udp", "tcp" or "unix"
+	Address  string            `yaml:"address,omitempty"`  // syslog: "host:514" or a socket path
+	Facility string            `yaml:"facility,omitempty"` // syslog facility, default "local0"
+	URL      string            `yaml:"url,omitempty"`      // webhook endpoint
+	Headers  map[string]string `yaml:"headers,omitempty"`  // webhook request headers, e.g. Authorization
+	Path     string            `yaml:"path,omitempty"`     // file: JSONL output path
 }
 
 // HardwareProfile stores detected hardware info
@@ -78,7 +129,8 @@ type HardwareProfile struct {
 // DefaultConfig returns a sensible default configuration
 func DefaultConfig() *Config {
 	return &Config{
-		Port: 8080,
+		Version: CurrentVersion,
+		Port:    8080,
 		Services: ServicesConfig{
 			LlamaServer: true,
 			Postgres:    true,
@@ -88,6 +140,8 @@ func DefaultConfig() *Config {
 		AI: AIConfig{
 			Enabled:      true,
 			Host:         "localhost:8085",
+			ModelsDir:    "/data/data/com.termux/files/home/models",
+			LlamaBin:     "/data/data/com.termux/files/home/llama.cpp/bld/bin/llama-server",
 			ImageGenHost: "localhost:8090",
 			VoiceHost:    "localhost:8088",
 			MusicGenHost: "10.0.0.2:8091",
@@ -97,6 +151,17 @@ func DefaultConfig() *Config {
 		Backup: BackupConfig{
 			Enabled:  true,
 			Schedule: "0 3 * * *", // Daily at 3am
+			Password: "secret://backup/password",
+		},
+		Audit: AuditConfig{
+			MaxSizeMB:  10,
+			MaxAgeDays: 365,
+			MaxTotalMB: 500,
+			MaxFiles:   100,
+			Compress:   true,
+		},
+		Apps: AppsConfig{
+			PortRange: "20000-20999",
 		},
 	}
 }
@@ -123,44 +188,82 @@ func DataDir() string {
 	return filepath.Join(ConfigDir(), "data")
 }
 
-// Load reads the configuration from disk
+// Load reads and validates the configuration from disk. It covers the file
+// layer only; Resolve adds environment and flag overrides on top.
 func Load(path string) (*Config, error) {
+	cfg, _, err := load(path)
+	return cfg, err
+}
+
+// load is Load that also returns the (possibly migrated) file contents.
+// A missing file is reported with an error matching fs.ErrNotExist.
+func load(path string) (*Config, []byte, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config: %w", err)
+		return nil, nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	// Upgrade files written by older releases. If the rewrite fails (e.g. a
+	// read-only mount) the migrated config is still used for this run.
+	migrated, applied, err := Migrate(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	if len(applied) > 0 {
+		if err := Validate(migrated); err != nil {
+			return nil, nil, fmt.Errorf("invalid config %s: %w", path, err)
+		}
+		if err := writeMigrated(path, data, migrated, applied[0].From); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: config %s migrated in memory only: %v\n", path, err)
+		}
+		data = migrated
+	}
+
+	if err := Validate(data); err != nil {
+		return nil, nil, fmt.Errorf("invalid config %s: %w", path, err)
 	}
 
 	cfg := DefaultConfig()
 	if err := yaml.Unmarshal(data, cfg); err != nil {
-		return nil, fmt.Errorf("failed to parse config: %w", err)
+		return nil, nil, fmt.Errorf("failed to parse config: %w", err)
 	}
 
-	return cfg, nil
+	return cfg, data, nil
 }
 
-// Save writes the configuration to disk
+// Save writes the configuration to disk, readable only by its owner since
+// it may still hold plaintext credentials
 func (c *Config) Save(path string) error {
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
+	c.Version = CurrentVersion
 	data, err := yaml.Marshal(c)
 	if err != nil {
 		return fmt.Errorf("failed to serialize config: %w", err)
 	}
 
-	if err := os.WriteFile(path, data, 0644); err != nil {
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
 		return fmt.Errorf("failed to write config: %w", err)
 	}
 
 	return nil
 }
 
-// LoadOrDefault loads config from path, or returns defaults if not found
+// LoadOrDefault loads config from path, or returns defaults if not found.
+// A config that exists but fails validation is reported on stderr, since
+// silently running on defaults would hide the mistake.
 func LoadOrDefault(path string) *Config {
 	cfg, err := Load(path)
 	if err != nil {
+		if _, statErr := os.Stat(path); statErr == nil {
+			fmt.Fprintf(os.Stderr, "warning: %v\nwarning: using default configuration\n", err)
+		}
 		return DefaultConfig()
 	}
 	return cfg
@@ -23,6 +23,9 @@ func TestLoadOrDefault_NoFile(t *testing.T) {
 	if !cfg.Backup.Enabled {
 		t.Error("expected backup to be enabled by default")
 	}
+	if !cfg.Audit.Compress || cfg.Audit.MaxSizeMB != 10 {
+		t.Errorf("unexpected audit defaults: %+v", cfg.Audit)
+	}
 }
 
 func TestSaveAndLoad(t *testing.T) {
@@ -0,0 +1,349 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Source identifies the layer that supplied a config value. Later layers win:
+// defaults < config file < SOVEREIGN_* environment variables < command-line flags.
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+)
+
+// Origin records where an effective value came from
+type Origin struct {
+	Source Source `json:"source"`
+	Detail string `json:"detail,omitempty"` // file:line, env var name or flag
+}
+
+func (o Origin) String() string {
+	if o.Detail == "" {
+		return string(o.Source)
+	}
+	return fmt.Sprintf("%s (%s)", o.Source, o.Detail)
+}
+
+// Effective is a fully resolved config together with the origin of each field
+type Effective struct {
+	Config  *Config
+	Origins map[string]Origin // keyed by YAML path
+}
+
+// legacyEnv maps environment variables from before the layered config to
+// the paths they now override
+var legacyEnv = map[string]string{
+	"SOVEREIGN_MODELS_DIR": "ai.models_dir",
+	"SOVEREIGN_LLAMA_BIN":  "ai.llama_bin",
+}
+
+// environ is swapped out in tests
+var environ = os.Environ
+
+// Paths returns every settable config path in declaration order, e.g.
+// "port", "ai.rag_host", "audit.sinks". Lists and maps are single values;
+// the schema version is not settable.
+func Paths() []string {
+	var paths []string
+	var walk func(t reflect.Type, prefix string)
+	walk = func(t reflect.Type, prefix string) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name := strings.Split(f.Tag.Get("yaml"), ",")[0]
+			if name == "" || name == "-" || (prefix == "" && name == "version") {
+				continue
+			}
+			path := joinPath(prefix, name)
+			if f.Type.Kind() == reflect.Struct {
+				walk(f.Type, path)
+				continue
+			}
+			paths = append(paths, path)
+		}
+	}
+	walk(reflect.TypeOf(Config{}), "")
+	return paths
+}
+
+// EnvVar returns the environment variable that overrides a config path,
+// e.g. "ai.rag_host" → SOVEREIGN_AI_RAG_HOST
+func EnvVar(path string) string {
+	return "SOVEREIGN_" + strings.ToUpper(strings.ReplaceAll(path, ".", "_"))
+}
+
+// ResolveFile builds the config from defaults and the config file only.
+// A missing config file means defaults.
+func ResolveFile(path string) (*Effective, error) {
+	eff := &Effective{Config: DefaultConfig(), Origins: make(map[string]Origin)}
+	for _, p := range Paths() {
+		eff.Origins[p] = Origin{Source: SourceDefault}
+	}
+
+	cfg, data, err := load(path)
+	switch {
+	case err == nil:
+		eff.Config = cfg
+		eff.recordFile(path, data)
+	case errors.Is(err, fs.ErrNotExist):
+	default:
+		return nil, err
+	}
+	return eff, nil
+}
+
+// Resolve builds the effective config from every layer. overrides are
+// "path=value" pairs as given to --set.
+func Resolve(path string, overrides []string) (*Effective, error) {
+	eff, err := ResolveFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Environment: canonical names first, so they beat the legacy aliases
+	env := make(map[string]string)
+	for _, kv := range environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok && strings.HasPrefix(k, "SOVEREIGN_") {
+			env[k] = v
+		}
+	}
+	legacy := make([]string, 0, len(legacyEnv))
+	for name := range legacyEnv {
+		legacy = append(legacy, name)
+	}
+	sort.Strings(legacy)
+	for _, name := range legacy {
+		if v, ok := env[name]; ok && v != "" {
+			if err := eff.set(legacyEnv[name], v, Origin{Source: SourceEnv, Detail: name}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, p := range Paths() {
+		name := EnvVar(p)
+		if v, ok := env[name]; ok {
+			if err := eff.set(p, v, Origin{Source: SourceEnv, Detail: name}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, o := range overrides {
+		p, v, ok := strings.Cut(o, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid --set %q: expected path=value", o)
+		}
+		if err := eff.set(strings.TrimSpace(p), v, Origin{Source: SourceFlag, Detail: "--set " + strings.TrimSpace(p)}); err != nil {
+			return nil, err
+		}
+	}
+
+	// Overrides are held to the same rules as the file
+	if errs := eff.Config.check(); len(errs) > 0 {
+		for i := range errs {
+			if o := eff.Origins[errs[i].Path]; o.Source == SourceEnv || o.Source == SourceFlag {
+				errs[i].Message += " (set by " + o.Detail + ")"
+			}
+		}
+		return nil, fmt.Errorf("invalid config: %w", errs)
+	}
+	return eff, nil
+}
+
+// SetFlag applies a value from a command-specific flag (such as dashboard --port)
+// as the highest-priority layer
+func (e *Effective) SetFlag(path, value, flag string) error {
+	if err := e.set(path, value, Origin{Source: SourceFlag, Detail: flag}); err != nil {
+		return err
+	}
+	if errs := e.Config.check(); len(errs) > 0 {
+		return fmt.Errorf("invalid %s: %w", flag, errs)
+	}
+	return nil
+}
+
+func (e *Effective) set(path, value string, origin Origin) error {
+	if err := Set(e.Config, path, value); err != nil {
+		return fmt.Errorf("%s: %w", origin.Detail, err)
+	}
+	e.Origins[path] = origin
+	return nil
+}
+
+// recordFile marks every path present in the config file
+func (e *Effective) recordFile(path string, data []byte) {
+	var doc yaml.Node
+	if yaml.Unmarshal(data, &doc) != nil || len(doc.Content) == 0 {
+		return
+	}
+	for _, p := range Paths() {
+		node := doc.Content[0]
+		var key *yaml.Node
+		for _, part := range strings.Split(p, ".") {
+			key, node = mappingEntry(node, part)
+			if node == nil {
+				break
+			}
+		}
+		if node != nil && node.Tag != "!!null" {
+			e.Origins[p] = Origin{Source: SourceFile, Detail: fmt.Sprintf("%s:%d", path, key.Line)}
+		}
+	}
+}
+
+// mappingEntry returns the key and value nodes for key in a mapping
+func mappingEntry(m *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if m == nil || m.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i], m.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// Set assigns a value, given as text, to the field at a config path.
+// Lists and maps take YAML or JSON, e.g. `[{type: file, path: /var/log/a.jsonl}]`.
+func Set(cfg *Config, path, value string) error {
+	field, err := fieldByPath(cfg, path)
+	if err != nil {
+		return err
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s: expected a whole number, got %q", path, value)
+		}
+		field.SetInt(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("%s: expected true or false, got %q", path, value)
+		}
+		field.SetBool(b)
+	default:
+		v := reflect.New(field.Type())
+		if err := yaml.Unmarshal([]byte(value), v.Interface()); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		field.Set(v.Elem())
+	}
+	return nil
+}
+
+// SetValue assigns a value of the field's own type, as returned by Get
+func SetValue(cfg *Config, path string, v interface{}) error {
+	field, err := fieldByPath(cfg, path)
+	if err != nil {
+		return err
+	}
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+	if !rv.Type().AssignableTo(field.Type()) {
+		return fmt.Errorf("%s: cannot assign %s to %s", path, rv.Type(), field.Type())
+	}
+	field.Set(rv)
+	return nil
+}
+
+// Get returns the value at a config path
+func Get(cfg *Config, path string) (interface{}, error) {
+	field, err := fieldByPath(cfg, path)
+	if err != nil {
+		return nil, err
+	}
+	return field.Interface(), nil
+}
+
+// Redacted stands in for secret values in output. Writing it back leaves
+// the stored value unchanged.
+const Redacted = "********"
+
+// RedactValue hides secrets in a value returned by Get: a plaintext backup
+// password and audit sink headers. secret:// references are not secret.
+func RedactValue(path string, v interface{}) interface{} {
+	switch val := v.(type) {
+	case string:
+		if path == "backup.password" && val != "" && !strings.HasPrefix(val, "secret://") {
+			return Redacted
+		}
+	case []AuditSinkConfig:
+		out := make([]AuditSinkConfig, len(val))
+		for i, s := range val {
+			out[i] = s
+			if len(s.Headers) > 0 {
+				out[i].Headers = make(map[string]string, len(s.Headers))
+				for k := range s.Headers {
+					out[i].Headers[k] = Redacted
+				}
+			}
+		}
+		return out
+	}
+	return v
+}
+
+// Unredact restores values that were sent back as Redacted from old, the
+// value they replace. Sink headers are matched by position and name.
+func Unredact(path string, v, old interface{}) interface{} {
+	switch val := v.(type) {
+	case string:
+		if val == Redacted {
+			return old
+		}
+	case []AuditSinkConfig:
+		prev, _ := old.([]AuditSinkConfig)
+		for i := range val {
+			for k, h := range val[i].Headers {
+				if h != Redacted {
+					continue
+				}
+				if i < len(prev) {
+					val[i].Headers[k] = prev[i].Headers[k]
+				} else {
+					delete(val[i].Headers, k)
+				}
+			}
+		}
+	}
+	return v
+}
+
+func fieldByPath(cfg *Config, path string) (reflect.Value, error) {
+	v := reflect.ValueOf(cfg).Elem()
+	for _, part := range strings.Split(path, ".") {
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, fmt.Errorf("unknown config path %q", path)
+		}
+		f, ok := yamlFields(v.Type())[part]
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("unknown config path %q", path)
+		}
+		v = v.FieldByIndex(f.Index)
+	}
+	if v.Kind() == reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("config path %q is a section, not a value", path)
+	}
+	return v, nil
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestConfig(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestResolvePrecedence(t *testing.T) {
+	path := writeTestConfig(t, "version: 2\nport: 8081\nai:\n    rag_host: file:8093\n    voice_host: file:8088\n")
+	t.Setenv("SOVEREIGN_AI_RAG_HOST", "env:8093")
+	t.Setenv("SOVEREIGN_AI_AGENT_HOST", "env:8095")
+
+	eff, err := Resolve(path, []string{"ai.rag_host=flag:8093"})
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+
+	cases := []struct {
+		path   string
+		value  interface{}
+		source Source
+	}{
+		{"backup.schedule", "0 3 * * *", SourceDefault},
+		{"port", 8081, SourceFile},
+		{"ai.voice_host", "file:8088", SourceFile},
+		{"ai.agent_host", "env:8095", SourceEnv},
+		{"ai.rag_host", "flag:8093", SourceFlag},
+	}
+	for _, c := range cases {
+		v, err := Get(eff.Config, c.path)
+		if err != nil {
+			t.Fatalf("Get(%s): %v", c.path, err)
+		}
+		if v != c.value {
+			t.Errorf("%s = %v, want %v", c.path, v, c.value)
+		}
+		if got := eff.Origins[c.path].Source; got != c.source {
+			t.Errorf("%s source = %s, want %s", c.path, got, c.source)
+		}
+	}
+
+	if got := eff.Origins["port"].Detail; got != path+":2" {
+		t.Errorf("port origin = %q, want %s:2", got, path)
+	}
+}
+
+func TestResolveLegacyEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.yaml")
+	t.Setenv("SOVEREIGN_MODELS_DIR", "/legacy/models")
+
+	eff, err := Resolve(path, nil)
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if eff.Config.AI.ModelsDir != "/legacy/models" {
+		t.Errorf("legacy env ignored: %q", eff.Config.AI.ModelsDir)
+	}
+
+	// The canonical name wins over the legacy alias
+	t.Setenv("SOVEREIGN_AI_MODELS_DIR", "/canonical/models")
+	eff, err = Resolve(path, nil)
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	if eff.Config.AI.ModelsDir != "/canonical/models" || eff.Origins["ai.models_dir"].Detail != "SOVEREIGN_AI_MODELS_DIR" {
+		t.Errorf("expected canonical env to win, got %q from %s", eff.Config.AI.ModelsDir, eff.Origins["ai.models_dir"])
+	}
+}
+
+func TestResolveInvalidOverride(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.yaml")
+
+	cases := []struct {
+		env, value string
+		overrides  []string
+		want       string
+	}{
+		{"SOVEREIGN_PORT", "eighty", nil, "expected a whole number"},
+		{"SOVEREIGN_AUDIT_COMPRESS", "maybe", nil, "expected true or false"},
+		{"", "", []string{"port=70000"}, "set by --set port"},
+		{"", "", []string{"ai.nope=1"}, "unknown config path"},
+		{"", "", []string{"port"}, "expected path=value"},
+	}
+	for _, c := range cases {
+		if c.env != "" {
+			t.Setenv(c.env, c.value)
+		}
+		_, err := Resolve(path, c.overrides)
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("env %s=%q overrides %v: expected error containing %q, got %v", c.env, c.value, c.overrides, c.want, err)
+		}
+		if c.env != "" {
+			os.Unsetenv(c.env)
+		}
+	}
+}
+
+func TestSetStructuredValue(t *testing.T) {
+	cfg := DefaultConfig()
+	if err := Set(cfg, "audit.sinks", `[{type: file, path: /var/log/audit.jsonl}]`); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if len(cfg.Audit.Sinks) != 1 || cfg.Audit.Sinks[0].Path != "/var/log/audit.jsonl" {
+		t.Errorf("unexpected sinks: %+v", cfg.Audit.Sinks)
+	}
+	if err := Set(cfg, "ai", "x"); err == nil {
+		t.Error("expected an error setting a whole section")
+	}
+}
+
+func TestPathsEnvVar(t *testing.T) {
+	paths := Paths()
+	for _, p := range paths {
+		if p == "version" {
+			t.Error("version should not be settable")
+		}
+		if _, err := Get(DefaultConfig(), p); err != nil {
+			t.Errorf("Get(%s): %v", p, err)
+		}
+	}
+	if got := EnvVar("ai.rag_host"); got != "SOVEREIGN_AI_RAG_HOST" {
+		t.Errorf("EnvVar = %s", got)
+	}
+}
@@ -0,0 +1,192 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentVersion is the config schema version this binary writes.
+// Files without a version field are version 1.
+const CurrentVersion = 2
+
+// Migration upgrades a config document from one schema version to the next.
+// Migrations edit the YAML tree so comments and unrelated keys survive.
+type Migration struct {
+	From        int
+	Description string
+	Apply       func(root *yaml.Node) error
+}
+
+// migrations must stay in order, one per version step
+var migrations = []Migration{
+	{
+		From:        1,
+		Description: "rename ai.ollama_host to ai.llama_host (inference moved to llama-server)",
+		Apply: func(root *yaml.Node) error {
+			renameKey(mappingValue(root, "ai"), "ollama_host", "llama_host")
+			return nil
+		},
+	},
+}
+
+// Migrations returns the registered migrations, oldest first
+func Migrations() []Migration {
+	return append([]Migration(nil), migrations...)
+}
+
+// Migrate upgrades raw config YAML to CurrentVersion, returning the rewritten
+// document and the migrations that were applied. Data that is already
+// current is returned unchanged.
+func Migrate(data []byte) ([]byte, []Migration, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	applied, err := migrateNode(&doc)
+	if err != nil || len(applied) == 0 {
+		return data, nil, err
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(4) // match yaml.Marshal, which Save uses
+	if err := enc.Encode(&doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to serialize config: %w", err)
+	}
+	enc.Close()
+	return buf.Bytes(), applied, nil
+}
+
+// migrateNode applies pending migrations to a parsed document in place
+func migrateNode(doc *yaml.Node) ([]Migration, error) {
+	if len(doc.Content) == 0 {
+		return nil, nil // empty file: all defaults
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config must be a mapping")
+	}
+
+	version, err := documentVersion(root)
+	if err != nil {
+		return nil, err
+	}
+	if version > CurrentVersion {
+		return nil, fmt.Errorf("config version %d is newer than this binary supports (%d) — upgrade sovereign", version, CurrentVersion)
+	}
+
+	var applied []Migration
+	for _, m := range migrations {
+		if m.From < version {
+			continue
+		}
+		if err := m.Apply(root); err != nil {
+			return applied, fmt.Errorf("migration from version %d failed: %w", m.From, err)
+		}
+		applied = append(applied, m)
+		version = m.From + 1
+	}
+	if len(applied) > 0 {
+		setVersion(root, version)
+	}
+	return applied, nil
+}
+
+func documentVersion(root *yaml.Node) (int, error) {
+	v := mappingValue(root, "version")
+	if v == nil || v.Tag == "!!null" {
+		return 1, nil
+	}
+	n, err := strconv.Atoi(v.Value)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("version (line %d): expected a positive whole number, got %q", v.Line, v.Value)
+	}
+	return n, nil
+}
+
+// setVersion writes the version key, adding it at the top of the file if missing
+func setVersion(root *yaml.Node, version int) {
+	if v := mappingValue(root, "version"); v != nil {
+		v.Kind, v.Tag, v.Value = yaml.ScalarNode, "!!int", strconv.Itoa(version)
+		return
+	}
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "version"}
+	val := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(version)}
+	root.Content = append([]*yaml.Node{key, val}, root.Content...)
+}
+
+// mappingValue returns the value node for key in a mapping, or nil
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	if m == nil || m.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// renameKey renames a mapping key in place. If the new key is already set
+// it wins and the old entry is dropped.
+func renameKey(m *yaml.Node, from, to string) {
+	if m == nil || m.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value != from {
+			continue
+		}
+		if mappingValue(m, to) != nil {
+			m.Content = append(m.Content[:i], m.Content[i+2:]...)
+		} else {
+			m.Content[i].Value = to
+		}
+		return
+	}
+}
+
+// writeMigrated replaces a config file with its migrated contents, keeping
+// the original as <path>.v<N>.bak
+func writeMigrated(path string, original, migrated []byte, from int) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	backup := fmt.Sprintf("%s.v%d.bak", path, from)
+	if err := os.WriteFile(backup, original, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to back up config before migrating: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, migrated, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to write migrated config: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write migrated config: %w", err)
+	}
+	return nil
+}
+
+// MigrateFile upgrades a config file on disk and returns the migrations applied
+func MigrateFile(path string) ([]Migration, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	migrated, applied, err := Migrate(data)
+	if err != nil || len(applied) == 0 {
+		return nil, err
+	}
+	if err := Validate(migrated); err != nil {
+		return nil, fmt.Errorf("migrated config is invalid: %w", err)
+	}
+	return applied, writeMigrated(path, data, migrated, applied[0].From)
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const v1Config = `# Brain Net
+platform: linux
+port: 8080
+ai:
+    enabled: true
+    ollama_host: localhost:8085 # phone via ADB
+`
+
+func TestMigrateV1(t *testing.T) {
+	out, applied, err := Migrate([]byte(v1Config))
+	if err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if len(applied) != 1 || applied[0].From != 1 {
+		t.Fatalf("expected the v1 migration to run, got %+v", applied)
+	}
+
+	text := string(out)
+	if strings.Contains(text, "ollama_host") || !strings.Contains(text, "llama_host: localhost:8085 # phone via ADB") {
+		t.Errorf("key not renamed with its comment:\n%s", text)
+	}
+	if !strings.Contains(text, "version: 2") || !strings.Contains(text, "# Brain Net") {
+		t.Errorf("expected version added and comments kept:\n%s", text)
+	}
+
+	// Already current: nothing to do
+	again, applied, err := Migrate(out)
+	if err != nil || len(applied) != 0 || string(again) != text {
+		t.Errorf("migrating a current config should be a no-op, got %d migrations (%v)", len(applied), err)
+	}
+}
+
+func TestMigrateRejectsNewerVersion(t *testing.T) {
+	if _, _, err := Migrate([]byte("version: 99\n")); err == nil {
+		t.Error("expected a config from a newer release to be rejected")
+	}
+}
+
+func TestLoadMigratesOnDisk(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte(v1Config), 0600)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.AI.Host != "localhost:8085" || cfg.Version != CurrentVersion {
+		t.Errorf("expected migrated values, got host=%q version=%d", cfg.AI.Host, cfg.Version)
+	}
+
+	backup, err := os.ReadFile(path + ".v1.bak")
+	if err != nil || string(backup) != v1Config {
+		t.Errorf("expected the original to be kept as .v1.bak: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "llama_host") {
+		t.Errorf("expected the config to be rewritten:\n%s", data)
+	}
+}
@@ -0,0 +1,523 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ValidationError is one problem found in a config file
+type ValidationError struct {
+	Path    string `json:"path"`           // YAML path, e.g. "ai.image_gen_host" or "audit.sinks[0].url"
+	Line    int    `json:"line,omitempty"` // 1-based line in the file; 0 when unknown
+	Message string `json:"message"`
+}
+
+func (e ValidationError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s (line %d): %s", e.Path, e.Line, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// ValidationErrors collects every problem in a config so they can be fixed in one pass
+type ValidationErrors []ValidationError
+
+func (v ValidationErrors) Error() string {
+	lines := make([]string, len(v))
+	for i, e := range v {
+		lines[i] = "  " + e.Error()
+	}
+	return fmt.Sprintf("%d config problem(s):\n%s", len(v), strings.Join(lines, "\n"))
+}
+
+// Validate checks raw config YAML: unknown keys, values of the wrong type and
+// out-of-range settings. It returns ValidationErrors listing every problem.
+func Validate(data []byte) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse config: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return nil // empty file: all defaults
+	}
+
+	// Older files are checked against the schema they will be migrated to
+	if _, err := migrateNode(&doc); err != nil {
+		return err
+	}
+
+	w := &schemaWalker{lines: make(map[string]int)}
+	w.walk(doc.Content[0], reflect.TypeOf(Config{}), "")
+	if len(w.errs) > 0 {
+		return w.errs
+	}
+
+	cfg := DefaultConfig()
+	if err := doc.Decode(cfg); err != nil {
+		return fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	errs := cfg.check()
+	for i := range errs {
+		errs[i].Line = w.lines[errs[i].Path]
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Validate checks the semantic rules for an in-memory config
+func (c *Config) Validate() error {
+	if errs := c.check(); len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// schemaWalker matches a YAML tree against the Config struct, recording the
+// line of every key and flagging keys and scalars that do not fit
+type schemaWalker struct {
+	lines map[string]int
+	errs  ValidationErrors
+}
+
+func (w *schemaWalker) fail(path string, node *yaml.Node, format string, args ...interface{}) {
+	w.errs = append(w.errs, ValidationError{Path: path, Line: node.Line, Message: fmt.Sprintf(format, args...)})
+}
+
+func (w *schemaWalker) walk(node *yaml.Node, t reflect.Type, path string) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Tag == "!!null" {
+		return // explicit empty value keeps the default
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			w.fail(path, node, "expected a mapping")
+			return
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, val := node.Content[i], node.Content[i+1]
+			child := joinPath(path, key.Value)
+			w.lines[child] = key.Line
+
+			field, ok := fields[key.Value]
+			if !ok {
+				w.fail(child, key, "unknown key%s", suggestKey(key.Value, fields))
+				continue
+			}
+			w.walk(val, field.Type, child)
+		}
+
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			w.fail(path, node, "expected a list")
+			return
+		}
+		for i, item := range node.Content {
+			child := fmt.Sprintf("%s[%d]", path, i)
+			w.lines[child] = item.Line
+			w.walk(item, t.Elem(), child)
+		}
+
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			w.fail(path, node, "expected a mapping")
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, val := node.Content[i], node.Content[i+1]
+			child := joinPath(path, key.Value)
+			w.lines[child] = key.Line
+			w.walk(val, t.Elem(), child)
+		}
+
+	case reflect.Bool:
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!bool" {
+			w.fail(path, node, "expected true or false, got %q", node.Value)
+		}
+
+	case reflect.Int, reflect.Int64:
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!int" {
+			w.fail(path, node, "expected a whole number, got %q", node.Value)
+		}
+
+	case reflect.String:
+		if node.Kind != yaml.ScalarNode {
+			w.fail(path, node, "expected a string")
+		}
+	}
+}
+
+// yamlFields maps YAML keys to the struct fields they decode into
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("yaml"), ",")[0]
+		if name == "-" || !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f
+	}
+	return fields
+}
+
+// suggestKey points out the closest valid key for a likely typo
+func suggestKey(key string, fields map[string]reflect.StructField) string {
+	best, bestDist := "", 3
+	for name := range fields {
+		if d := editDistance(key, name); d < bestDist {
+			best, bestDist = name, d
+		}
+	}
+	if best != "" {
+		return fmt.Sprintf(" (did you mean %q?)", best)
+	}
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return fmt.Sprintf(" (expected one of: %s)", strings.Join(names, ", "))
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func joinPath(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+// check applies the semantic rules that the YAML types alone cannot express
+func (c *Config) check() ValidationErrors {
+	var errs ValidationErrors
+	add := func(path, format string, args ...interface{}) {
+		errs = append(errs, ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	oneOf := func(path, value string, allowed ...string) {
+		if value == "" {
+			return
+		}
+		for _, a := range allowed {
+			if value == a {
+				return
+			}
+		}
+		add(path, "must be one of %s, got %q", strings.Join(allowed, ", "), value)
+	}
+
+	if c.Version != CurrentVersion {
+		add("version", "must be %d, got %d", CurrentVersion, c.Version)
+	}
+	oneOf("platform", c.Platform, "linux", "darwin", "wsl2")
+	oneOf("mode", c.Mode, "server", "personal", "wsl2")
+
+	if strings.Contains(c.Domain, "://") || strings.ContainsAny(c.Domain, " /") {
+		add("domain", "must be a bare hostname, got %q", c.Domain)
+	}
+	if c.Port < 1 || c.Port > 65535 {
+		add("port", "must be between 1 and 65535, got %d", c.Port)
+	}
+
+	hosts := []struct{ key, value string }{
+		{"llama_host", c.AI.Host},
+		{"phone_host", c.AI.PhoneHost},
+		{"image_gen_host", c.AI.ImageGenHost},
+		{"voice_host", c.AI.VoiceHost},
+		{"music_gen_host", c.AI.MusicGenHost},
+		{"rag_host", c.AI.RAGHost},
+		{"agent_host", c.AI.AgentHost},
+	}
+	for _, h := range hosts {
+		if h.value == "" {
+			continue
+		}
+		if err := ValidateHostPort(h.value); err != nil {
+			add("ai."+h.key, "%v", err)
+		}
+	}
+
+	if c.Apps.PortRange != "" {
+		if _, _, err := ParsePortRange(c.Apps.PortRange); err != nil {
+			add("apps.port_range", "%v", err)
+		}
+	}
+
+	for _, p := range c.Dashboard.TrustedProxies {
+		if _, err := ParseProxy(p); err != nil {
+			add("dashboard.trusted_proxies", "%v", err)
+		}
+	}
+	for _, o := range c.Dashboard.AllowedOrigins {
+		if err := ValidateOrigin(o); err != nil {
+			add("dashboard.allowed_origins", "%v", err)
+		}
+	}
+
+	if ref, ok := strings.CutPrefix(c.Backup.Password, "secret://"); ok && ref == "" {
+		add("backup.password", "secret reference needs a name, e.g. secret://backup/password")
+	}
+
+	if c.Backup.Schedule != "" {
+		if err := ValidateCron(c.Backup.Schedule); err != nil {
+			add("backup.schedule", "%v", err)
+		}
+	}
+
+	for _, f := range []struct {
+		key   string
+		value int
+	}{
+		{"max_size_mb", c.Audit.MaxSizeMB},
+		{"max_age_days", c.Audit.MaxAgeDays},
+		{"max_total_mb", c.Audit.MaxTotalMB},
+		{"max_files", c.Audit.MaxFiles},
+	} {
+		if f.value < 0 {
+			add("audit."+f.key, "must not be negative, got %d", f.value)
+		}
+	}
+	for i, s := range c.Audit.Sinks {
+		checkSink(fmt.Sprintf("audit.sinks[%d]", i), s, add)
+	}
+
+	return errs
+}
+
+var syslogFacilityNames = []string{"user", "daemon", "auth", "authpriv",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7"}
+
+func checkSink(path string, s AuditSinkConfig, add func(path, format string, args ...interface{})) {
+	switch s.Format {
+	case "", "json", "cef":
+	default:
+		add(path+".format", "must be json or cef, got %q", s.Format)
+	}
+
+	switch s.Type {
+	case "syslog":
+		switch s.Network {
+		case "", "udp", "tcp":
+			if s.Address == "" {
+				add(path+".address", "syslog sink needs an address")
+			} else if err := ValidateHostPort(s.Address); err != nil {
+				add(path+".address", "%v", err)
+			}
+		case "unix":
+		default:
+			add(path+".network", "must be udp, tcp or unix, got %q", s.Network)
+		}
+		if s.Facility != "" && !contains(syslogFacilityNames, s.Facility) {
+			add(path+".facility", "unknown syslog facility %q", s.Facility)
+		}
+	case "webhook":
+		if !strings.HasPrefix(s.URL, "http://") && !strings.HasPrefix(s.URL, "https://") {
+			add(path+".url", "webhook sink needs an http(s) url")
+		}
+	case "file":
+		if s.Path == "" {
+			add(path+".path", "file sink needs a path")
+		}
+	case "":
+		add(path+".type", "sink type is required (syslog, webhook or file)")
+	default:
+		add(path+".type", "must be syslog, webhook or file, got %q", s.Type)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateHostPort checks a "host:port" address such as "localhost:8085"
+func ValidateHostPort(addr string) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("must be host:port, got %q", addr)
+	}
+	if host == "" {
+		return fmt.Errorf("missing host in %q", addr)
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("invalid port in %q", addr)
+	}
+	return nil
+}
+
+// ParsePortRange reads a "low-high" port range such as "20000-20999"
+func ParsePortRange(r string) (low, high int, err error) {
+	lo, hi, ok := strings.Cut(r, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("must be low-high, got %q", r)
+	}
+	low, errLo := strconv.Atoi(strings.TrimSpace(lo))
+	high, errHi := strconv.Atoi(strings.TrimSpace(hi))
+	if errLo != nil || errHi != nil || low < 1 || high > 65535 || low > high {
+		return 0, 0, fmt.Errorf("invalid port range %q", r)
+	}
+	return low, high, nil
+}
+
+// ParseProxy reads a trusted proxy given as an IP such as "172.18.0.2" or a
+// CIDR such as "172.18.0.0/16"
+func ParseProxy(p string) (*net.IPNet, error) {
+	if _, n, err := net.ParseCIDR(p); err == nil {
+		return n, nil
+	}
+	ip := net.ParseIP(p)
+	if ip == nil {
+		return nil, fmt.Errorf("must be an IP or CIDR, got %q", p)
+	}
+	bits := 8 * len(ip.To4())
+	if bits == 0 {
+		bits = 8 * net.IPv6len
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+// ValidateOrigin checks a browser origin such as "https://admin.example.com"
+func ValidateOrigin(o string) error {
+	u, err := url.Parse(o)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("must be http(s)://host[:port], got %q", o)
+	}
+	if u.Path != "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return fmt.Errorf("must be scheme and host only, got %q", o)
+	}
+	return nil
+}
+
+var cronMacros = map[string]bool{
+	"@yearly": true, "@annually": true, "@monthly": true, "@weekly": true,
+	"@daily": true, "@midnight": true, "@hourly": true, "@reboot": true,
+}
+
+var cronFields = []struct {
+	name     string
+	min, max int
+	names    []string // accepted aliases, index = value - min
+}{
+	{"minute", 0, 59, nil},
+	{"hour", 0, 23, nil},
+	{"day of month", 1, 31, nil},
+	{"month", 1, 12, []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	{"day of week", 0, 7, []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat", "sun"}},
+}
+
+// ValidateCron checks a standard five-field crontab expression or an @macro
+func ValidateCron(expr string) error {
+	expr = strings.TrimSpace(expr)
+	if strings.HasPrefix(expr, "@") {
+		if cronMacros[expr] {
+			return nil
+		}
+		return fmt.Errorf("unknown cron macro %q", expr)
+	}
+
+	parts := strings.Fields(expr)
+	if len(parts) != 5 {
+		return fmt.Errorf("cron expression needs 5 fields (minute hour day month weekday), got %d in %q", len(parts), expr)
+	}
+
+	for i, part := range parts {
+		f := cronFields[i]
+		for _, item := range strings.Split(part, ",") {
+			if err := checkCronItem(item, f.min, f.max, f.names); err != nil {
+				return fmt.Errorf("invalid %s %q: %v", f.name, part, err)
+			}
+		}
+	}
+	return nil
+}
+
+func checkCronItem(item string, min, max int, names []string) error {
+	rng, step, hasStep := strings.Cut(item, "/")
+	if hasStep {
+		n, err := strconv.Atoi(step)
+		if err != nil || n < 1 {
+			return fmt.Errorf("bad step %q", step)
+		}
+	}
+	if rng == "*" {
+		return nil
+	}
+
+	value := func(s string) (int, error) {
+		for i, name := range names {
+			if strings.EqualFold(s, name) {
+				return min + i, nil
+			}
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a number", s)
+		}
+		if n < min || n > max {
+			return 0, fmt.Errorf("%d is outside %d-%d", n, min, max)
+		}
+		return n, nil
+	}
+
+	lo, hi, isRange := strings.Cut(rng, "-")
+	from, err := value(lo)
+	if err != nil {
+		return err
+	}
+	if isRange {
+		to, err := value(hi)
+		if err != nil {
+			return err
+		}
+		if to < from {
+			return fmt.Errorf("range %s runs backwards", rng)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateReportsPathAndLine(t *testing.T) {
+	data := []byte(`platform: linux
+port: 70000
+ai:
+  enabled: true
+  image_gen_host: 10.0.0.2
+  rag_hots: localhost:8093
+backup:
+  schedule: "0 3 * *"
+  enabled: yes please
+audit:
+  sinks:
+    - type: webhook
+      url: ftp://siem.local
+`)
+
+	err := Validate(data)
+	var problems ValidationErrors
+	if !errors.As(err, &problems) {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+
+	// Structural problems are reported before semantic ones
+	want := map[string]int{
+		"ai.rag_hots":    6,
+		"backup.enabled": 9,
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("expected %d problems, got %v", len(want), problems)
+	}
+	for _, p := range problems {
+		if line, ok := want[p.Path]; !ok || line != p.Line {
+			t.Errorf("unexpected problem %v", p)
+		}
+	}
+	if !strings.Contains(problems[0].Message, `did you mean "rag_host"`) {
+		t.Errorf("expected a typo suggestion, got %q", problems[0].Message)
+	}
+
+	// Once the structure is fixed, every semantic problem shows up together
+	fixed := strings.Replace(strings.Replace(string(data), "rag_hots", "rag_host", 1), "yes please", "true", 1)
+	err = Validate([]byte(fixed))
+	if !errors.As(err, &problems) {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+	want = map[string]int{
+		"port":               2,
+		"ai.image_gen_host":  5,
+		"backup.schedule":    8,
+		"audit.sinks[0].url": 13,
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("expected %d problems, got %v", len(want), problems)
+	}
+	for _, p := range problems {
+		if line, ok := want[p.Path]; !ok || line != p.Line {
+			t.Errorf("unexpected problem %v", p)
+		}
+	}
+}
+
+func TestValidateDefaultConfig(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Errorf("default config should be valid: %v", err)
+	}
+	if err := Validate(nil); err != nil {
+		t.Errorf("empty file should be valid: %v", err)
+	}
+}
+
+func TestLoadRejectsInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte("port: 8080\ndomian: example.com\n"), 0600)
+
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "domian") {
+		t.Errorf("expected Load to reject unknown key, got %v", err)
+	}
+}
+
+func TestValidateCron(t *testing.T) {
+	valid := []string{"0 3 * * *", "*/15 * * * *", "0 0 1,15 * mon-fri", "30 2 * jan-jun 0", "@daily"}
+	for _, expr := range valid {
+		if err := ValidateCron(expr); err != nil {
+			t.Errorf("ValidateCron(%q) = %v", expr, err)
+		}
+	}
+
+	invalid := []string{"", "0 3 * *", "60 * * * *", "0 24 * * *", "* * 0 * *", "0 0 * * 8", "*/0 * * * *", "5-1 * * * *", "@often"}
+	for _, expr := range invalid {
+		if err := ValidateCron(expr); err == nil {
+			t.Errorf("ValidateCron(%q) should fail", expr)
+		}
+	}
+}
+
+func TestParsePortRange(t *testing.T) {
+	if lo, hi, err := ParsePortRange("20000-20999"); err != nil || lo != 20000 || hi != 20999 {
+		t.Errorf("ParsePortRange = %d, %d, %v", lo, hi, err)
+	}
+	for _, r := range []string{"20000", "2000-1000", "0-10", "1-70000", "a-b"} {
+		if _, _, err := ParsePortRange(r); err == nil {
+			t.Errorf("ParsePortRange(%q) should fail", r)
+		}
+	}
+}
+
+func TestParseProxy(t *testing.T) {
+	n, err := ParseProxy("172.18.0.2")
+	if err != nil || !n.Contains(net.ParseIP("172.18.0.2")) || n.Contains(net.ParseIP("172.18.0.3")) {
+		t.Errorf("ParseProxy(172.18.0.2) = %v, %v", n, err)
+	}
+	n, err = ParseProxy("172.18.0.0/16")
+	if err != nil || !n.Contains(net.ParseIP("172.18.9.9")) {
+		t.Errorf("ParseProxy(172.18.0.0/16) = %v, %v", n, err)
+	}
+	if _, err := ParseProxy("caddy"); err == nil {
+		t.Error("ParseProxy(caddy) should fail")
+	}
+}
+
+func TestValidateOrigin(t *testing.T) {
+	if err := ValidateOrigin("https://admin.example.com:8443"); err != nil {
+		t.Errorf("ValidateOrigin: %v", err)
+	}
+	for _, o := range []string{"*", "admin.example.com", "ftp://example.com", "https://example.com/", "https://example.com/dashboard"} {
+		if err := ValidateOrigin(o); err == nil {
+			t.Errorf("ValidateOrigin(%q) should fail", o)
+		}
+	}
+}
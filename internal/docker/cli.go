@@ -0,0 +1,57 @@
+package docker
+
+import (
+	"context"
+	"io"
+
+	"github.com/Achilles1089/sovereign-stack/internal/runner"
+)
+
+// cli runs the docker command-line tool, which is still needed for compose
+var cli = runner.Default
+
+// SetRunner replaces the runner used for docker commands and returns the
+// previous one, so tests can restore it
+func SetRunner(r runner.Runner) runner.Runner {
+	prev := cli
+	cli = r
+	return prev
+}
+
+// Run runs the docker CLI with args, streaming output to stdout and stderr
+// when they are non-nil
+func Run(ctx context.Context, stdout, stderr io.Writer, args ...string) (*runner.Result, error) {
+	return cli.Run(ctx, runner.Command{Name: "docker", Args: args, Stdout: stdout, Stderr: stderr})
+}
+
+// Exec runs a command in a running container, feeding it stdin if non-nil
+func Exec(ctx context.Context, container string, stdin io.Reader, args ...string) (*runner.Result, error) {
+	argv := []string{"exec", container}
+	if stdin != nil {
+		argv = []string{"exec", "-i", container}
+	}
+	return cli.Run(ctx, runner.Command{Name: "docker", Args: append(argv, args...), Stdin: stdin})
+}
+
+// Compose runs a docker compose subcommand against composePath
+func Compose(ctx context.Context, composePath string, stdout, stderr io.Writer, args ...string) (*runner.Result, error) {
+	return Run(ctx, stdout, stderr, append([]string{"compose", "-f", composePath}, args...)...)
+}
+
+// ComposeUp starts services in the background, all of them when none are named
+func ComposeUp(ctx context.Context, composePath string, stdout, stderr io.Writer, services ...string) error {
+	_, err := Compose(ctx, composePath, stdout, stderr, append([]string{"up", "-d"}, services...)...)
+	return err
+}
+
+// ComposeDown stops and removes the services of the project at composePath
+func ComposeDown(ctx context.Context, composePath string) error {
+	_, err := Compose(ctx, composePath, nil, nil, "down")
+	return err
+}
+
+// ComposePull pulls latest images
+func ComposePull(ctx context.Context, composePath string, stdout, stderr io.Writer) error {
+	_, err := Compose(ctx, composePath, stdout, stderr, "pull")
+	return err
+}
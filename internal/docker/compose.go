@@ -1,12 +1,16 @@
 package docker
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/Achilles1089/sovereign-stack/internal/config"
+	"github.com/Achilles1089/sovereign-stack/internal/secrets"
 	"gopkg.in/yaml.v3"
 )
 
@@ -18,6 +22,7 @@ type ComposeService struct {
 	Ports         []string          `yaml:"ports,omitempty"`
 	Volumes       []string          `yaml:"volumes,omitempty"`
 	Environment   []string          `yaml:"environment,omitempty"`
+	EnvFile       []string          `yaml:"env_file,omitempty"`
 	DependsOn     []string          `yaml:"depends_on,omitempty"`
 	Labels        map[string]string `yaml:"labels,omitempty"`
 	HealthCheck   *HealthCheck      `yaml:"healthcheck,omitempty"`
@@ -33,18 +38,28 @@ type HealthCheck struct {
 	Retries  int      `yaml:"retries"`
 }
 
-// DeployConfig for GPU resources
+// DeployConfig for resource limits, reservations and GPU devices
 type DeployConfig struct {
 	Resources *Resources `yaml:"resources,omitempty"`
 }
 
-// Resources for resource reservations
+// Resources for resource limits and reservations
 type Resources struct {
+	Limits       *Limits       `yaml:"limits,omitempty"`
 	Reservations *Reservations `yaml:"reservations,omitempty"`
 }
 
-// Reservations for device reservations (GPU)
+// Limits caps what a container may use
+type Limits struct {
+	CPUs   string `yaml:"cpus,omitempty"`   // cores, e.g. "1.5"
+	Memory string `yaml:"memory,omitempty"` // e.g. "2048M"
+	PIDs   int    `yaml:"pids,omitempty"`
+}
+
+// Reservations for guaranteed memory and CPU, and device reservations (GPU)
 type Reservations struct {
+	CPUs    string   `yaml:"cpus,omitempty"`
+	Memory  string   `yaml:"memory,omitempty"`
 	Devices []Device `yaml:"devices,omitempty"`
 }
 
@@ -55,21 +70,86 @@ type Device struct {
 	Capabilities [][]string `yaml:"capabilities"`
 }
 
-// ComposeFile represents the full docker-compose.yml
+// ComposeFile represents one compose project: the core docker-compose.yml
+// or an app's apps/<name>/compose.yml
 type ComposeFile struct {
 	Version  string                     `yaml:"version,omitempty"`
+	Name     string                     `yaml:"name,omitempty"` // compose project name
 	Services map[string]*ComposeService `yaml:"services"`
 	Volumes  map[string]interface{}     `yaml:"volumes,omitempty"`
 	Networks map[string]interface{}     `yaml:"networks,omitempty"`
 }
 
-// GenerateCoreCompose creates the core docker-compose.yml from config
+// CoreProject is the compose project of the core services. Apps used to be
+// services of this project, so their volumes keep its prefix.
+const CoreProject = "sovereign"
+
+// NetworkName is the Docker network shared by the core services and every app
+const NetworkName = "sovereign"
+
+// CoreComposePath returns the compose file of the core services
+func CoreComposePath() string {
+	return filepath.Join(config.ConfigDir(), "docker-compose.yml")
+}
+
+// AppsDir returns the directory holding one compose project per app
+func AppsDir() string {
+	return filepath.Join(config.ConfigDir(), "apps")
+}
+
+// AppComposePath returns the compose file of an app's own project
+func AppComposePath(appName string) string {
+	return filepath.Join(AppsDir(), appName, "compose.yml")
+}
+
+// AppProject returns the compose project name of an app
+func AppProject(appName string) string {
+	return CoreProject + "-" + appName
+}
+
+// AppOf returns the app whose compose file is path, or "" for the core file
+func AppOf(path string) string {
+	name := filepath.Base(filepath.Dir(path))
+	if filepath.Clean(path) == AppComposePath(name) {
+		return name
+	}
+	return ""
+}
+
+// ProjectFiles returns the core compose file followed by each app's, in
+// name order. Missing files are skipped.
+func ProjectFiles() ([]string, error) {
+	var files []string
+	if _, err := os.Stat(CoreComposePath()); err == nil {
+		files = append(files, CoreComposePath())
+	}
+	apps, err := filepath.Glob(AppComposePath("*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(apps)
+	return append(files, apps...), nil
+}
+
+// ProjectFileFor returns the compose file defining service: its app's file
+// if it is an installed app, the core file otherwise
+func ProjectFileFor(service string) string {
+	if _, err := os.Stat(AppComposePath(service)); err == nil {
+		return AppComposePath(service)
+	}
+	return CoreComposePath()
+}
+
+// GenerateCoreCompose creates the core docker-compose.yml from config. Apps
+// live in their own projects; see NewAppCompose.
 func GenerateCoreCompose(cfg *config.Config) *ComposeFile {
 	compose := &ComposeFile{
+		Name:     CoreProject,
 		Services: make(map[string]*ComposeService),
 		Volumes:  make(map[string]interface{}),
 		Networks: map[string]interface{}{
-			"sovereign": map[string]string{"driver": "bridge"},
+			// A fixed name, so app projects can join it as an external network
+			NetworkName: map[string]string{"driver": "bridge", "name": NetworkName},
 		},
 	}
 
@@ -84,9 +164,9 @@ func GenerateCoreCompose(cfg *config.Config) *ComposeFile {
 			Volumes:       []string{"postgres_data:/var/lib/postgresql/data"},
 			Environment: []string{
 				"POSTGRES_USER=sovereign",
-				"POSTGRES_PASSWORD=sovereign",
 				"POSTGRES_DB=sovereign",
 			},
+			EnvFile:  []string{EnvFilePath("postgres")}, // POSTGRES_PASSWORD, see WriteCoreEnvFiles
 			Labels:   sovLabels,
 			Networks: []string{"sovereign"},
 			HealthCheck: &HealthCheck{
@@ -123,26 +203,61 @@ func GenerateCoreCompose(cfg *config.Config) *ComposeFile {
 	return compose
 }
 
-// WriteComposeFile writes the compose file to disk
-func WriteComposeFile(compose *ComposeFile, path string) error {
-	dir := filepath.Dir(path)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
-	}
+// PostgresSecret holds the password of the shared PostgreSQL superuser
+const PostgresSecret = "postgres/password"
+
+// EnvFilePath returns the env file holding a service's credentials. Env files
+// are 0600 so credentials stay out of the world-readable compose file.
+func EnvFilePath(service string) string {
+	return filepath.Join(config.ConfigDir(), "env", service+".env")
+}
 
-	data, err := yaml.Marshal(compose)
+// WriteCoreEnvFiles writes the credential env files for the core services,
+// generating their secrets on first use. Stacks set up before the secret
+// store had the postgres password inline in the core compose file, and an
+// initialised data volume keeps it, so the secret is seeded from there.
+func WriteCoreEnvFiles(cfg *config.Config) error {
+	if !cfg.Services.Postgres {
+		return nil
+	}
+	store := secrets.Default()
+	if _, err := store.Get(PostgresSecret); errors.Is(err, secrets.ErrNotFound) {
+		if old := legacyPostgresPassword(); old != "" {
+			if err := store.Set(PostgresSecret, old); err != nil {
+				return fmt.Errorf("failed to migrate postgres password: %w", err)
+			}
+		}
+	}
+	password, err := store.Ensure(PostgresSecret, secrets.DefaultLength)
 	if err != nil {
-		return fmt.Errorf("failed to serialize compose file: %w", err)
+		return fmt.Errorf("failed to generate postgres password: %w", err)
 	}
+	return secrets.WriteEnvFile(EnvFilePath("postgres"), []string{"POSTGRES_PASSWORD=" + password})
+}
 
-	// Add header comment
-	header := "# Sovereign Stack — Docker Compose Configuration\n# Auto-generated by 'sovereign init'. Do not edit manually.\n# To regenerate: sovereign init --force\n\n"
-
-	if err := os.WriteFile(path, []byte(header+string(data)), 0644); err != nil {
-		return fmt.Errorf("failed to write compose file: %w", err)
+// legacyPostgresPassword returns the POSTGRES_PASSWORD an older core compose
+// file set inline: the current file's, or that of its newest revision with
+// one, since init may already have rewritten the file. Empty if none did.
+func legacyPostgresPassword() string {
+	paths := []string{CoreComposePath()}
+	revs, _ := History()
+	for i := len(revs) - 1; i >= 0; i-- {
+		if revs[i].Path == CoreComposePath() && !revs[i].Removed {
+			paths = append(paths, revisionFile(revs[i].Rev))
+		}
 	}
-
-	return nil
+	for _, path := range paths {
+		compose, err := LoadComposeFile(path)
+		if err != nil || compose.Services["postgres"] == nil {
+			continue
+		}
+		for _, kv := range compose.Services["postgres"].Environment {
+			if v, ok := strings.CutPrefix(kv, "POSTGRES_PASSWORD="); ok {
+				return v
+			}
+		}
+	}
+	return ""
 }
 
 // GenerateCaddyfile creates a basic Caddyfile
@@ -175,19 +290,84 @@ func WriteCaddyfile(cfg *config.Config) error {
 	return os.WriteFile(path, []byte(content), 0644)
 }
 
-// AddAppToCompose merges an app's compose service into the main compose file
+// NewAppCompose returns an empty compose project for an app, joined to the
+// core services' network
+func NewAppCompose(appName string) *ComposeFile {
+	return &ComposeFile{
+		Name:     AppProject(appName),
+		Services: make(map[string]*ComposeService),
+		Volumes:  make(map[string]interface{}),
+		Networks: map[string]interface{}{
+			NetworkName: map[string]interface{}{"external": true, "name": NetworkName},
+		},
+	}
+}
+
+// AddAppToCompose adds an app's service to compose, labelled as an app and
+// attached to the shared network
 func AddAppToCompose(compose *ComposeFile, appName string, service *ComposeService) {
 	service.Labels = map[string]string{
 		"sovereign.managed": "true",
 		"sovereign.app":     appName,
 	}
-	service.Networks = []string{"sovereign"}
+	service.Networks = []string{NetworkName}
 	compose.Services[appName] = service
 }
 
-// RemoveAppFromCompose removes an app from the compose file
-func RemoveAppFromCompose(compose *ComposeFile, appName string) {
-	delete(compose.Services, appName)
+// AppVolume is the definition of an app's named volume. The explicit name
+// is the one it had when apps shared the core project, so existing data
+// survives the move to per-app projects.
+func AppVolume(volume string) map[string]interface{} {
+	return map[string]interface{}{"name": CoreProject + "_" + volume}
+}
+
+// Restart policies accepted by compose
+var restartPolicies = []string{"no", "always", "on-failure", "unless-stopped"}
+
+// ValidRestart reports whether policy is a compose restart policy, such as
+// "unless-stopped" or "on-failure:5"
+func ValidRestart(policy string) bool {
+	name, retries, hasRetries := strings.Cut(policy, ":")
+	if hasRetries {
+		n, err := strconv.Atoi(retries)
+		return name == "on-failure" && err == nil && n > 0
+	}
+	for _, p := range restartPolicies {
+		if policy == p {
+			return true
+		}
+	}
+	return false
+}
+
+// MemoryMB formats megabytes as a compose memory size
+func MemoryMB(mb int) string {
+	return strconv.Itoa(mb) + "M"
+}
+
+// ParseMemoryMB reads a compose memory size such as "512M", "2g" or
+// "1073741824" as megabytes
+func ParseMemoryMB(size string) (int, error) {
+	s := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(size)), "b")
+	unit := int64(1)
+	if s != "" {
+		switch s[len(s)-1] {
+		case 'k':
+			unit = 1 << 10
+		case 'm':
+			unit = 1 << 20
+		case 'g':
+			unit = 1 << 30
+		}
+		if unit > 1 {
+			s = s[:len(s)-1]
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid memory size %q", size)
+	}
+	return int(n * float64(unit) / (1 << 20)), nil
 }
 
 // LoadComposeFile reads a compose file from disk
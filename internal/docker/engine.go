@@ -0,0 +1,473 @@
+package docker
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultHost is the Docker Engine socket used when $DOCKER_HOST is unset
+const DefaultHost = "unix:///var/run/docker.sock"
+
+// ErrNotFound is returned when a container, image or volume does not exist
+var ErrNotFound = errors.New("not found")
+
+// Engine is the subset of the Docker Engine API the stack uses. Client talks
+// to a real daemon; tests can point a Client at a fake socket server.
+type Engine interface {
+	Ping(ctx context.Context) error
+	ListContainers(ctx context.Context, opts ListOptions) ([]Container, error)
+	InspectContainer(ctx context.Context, id string) (*ContainerDetails, error)
+	StartContainer(ctx context.Context, id string) error
+	StopContainer(ctx context.Context, id string, timeout time.Duration) error
+	RemoveContainer(ctx context.Context, id string, force bool) error
+	ContainerLogs(ctx context.Context, id string, opts LogOptions, stdout, stderr io.Writer) error
+	ContainerStats(ctx context.Context, id string) (*ContainerStats, error)
+	ListImages(ctx context.Context) ([]Image, error)
+	ListVolumes(ctx context.Context) ([]Volume, error)
+	RemoveVolume(ctx context.Context, name string, force bool) error
+	Events(ctx context.Context, filters map[string][]string) (<-chan Event, <-chan error)
+}
+
+// ListOptions filters ListContainers
+type ListOptions struct {
+	All     bool                // include stopped containers
+	Filters map[string][]string // Engine filters, e.g. {"name": {"sovereign-"}}
+}
+
+// Port is a published container port
+type Port struct {
+	IP          string `json:"IP"`
+	PrivatePort int    `json:"PrivatePort"`
+	PublicPort  int    `json:"PublicPort"`
+	Type        string `json:"Type"`
+}
+
+// Container is an entry from GET /containers/json
+type Container struct {
+	ID      string            `json:"Id"`
+	Names   []string          `json:"Names"`
+	Image   string            `json:"Image"`
+	State   string            `json:"State"`  // "running", "exited", ...
+	Status  string            `json:"Status"` // e.g. "Up 3 hours (healthy)"
+	Created int64             `json:"Created"`
+	Ports   []Port            `json:"Ports"`
+	Labels  map[string]string `json:"Labels"`
+}
+
+// Name returns the container name without the leading slash
+func (c Container) Name() string {
+	if len(c.Names) == 0 {
+		return ""
+	}
+	return strings.TrimPrefix(c.Names[0], "/")
+}
+
+// ContainerDetails is the part of GET /containers/{id}/json the stack uses
+type ContainerDetails struct {
+	ID           string `json:"Id"`
+	Name         string `json:"Name"`
+	RestartCount int    `json:"RestartCount"`
+	State        struct {
+		Status     string    `json:"Status"`
+		Running    bool      `json:"Running"`
+		ExitCode   int       `json:"ExitCode"`
+		StartedAt  time.Time `json:"StartedAt"`
+		FinishedAt time.Time `json:"FinishedAt"`
+		Health     *struct {
+			Status        string `json:"Status"` // "starting", "healthy" or "unhealthy"
+			FailingStreak int    `json:"FailingStreak"`
+		} `json:"Health"`
+	} `json:"State"`
+	Config struct {
+		Image  string            `json:"Image"`
+		Tty    bool              `json:"Tty"`
+		Labels map[string]string `json:"Labels"`
+	} `json:"Config"`
+}
+
+// HealthStatus returns the health check state, or "none" without a health check
+func (d *ContainerDetails) HealthStatus() string {
+	if d.State.Health == nil || d.State.Health.Status == "" {
+		return "none"
+	}
+	return d.State.Health.Status
+}
+
+// LogOptions selects container log output
+type LogOptions struct {
+	Tail       int // lines from the end; 0 = all
+	Since      time.Time
+	Follow     bool
+	Timestamps bool
+}
+
+// ContainerStats is a one-shot resource usage sample
+type ContainerStats struct {
+	CPUPercent  float64 `json:"cpu_percent"`
+	MemoryUsage uint64  `json:"memory_usage"`
+	MemoryLimit uint64  `json:"memory_limit"`
+	PIDs        uint64  `json:"pids"`
+}
+
+// Image is an entry from GET /images/json
+type Image struct {
+	ID       string   `json:"Id"`
+	RepoTags []string `json:"RepoTags"`
+	Size     int64    `json:"Size"`
+	Created  int64    `json:"Created"`
+}
+
+// Volume is an entry from GET /volumes
+type Volume struct {
+	Name       string            `json:"Name"`
+	Driver     string            `json:"Driver"`
+	Mountpoint string            `json:"Mountpoint"`
+	Labels     map[string]string `json:"Labels"`
+}
+
+// Event is a message from GET /events
+type Event struct {
+	Type   string `json:"Type"`   // "container", "image", "volume", ...
+	Action string `json:"Action"` // "start", "die", "health_status: healthy", ...
+	Actor  struct {
+		ID         string            `json:"ID"`
+		Attributes map[string]string `json:"Attributes"`
+	} `json:"Actor"`
+	Time int64 `json:"time"`
+}
+
+// Client is an Engine API client over a unix socket or plain TCP
+type Client struct {
+	http *http.Client
+	base string
+}
+
+// NewClient connects to host, e.g. "unix:///var/run/docker.sock" or
+// "tcp://127.0.0.1:2375". TLS hosts are not supported.
+func NewClient(host string) (*Client, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid docker host %q: %w", host, err)
+	}
+
+	switch u.Scheme {
+	case "unix":
+		socket := u.Path
+		transport := &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			},
+		}
+		return &Client{http: &http.Client{Transport: transport}, base: "http://docker"}, nil
+	case "tcp", "http":
+		return &Client{http: &http.Client{}, base: "http://" + u.Host}, nil
+	default:
+		return nil, fmt.Errorf("unsupported docker host %q: use unix:// or tcp://", host)
+	}
+}
+
+// DefaultEngine returns a client for $DOCKER_HOST, or the local socket
+func DefaultEngine() (*Client, error) {
+	host := os.Getenv("DOCKER_HOST")
+	if host == "" {
+		host = DefaultHost
+	}
+	return NewClient(host)
+}
+
+// apiError is the error body the Engine returns
+type apiError struct {
+	Message string `json:"message"`
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values) (*http.Response, error) {
+	u := c.base + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("docker engine unreachable: %w", err)
+	}
+	if resp.StatusCode < 300 || resp.StatusCode == http.StatusNotModified {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	var e apiError
+	json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&e)
+	if e.Message == "" {
+		e.Message = resp.Status
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, e.Message)
+	}
+	return nil, fmt.Errorf("docker %s %s: %s", method, path, e.Message)
+}
+
+func (c *Client) getJSON(ctx context.Context, path string, query url.Values, v interface{}) error {
+	resp, err := c.do(ctx, "GET", path, query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("docker %s: invalid response: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Client) call(ctx context.Context, method, path string, query url.Values) error {
+	resp, err := c.do(ctx, method, path, query)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
+
+func encodeFilters(filters map[string][]string) string {
+	if len(filters) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(filters)
+	return string(data)
+}
+
+// Ping checks that the daemon is reachable
+func (c *Client) Ping(ctx context.Context) error {
+	return c.call(ctx, "GET", "/_ping", nil)
+}
+
+// ListContainers lists containers matching opts
+func (c *Client) ListContainers(ctx context.Context, opts ListOptions) ([]Container, error) {
+	q := url.Values{}
+	if opts.All {
+		q.Set("all", "1")
+	}
+	if f := encodeFilters(opts.Filters); f != "" {
+		q.Set("filters", f)
+	}
+	var out []Container
+	return out, c.getJSON(ctx, "/containers/json", q, &out)
+}
+
+// InspectContainer returns low-level details for a container by ID or name
+func (c *Client) InspectContainer(ctx context.Context, id string) (*ContainerDetails, error) {
+	var out ContainerDetails
+	if err := c.getJSON(ctx, "/containers/"+url.PathEscape(id)+"/json", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// StartContainer starts a container; starting a running container is not an error
+func (c *Client) StartContainer(ctx context.Context, id string) error {
+	return c.call(ctx, "POST", "/containers/"+url.PathEscape(id)+"/start", nil)
+}
+
+// StopContainer stops a container, killing it after timeout
+func (c *Client) StopContainer(ctx context.Context, id string, timeout time.Duration) error {
+	q := url.Values{"t": {strconv.Itoa(int(timeout.Seconds()))}}
+	return c.call(ctx, "POST", "/containers/"+url.PathEscape(id)+"/stop", q)
+}
+
+// RemoveContainer deletes a container. force also kills a running one.
+func (c *Client) RemoveContainer(ctx context.Context, id string, force bool) error {
+	q := url.Values{}
+	if force {
+		q.Set("force", "1")
+	}
+	return c.call(ctx, "DELETE", "/containers/"+url.PathEscape(id), q)
+}
+
+// ContainerLogs copies a container's output to stdout and stderr
+func (c *Client) ContainerLogs(ctx context.Context, id string, opts LogOptions, stdout, stderr io.Writer) error {
+	details, err := c.InspectContainer(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	q := url.Values{"stdout": {"1"}, "stderr": {"1"}}
+	if opts.Tail > 0 {
+		q.Set("tail", strconv.Itoa(opts.Tail))
+	}
+	if !opts.Since.IsZero() {
+		q.Set("since", strconv.FormatInt(opts.Since.Unix(), 10))
+	}
+	if opts.Follow {
+		q.Set("follow", "1")
+	}
+	if opts.Timestamps {
+		q.Set("timestamps", "1")
+	}
+
+	resp, err := c.do(ctx, "GET", "/containers/"+url.PathEscape(id)+"/logs", q)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Containers with a TTY stream raw output; others multiplex both streams
+	if details.Config.Tty {
+		_, err = io.Copy(stdout, resp.Body)
+		return err
+	}
+	return demuxLogs(resp.Body, stdout, stderr)
+}
+
+// demuxLogs splits the Engine's multiplexed log stream: each frame is an
+// 8-byte header (stream type, 3 zero bytes, big-endian length) and payload
+func demuxLogs(r io.Reader, stdout, stderr io.Writer) error {
+	br := bufio.NewReader(r)
+	var header [8]byte
+	for {
+		if _, err := io.ReadFull(br, header[:]); err != nil {
+			if err == io.EOF || errors.Is(err, context.Canceled) {
+				return nil
+			}
+			return err
+		}
+		w := stdout
+		if header[0] == 2 {
+			w = stderr
+		}
+		size := int64(binary.BigEndian.Uint32(header[4:]))
+		if _, err := io.CopyN(w, br, size); err != nil {
+			return err
+		}
+	}
+}
+
+// rawStats is the part of GET /containers/{id}/stats used to compute usage
+type rawStats struct {
+	CPUStats    cpuStats `json:"cpu_stats"`
+	PreCPUStats cpuStats `json:"precpu_stats"`
+	MemoryStats struct {
+		Usage uint64            `json:"usage"`
+		Limit uint64            `json:"limit"`
+		Stats map[string]uint64 `json:"stats"`
+	} `json:"memory_stats"`
+	PIDsStats struct {
+		Current uint64 `json:"current"`
+	} `json:"pids_stats"`
+}
+
+type cpuStats struct {
+	CPUUsage struct {
+		TotalUsage uint64 `json:"total_usage"`
+	} `json:"cpu_usage"`
+	SystemUsage uint64 `json:"system_cpu_usage"`
+	OnlineCPUs  uint64 `json:"online_cpus"`
+}
+
+// ContainerStats samples a container's CPU and memory use once
+func (c *Client) ContainerStats(ctx context.Context, id string) (*ContainerStats, error) {
+	var raw rawStats
+	q := url.Values{"stream": {"0"}}
+	if err := c.getJSON(ctx, "/containers/"+url.PathEscape(id)+"/stats", q, &raw); err != nil {
+		return nil, err
+	}
+
+	// Same calculation as `docker stats`: page cache is not counted as used
+	mem := raw.MemoryStats.Usage
+	if cache, ok := raw.MemoryStats.Stats["inactive_file"]; ok && cache < mem {
+		mem -= cache
+	}
+	st := &ContainerStats{
+		MemoryUsage: mem,
+		MemoryLimit: raw.MemoryStats.Limit,
+		PIDs:        raw.PIDsStats.Current,
+	}
+	cpuDelta := float64(raw.CPUStats.CPUUsage.TotalUsage) - float64(raw.PreCPUStats.CPUUsage.TotalUsage)
+	sysDelta := float64(raw.CPUStats.SystemUsage) - float64(raw.PreCPUStats.SystemUsage)
+	if cpuDelta > 0 && sysDelta > 0 {
+		cpus := float64(raw.CPUStats.OnlineCPUs)
+		if cpus == 0 {
+			cpus = 1
+		}
+		st.CPUPercent = cpuDelta / sysDelta * cpus * 100
+	}
+	return st, nil
+}
+
+// ListImages lists local images
+func (c *Client) ListImages(ctx context.Context) ([]Image, error) {
+	var out []Image
+	return out, c.getJSON(ctx, "/images/json", nil, &out)
+}
+
+// ListVolumes lists volumes
+func (c *Client) ListVolumes(ctx context.Context) ([]Volume, error) {
+	var out struct {
+		Volumes []Volume `json:"Volumes"`
+	}
+	err := c.getJSON(ctx, "/volumes", nil, &out)
+	return out.Volumes, err
+}
+
+// RemoveVolume deletes a volume
+func (c *Client) RemoveVolume(ctx context.Context, name string, force bool) error {
+	q := url.Values{}
+	if force {
+		q.Set("force", "1")
+	}
+	return c.call(ctx, "DELETE", "/volumes/"+url.PathEscape(name), q)
+}
+
+// Events streams daemon events until ctx is cancelled. The error channel
+// receives at most one error and is closed with the event channel.
+func (c *Client) Events(ctx context.Context, filters map[string][]string) (<-chan Event, <-chan error) {
+	events := make(chan Event)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(events)
+		defer close(errs)
+
+		q := url.Values{}
+		if f := encodeFilters(filters); f != "" {
+			q.Set("filters", f)
+		}
+		resp, err := c.do(ctx, "GET", "/events", q)
+		if err != nil {
+			errs <- err
+			return
+		}
+		defer resp.Body.Close()
+
+		dec := json.NewDecoder(resp.Body)
+		for {
+			var ev Event
+			if err := dec.Decode(&ev); err != nil {
+				if ctx.Err() == nil && err != io.EOF {
+					errs <- err
+				}
+				return
+			}
+			select {
+			case events <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, errs
+}
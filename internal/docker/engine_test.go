@@ -0,0 +1,187 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeEngine serves handler on a unix socket and returns a client for it
+func fakeEngine(t *testing.T, handler http.Handler) *Client {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	srv := httptest.NewUnstartedServer(handler)
+	srv.Listener = ln
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	c, err := NewClient("unix://" + socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestCheckServices(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("all") != "1" || !strings.Contains(r.URL.Query().Get("filters"), "sovereign-") {
+			t.Errorf("unexpected list query: %s", r.URL.RawQuery)
+		}
+		fmt.Fprint(w, `[
+			{"Id": "b2", "Names": ["/sovereign-nextcloud"], "Image": "nextcloud:28", "State": "exited", "Status": "Exited (1) 2 minutes ago",
+			 "Labels": {"sovereign.app": "nextcloud"}},
+			{"Id": "a1", "Names": ["/sovereign-postgres"], "Image": "postgres:16", "State": "running", "Status": "Up 3 hours (healthy)",
+			 "Ports": [{"IP": "127.0.0.1", "PrivatePort": 5432, "PublicPort": 5432, "Type": "tcp"}]}
+		]`)
+	})
+	mux.HandleFunc("/containers/a1/json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"Id": "a1", "RestartCount": 2, "State": {"Running": true, "StartedAt": "2026-10-17T09:00:00Z", "Health": {"Status": "healthy"}}}`)
+	})
+	mux.HandleFunc("/containers/b2/json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"Id": "b2", "RestartCount": 5, "State": {"Running": false, "StartedAt": "0001-01-01T00:00:00Z"}}`)
+	})
+
+	services, err := CheckServices(context.Background(), fakeEngine(t, mux))
+	if err != nil {
+		t.Fatalf("CheckServices failed: %v", err)
+	}
+	if len(services) != 2 {
+		t.Fatalf("expected 2 services, got %d", len(services))
+	}
+
+	app, pg := services[0], services[1]
+	if pg.Name != "sovereign-postgres" || !pg.Running || pg.Health != "healthy" || pg.RestartCount != 2 {
+		t.Errorf("unexpected postgres health: %+v", pg)
+	}
+	if pg.StartedAt == nil || !pg.StartedAt.Equal(time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("started_at = %v", pg.StartedAt)
+	}
+	if pg.Uptime != "3 hours" || pg.Ports != "127.0.0.1:5432->5432/tcp" {
+		t.Errorf("uptime=%q ports=%q", pg.Uptime, pg.Ports)
+	}
+	if app.Running || app.Health != "none" || app.RestartCount != 5 || app.StartedAt != nil || !app.IsApp || app.AppName != "nextcloud" {
+		t.Errorf("unexpected app health: %+v", app)
+	}
+}
+
+func TestEngineErrors(t *testing.T) {
+	c := fakeEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/containers/missing/json":
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "No such container: missing"}`)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `{"message": "daemon exploded"}`)
+		}
+	}))
+
+	_, err := c.InspectContainer(context.Background(), "missing")
+	if !errors.Is(err, ErrNotFound) || !strings.Contains(err.Error(), "No such container") {
+		t.Errorf("expected ErrNotFound with the daemon message, got %v", err)
+	}
+	err = c.StartContainer(context.Background(), "x")
+	if err == nil || errors.Is(err, ErrNotFound) || !strings.Contains(err.Error(), "daemon exploded") {
+		t.Errorf("expected the daemon message, got %v", err)
+	}
+
+	unreachable, _ := NewClient("unix://" + filepath.Join(t.TempDir(), "none.sock"))
+	if err := unreachable.Ping(context.Background()); err == nil {
+		t.Error("Ping should fail without a daemon")
+	}
+}
+
+func TestContainerLogsDemux(t *testing.T) {
+	frame := func(stream byte, text string) []byte {
+		header := make([]byte, 8)
+		header[0] = stream
+		binary.BigEndian.PutUint32(header[4:], uint32(len(text)))
+		return append(header, text...)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/containers/web/json", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"Id": "web", "Config": {"Tty": false}}`)
+	})
+	mux.HandleFunc("/containers/web/logs", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("tail") != "50" {
+			t.Errorf("tail = %q", r.URL.Query().Get("tail"))
+		}
+		w.Write(frame(1, "started\n"))
+		w.Write(frame(2, "warning: low disk\n"))
+		w.Write(frame(1, "ready\n"))
+	})
+
+	var stdout, stderr bytes.Buffer
+	err := fakeEngine(t, mux).ContainerLogs(context.Background(), "web", LogOptions{Tail: 50}, &stdout, &stderr)
+	if err != nil {
+		t.Fatalf("ContainerLogs failed: %v", err)
+	}
+	if stdout.String() != "started\nready\n" || stderr.String() != "warning: low disk\n" {
+		t.Errorf("stdout=%q stderr=%q", stdout.String(), stderr.String())
+	}
+}
+
+func TestContainerStats(t *testing.T) {
+	c := fakeEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("stream") != "0" {
+			t.Errorf("stats should not stream: %s", r.URL.RawQuery)
+		}
+		fmt.Fprint(w, `{
+			"cpu_stats": {"cpu_usage": {"total_usage": 300}, "system_cpu_usage": 2000, "online_cpus": 4},
+			"precpu_stats": {"cpu_usage": {"total_usage": 100}, "system_cpu_usage": 1000},
+			"memory_stats": {"usage": 600, "limit": 4096, "stats": {"inactive_file": 100}},
+			"pids_stats": {"current": 7}
+		}`)
+	}))
+
+	st, err := c.ContainerStats(context.Background(), "web")
+	if err != nil {
+		t.Fatalf("ContainerStats failed: %v", err)
+	}
+	if st.CPUPercent != 80 || st.MemoryUsage != 500 || st.MemoryLimit != 4096 || st.PIDs != 7 {
+		t.Errorf("unexpected stats: %+v", st)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	c := fakeEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"Type": "container", "Action": "start", "Actor": {"ID": "a1", "Attributes": {"name": "sovereign-postgres"}}, "time": 1}`)
+		fmt.Fprintln(w, `{"Type": "container", "Action": "die", "Actor": {"ID": "a1"}, "time": 2}`)
+	}))
+
+	events, errs := c.Events(context.Background(), map[string][]string{"type": {"container"}})
+	var actions []string
+	for ev := range events {
+		actions = append(actions, ev.Action)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("Events failed: %v", err)
+	}
+	if strings.Join(actions, ",") != "start,die" {
+		t.Errorf("actions = %v", actions)
+	}
+}
+
+func TestNewClientHosts(t *testing.T) {
+	if c, err := NewClient("tcp://127.0.0.1:2375"); err != nil || c.base != "http://127.0.0.1:2375" {
+		t.Errorf("tcp host: %v", err)
+	}
+	if _, err := NewClient("ssh://user@host"); err == nil {
+		t.Error("expected unsupported scheme to be rejected")
+	}
+}
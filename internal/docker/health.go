@@ -2,100 +2,140 @@ package docker
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os/exec"
+	"sort"
 	"strings"
 	"time"
 )
 
 // ServiceHealth represents the health of a Docker service
 type ServiceHealth struct {
-	Name    string `json:"name"`
-	Running bool   `json:"running"`
-	Status  string `json:"status"`
-	Uptime  string `json:"uptime"`
-	Ports   string `json:"ports"`
-	Image   string `json:"image"`
-	IsApp   bool   `json:"is_app"`
-	AppName string `json:"app_name"`
+	Name         string     `json:"name"`
+	Running      bool       `json:"running"`
+	Status       string     `json:"status"`
+	Health       string     `json:"health"` // "healthy", "unhealthy", "starting" or "none"
+	RestartCount int        `json:"restart_count"`
+	StartedAt    *time.Time `json:"started_at,omitempty"`
+	Uptime       string     `json:"uptime"`
+	Ports        string     `json:"ports"`
+	Image        string     `json:"image"`
+	IsApp        bool       `json:"is_app"`
+	AppName      string     `json:"app_name"`
 }
 
 // CheckAllServices returns the health of all sovereign-managed containers
 func CheckAllServices() ([]ServiceHealth, error) {
-	out, err := exec.Command("docker", "ps", "-a",
-		"--filter", "name=sovereign-",
-		"--format", "{{.Names}}|{{.Status}}|{{.Ports}}|{{.Image}}|{{.Label \"sovereign.app\"}}",
-	).Output()
+	engine, err := DefaultEngine()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return CheckServices(ctx, engine)
+}
+
+// CheckServices returns the health of all sovereign-managed containers
+// known to engine
+func CheckServices(ctx context.Context, engine Engine) ([]ServiceHealth, error) {
+	containers, err := engine.ListContainers(ctx, ListOptions{
+		All:     true,
+		Filters: map[string][]string{"name": {"sovereign-"}},
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to query Docker: %w", err)
 	}
 
 	var services []ServiceHealth
-	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
-		if line == "" {
-			continue
-		}
-
-		parts := strings.SplitN(line, "|", 5)
-		if len(parts) < 4 {
-			continue
-		}
-
+	for _, c := range containers {
 		sh := ServiceHealth{
-			Name:    parts[0],
-			Running: strings.HasPrefix(parts[1], "Up"),
-			Status:  parts[1],
-			Ports:   parts[2],
-			Image:   parts[3],
+			Name:    c.Name(),
+			Running: c.State == "running",
+			Status:  c.Status,
+			Health:  "none",
+			Uptime:  extractUptime(c.Status),
+			Ports:   formatPorts(c.Ports),
+			Image:   c.Image,
 		}
-
-		if len(parts) >= 5 && parts[4] != "" {
+		if app := c.Labels["sovereign.app"]; app != "" {
 			sh.IsApp = true
-			sh.AppName = parts[4]
+			sh.AppName = app
 		}
 
-		// Clean up the name (remove "sovereign-" prefix for display)
-		sh.Uptime = extractUptime(parts[1])
+		// The list only has a status string; inspect for the structured state
+		details, err := engine.InspectContainer(ctx, c.ID)
+		if err == nil {
+			sh.Health = details.HealthStatus()
+			sh.RestartCount = details.RestartCount
+			if started := details.State.StartedAt; !started.IsZero() && started.Year() > 1 {
+				sh.StartedAt = &started
+			}
+		} else if !errors.Is(err, ErrNotFound) {
+			return nil, fmt.Errorf("failed to inspect %s: %w", sh.Name, err)
+		}
 
 		services = append(services, sh)
 	}
 
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
 	return services, nil
 }
 
 // IsDockerAvailable checks if Docker daemon is running
 func IsDockerAvailable() bool {
+	engine, err := DefaultEngine()
+	if err != nil {
+		return false
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-
-	err := exec.CommandContext(ctx, "docker", "info").Run()
-	return err == nil
+	return engine.Ping(ctx) == nil
 }
 
-// ComposeUp runs docker compose up for the sovereign stack
-func ComposeUp(composePath string) error {
-	cmd := exec.Command("docker", "compose", "-f", composePath, "up", "-d")
-	cmd.Stdout = nil
-	cmd.Stderr = nil
-	return cmd.Run()
-}
+// RemoveContainer stops and deletes a container by name. A container that
+// does not exist is not an error.
+func RemoveContainer(name string) error {
+	engine, err := DefaultEngine()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
 
-// ComposeDown stops all sovereign services
-func ComposeDown(composePath string) error {
-	cmd := exec.Command("docker", "compose", "-f", composePath, "down")
-	return cmd.Run()
+	if err := engine.StopContainer(ctx, name, 10*time.Second); err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	if err := engine.RemoveContainer(ctx, name, true); err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return nil
 }
 
-// ComposePull pulls latest images
-func ComposePull(composePath string) error {
-	cmd := exec.Command("docker", "compose", "-f", composePath, "pull")
-	return cmd.Run()
+// formatPorts renders published ports the way `docker ps` does
+func formatPorts(ports []Port) string {
+	var parts []string
+	for _, p := range ports {
+		if p.PublicPort == 0 {
+			parts = append(parts, fmt.Sprintf("%d/%s", p.PrivatePort, p.Type))
+			continue
+		}
+		ip := p.IP
+		if ip == "" {
+			ip = "0.0.0.0"
+		}
+		parts = append(parts, fmt.Sprintf("%s:%d->%d/%s", ip, p.PublicPort, p.PrivatePort, p.Type))
+	}
+	return strings.Join(parts, ", ")
 }
 
 // extractUptime parses Docker status string to get clean uptime
 func extractUptime(status string) string {
 	if strings.HasPrefix(status, "Up ") {
-		return strings.TrimPrefix(status, "Up ")
+		status = strings.TrimPrefix(status, "Up ")
+		// Health is reported separately; drop the "(healthy)" suffix
+		if i := strings.Index(status, " ("); i > 0 {
+			status = status[:i]
+		}
 	}
 	return status
 }
@@ -0,0 +1,392 @@
+package docker
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Achilles1089/sovereign-stack/internal/config"
+	"github.com/Achilles1089/sovereign-stack/internal/filelock"
+	"gopkg.in/yaml.v3"
+)
+
+// historyLimit is how many compose revisions are kept on disk
+const historyLimit = 50
+
+// Revision is a saved version of one of the stack's compose files
+type Revision struct {
+	Rev      int       `json:"rev"`
+	Time     time.Time `json:"time"`
+	Path     string    `json:"path"`    // the compose file it was written to
+	Project  string    `json:"project"` // compose project name, e.g. "sovereign-nextcloud"
+	Reason   string    `json:"reason"`  // e.g. "install nextcloud", "rollback to 3"
+	Services []string  `json:"services"`
+	Removed  bool      `json:"removed,omitempty"` // the file was deleted, e.g. by app remove
+}
+
+// composeLocks serialise writers of each compose file within this process;
+// the file locks cover other processes such as the CLI and the dashboard
+var composeLocks sync.Map
+
+// historyMu guards the revision index, which all compose files share
+var historyMu sync.Mutex
+
+// HistoryDir returns where revisions of every compose file in the stack
+// are kept
+func HistoryDir() string {
+	return filepath.Join(config.ConfigDir(), "compose-history")
+}
+
+// lockCompose takes the lock guarding load-modify-write of path
+func lockCompose(path string) (func(), error) {
+	mu, _ := composeLocks.LoadOrStore(filepath.Clean(path), &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	lock, err := filelock.Acquire(path + ".lock")
+	if err != nil {
+		mu.(*sync.Mutex).Unlock()
+		return nil, fmt.Errorf("failed to lock compose file: %w", err)
+	}
+	return func() {
+		lock.Release()
+		mu.(*sync.Mutex).Unlock()
+	}, nil
+}
+
+// lockHistory takes the lock guarding the revision index. It is always
+// taken after a compose file's lock, never before.
+func lockHistory() (func(), error) {
+	historyMu.Lock()
+	lock, err := filelock.Acquire(filepath.Join(HistoryDir(), "index.lock"))
+	if err != nil {
+		historyMu.Unlock()
+		return nil, fmt.Errorf("failed to lock compose history: %w", err)
+	}
+	return func() {
+		lock.Release()
+		historyMu.Unlock()
+	}, nil
+}
+
+// UpdateCompose applies fn to the compose file at path under a lock and
+// writes the result as a new revision. fn edits a copy; if it returns an
+// error, or changes nothing, the file is left alone. A missing file starts
+// out empty.
+func UpdateCompose(path, reason string, fn func(*ComposeFile) error) (*Plan, error) {
+	unlock, err := lockCompose(path)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	current, err := LoadComposeOrEmpty(path)
+	if err != nil {
+		return nil, err
+	}
+	proposed, err := current.Clone()
+	if err != nil {
+		return nil, err
+	}
+	if err := fn(proposed); err != nil {
+		return nil, err
+	}
+
+	plan := Diff(path, current, proposed)
+	if plan.Empty() {
+		return plan, nil
+	}
+	return plan, writeCompose(proposed, path, reason)
+}
+
+// WriteComposeFile replaces the compose file at path, keeping the previous
+// contents in the revision history
+func WriteComposeFile(compose *ComposeFile, path, reason string) error {
+	unlock, err := lockCompose(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return writeCompose(compose, path, reason)
+}
+
+// composeHeader starts every generated compose file
+const composeHeader = "# Sovereign Stack — Docker Compose Configuration\n# Auto-generated by 'sovereign init'. Do not edit manually.\n# To regenerate: sovereign init --force\n\n"
+
+// writeCompose atomically writes compose to path and records it as a
+// revision. The caller holds the compose lock.
+func writeCompose(compose *ComposeFile, path, reason string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	body, err := yaml.Marshal(compose)
+	if err != nil {
+		return fmt.Errorf("failed to serialize compose file: %w", err)
+	}
+	data := append([]byte(composeHeader), body...)
+
+	unlock, err := lockHistory()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// A file written before history was kept becomes its first revision, so
+	// the first change can still be rolled back
+	revs, err := readHistory()
+	if err != nil {
+		return err
+	}
+	if !hasRevisions(revs, path) {
+		if old, err := os.ReadFile(path); err == nil && !bytes.Equal(old, data) {
+			if _, err := saveRevision(path, old, "before history", nil, false); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := atomicWrite(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write compose file: %w", err)
+	}
+	if _, err := saveRevision(path, data, reason, compose, false); err != nil {
+		return fmt.Errorf("compose file written but not recorded in history: %w", err)
+	}
+	return nil
+}
+
+// RemoveComposeFile deletes the compose file at path and its directory,
+// recording the removal in the history. Its earlier revisions can still be
+// rolled back to.
+func RemoveComposeFile(path, reason string) error {
+	unlock, err := lockCompose(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read compose file: %w", err)
+	}
+
+	unlockHistory, err := lockHistory()
+	if err != nil {
+		return err
+	}
+	defer unlockHistory()
+
+	revs, err := readHistory()
+	if err != nil {
+		return err
+	}
+	if !hasRevisions(revs, path) {
+		if _, err := saveRevision(path, data, "before history", nil, false); err != nil {
+			return err
+		}
+	}
+	if _, err := saveRevision(path, data, reason, nil, true); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", filepath.Dir(path), err)
+	}
+	return nil
+}
+
+func hasRevisions(revs []Revision, path string) bool {
+	for _, r := range revs {
+		if r.Path == path {
+			return true
+		}
+	}
+	return false
+}
+
+// atomicWrite replaces path via a temp file and rename, so readers never
+// see a partially written file
+func atomicWrite(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func historyIndex() string {
+	return filepath.Join(HistoryDir(), "index.jsonl")
+}
+
+func revisionFile(rev int) string {
+	return filepath.Join(HistoryDir(), fmt.Sprintf("%06d.yml", rev))
+}
+
+// saveRevision stores data as the next revision of path and drops
+// revisions beyond historyLimit. The caller holds the history lock. A
+// removal revision keeps the deleted contents for reference.
+func saveRevision(path string, data []byte, reason string, compose *ComposeFile, removed bool) (*Revision, error) {
+	revs, err := readHistory()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(HistoryDir(), 0700); err != nil {
+		return nil, err
+	}
+
+	rev := &Revision{Rev: 1, Time: time.Now().UTC(), Path: path, Reason: reason, Services: []string{}, Removed: removed}
+	if len(revs) > 0 {
+		rev.Rev = revs[len(revs)-1].Rev + 1
+	}
+	if compose == nil {
+		compose = &ComposeFile{}
+		yaml.Unmarshal(data, compose)
+	}
+	rev.Project = compose.Name
+	for name := range compose.Services {
+		rev.Services = append(rev.Services, name)
+	}
+	sort.Strings(rev.Services)
+
+	if err := atomicWrite(revisionFile(rev.Rev), data, 0600); err != nil {
+		return nil, err
+	}
+
+	revs = append(revs, *rev)
+	if len(revs) > historyLimit {
+		for _, old := range revs[:len(revs)-historyLimit] {
+			os.Remove(revisionFile(old.Rev))
+		}
+		revs = revs[len(revs)-historyLimit:]
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, r := range revs {
+		enc.Encode(r)
+	}
+	if err := atomicWrite(historyIndex(), buf.Bytes(), 0600); err != nil {
+		return nil, err
+	}
+	return rev, nil
+}
+
+// readHistory returns the recorded revisions, oldest first
+func readHistory() ([]Revision, error) {
+	f, err := os.Open(historyIndex())
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var revs []Revision
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r Revision
+		if json.Unmarshal(scanner.Bytes(), &r) == nil {
+			revs = append(revs, r)
+		}
+	}
+	return revs, scanner.Err()
+}
+
+// History returns the saved revisions of the stack's compose files, oldest
+// first
+func History() ([]Revision, error) {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	return readHistory()
+}
+
+// ErrUnknownRevision is returned for a revision not in the history
+var ErrUnknownRevision = errors.New("unknown compose revision")
+
+// PlanRollback returns the changes rolling back to rev would make. The
+// plan's Path is the compose file the revision belongs to.
+func PlanRollback(rev int) (*Plan, error) {
+	r, target, err := loadRevision(rev)
+	if err != nil {
+		return nil, err
+	}
+	current, err := LoadComposeOrEmpty(r.Path)
+	if err != nil {
+		return nil, err
+	}
+	return Diff(r.Path, current, target), nil
+}
+
+// Rollback makes revision rev the current contents of its compose file
+// again, recorded as a new revision. A file deleted since is recreated.
+// The caller restarts services to apply it.
+func Rollback(rev int) (*Plan, error) {
+	r, target, err := loadRevision(rev)
+	if err != nil {
+		return nil, err
+	}
+	return UpdateCompose(r.Path, fmt.Sprintf("rollback to %d", rev), func(c *ComposeFile) error {
+		*c = *target
+		return nil
+	})
+}
+
+func loadRevision(rev int) (*Revision, *ComposeFile, error) {
+	revs, err := History()
+	if err != nil {
+		return nil, nil, err
+	}
+	var r *Revision
+	for i := range revs {
+		if revs[i].Rev == rev {
+			r = &revs[i]
+		}
+	}
+	if r == nil {
+		return nil, nil, fmt.Errorf("%w: %d", ErrUnknownRevision, rev)
+	}
+	if r.Removed {
+		return nil, nil, fmt.Errorf("revision %d removed %s; roll back to an earlier revision", rev, r.Path)
+	}
+
+	data, err := os.ReadFile(revisionFile(rev))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, fmt.Errorf("%w: %d", ErrUnknownRevision, rev)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	compose := &ComposeFile{}
+	if err := yaml.Unmarshal(data, compose); err != nil {
+		return nil, nil, fmt.Errorf("revision %d is unreadable: %w", rev, err)
+	}
+	if compose.Services == nil {
+		compose.Services = make(map[string]*ComposeService)
+	}
+	return r, compose, nil
+}
@@ -0,0 +1,202 @@
+package docker
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Achilles1089/sovereign-stack/internal/config"
+	"github.com/Achilles1089/sovereign-stack/internal/secrets"
+)
+
+func addService(name string) func(*ComposeFile) error {
+	return func(c *ComposeFile) error {
+		c.Services[name] = &ComposeService{Image: name + ":latest"}
+		return nil
+	}
+}
+
+func TestUpdateComposeConcurrent(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := CoreComposePath()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := UpdateCompose(path, fmt.Sprintf("install app%d", i), addService(fmt.Sprintf("app%d", i))); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	compose, err := LoadComposeFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(compose.Services) != 10 {
+		t.Errorf("lost updates: %d of 10 services saved", len(compose.Services))
+	}
+	revs, _ := History()
+	if len(revs) != 10 || revs[9].Rev != 10 || len(revs[9].Services) != 10 {
+		t.Errorf("unexpected history: %+v", revs)
+	}
+
+	leftovers, _ := filepath.Glob(filepath.Join(filepath.Dir(path), ".docker-compose.yml.tmp-*"))
+	if len(leftovers) != 0 {
+		t.Errorf("temp files left behind: %v", leftovers)
+	}
+}
+
+func TestUpdateComposeNoChange(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := CoreComposePath()
+	UpdateCompose(path, "install a", addService("a"))
+
+	plan, err := UpdateCompose(path, "install a again", addService("a"))
+	if err != nil || !plan.Empty() {
+		t.Fatalf("expected an empty plan, got %+v, %v", plan, err)
+	}
+	if _, err := UpdateCompose(path, "fail", func(*ComposeFile) error { return errors.New("nope") }); err == nil {
+		t.Error("fn's error should be returned")
+	}
+	if revs, _ := History(); len(revs) != 1 {
+		t.Errorf("unchanged or failed updates should not add revisions: %+v", revs)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := CoreComposePath()
+
+	// A file from before history was kept becomes revision 1
+	os.MkdirAll(filepath.Dir(path), 0755)
+	os.WriteFile(path, []byte("services:\n    legacy:\n        image: legacy:1\n"), 0644)
+	UpdateCompose(path, "install a", addService("a"))
+	UpdateCompose(path, "install b", addService("b"))
+
+	revs, _ := History()
+	if len(revs) != 3 || revs[0].Reason != "before history" || strings.Join(revs[0].Services, ",") != "legacy" {
+		t.Fatalf("unexpected history: %+v", revs)
+	}
+
+	plan, err := PlanRollback(2)
+	if err != nil || plan.Path != path || plan.Summary() != "0 to add, 0 to change, 1 to remove" {
+		t.Fatalf("PlanRollback: %v %+v", err, plan)
+	}
+	if _, err := Rollback(2); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+
+	compose, _ := LoadComposeFile(path)
+	if _, ok := compose.Services["b"]; ok || compose.Services["a"] == nil || compose.Services["legacy"] == nil {
+		t.Errorf("rollback did not restore revision 2: %v", compose.Services)
+	}
+	revs, _ = History()
+	if last := revs[len(revs)-1]; last.Rev != 4 || last.Reason != "rollback to 2" {
+		t.Errorf("rollback should be recorded as a new revision: %+v", last)
+	}
+
+	if _, err := Rollback(99); !errors.Is(err, ErrUnknownRevision) {
+		t.Errorf("expected ErrUnknownRevision, got %v", err)
+	}
+}
+
+func TestRemoveComposeFileRollback(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	core, app := CoreComposePath(), AppComposePath("gitea")
+	UpdateCompose(core, "init", addService("postgres"))
+	UpdateCompose(app, "install gitea", func(c *ComposeFile) error {
+		*c = *NewAppCompose("gitea")
+		return addService("gitea")(c)
+	})
+
+	if err := RemoveComposeFile(app, "remove gitea"); err != nil {
+		t.Fatalf("RemoveComposeFile failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Dir(app)); !os.IsNotExist(err) {
+		t.Errorf("app directory should be removed: %v", err)
+	}
+
+	revs, _ := History()
+	if len(revs) != 3 || revs[1].Project != "sovereign-gitea" || !revs[2].Removed || revs[2].Path != app {
+		t.Fatalf("unexpected history: %+v", revs)
+	}
+	if _, err := Rollback(3); err == nil {
+		t.Error("rolling back to a removal should fail")
+	}
+
+	plan, err := Rollback(2)
+	if err != nil || plan.Path != app || plan.Count(ActionAdd) == 0 {
+		t.Fatalf("Rollback failed: %v %+v", err, plan)
+	}
+	compose, err := LoadComposeFile(app)
+	if err != nil || compose.Name != "sovereign-gitea" || compose.Services["gitea"] == nil {
+		t.Errorf("rollback did not recreate the app's compose file: %+v, %v", compose, err)
+	}
+}
+
+func TestHistoryLimit(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := CoreComposePath()
+	for i := 0; i < historyLimit+5; i++ {
+		UpdateCompose(path, "install", addService(fmt.Sprintf("s%d", i)))
+	}
+
+	revs, _ := History()
+	if len(revs) != historyLimit || revs[0].Rev != 6 {
+		t.Errorf("expected the last %d revisions from 6, got %d from %d", historyLimit, len(revs), revs[0].Rev)
+	}
+	files, _ := filepath.Glob(filepath.Join(HistoryDir(), "*.yml"))
+	if len(files) != historyLimit {
+		t.Errorf("expected %d revision files, got %d", historyLimit, len(files))
+	}
+}
+
+func TestWriteCoreEnvFilesKeepsLegacyPassword(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := config.DefaultConfig()
+	cfg.Services.Postgres = true
+
+	// An install from before the secret store, since rewritten by init
+	legacy := GenerateCoreCompose(cfg)
+	legacy.Services["postgres"].EnvFile = nil
+	legacy.Services["postgres"].Environment = []string{"POSTGRES_USER=sovereign", "POSTGRES_PASSWORD=sovereign", "POSTGRES_DB=sovereign"}
+	if err := WriteComposeFile(legacy, CoreComposePath(), "init"); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteComposeFile(GenerateCoreCompose(cfg), CoreComposePath(), "init"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteCoreEnvFiles(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if password, err := secrets.Default().Get(PostgresSecret); err != nil || password != "sovereign" {
+		t.Errorf("the secret should keep the volume's password, got %q, %v", password, err)
+	}
+	data, _ := os.ReadFile(EnvFilePath("postgres"))
+	if !strings.HasSuffix(string(data), "\nPOSTGRES_PASSWORD=sovereign\n") {
+		t.Errorf("env file = %q", data)
+	}
+}
+
+func TestWriteCoreEnvFilesGeneratesPassword(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	cfg := config.DefaultConfig()
+	cfg.Services.Postgres = true
+
+	if err := WriteCoreEnvFiles(cfg); err != nil {
+		t.Fatal(err)
+	}
+	password, err := secrets.Default().Get(PostgresSecret)
+	if err != nil || len(password) != secrets.DefaultLength {
+		t.Errorf("a fresh install should get a generated password, got %q, %v", password, err)
+	}
+}
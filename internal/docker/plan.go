@@ -0,0 +1,248 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Change actions
+const (
+	ActionAdd    = "add"
+	ActionChange = "change"
+	ActionRemove = "remove"
+)
+
+// Change resource kinds
+const (
+	KindService   = "service"
+	KindVolume    = "volume"
+	KindNetwork   = "network"
+	KindContainer = "container"
+)
+
+// FieldChange is one changed setting of a service. Old is empty for added
+// settings and New for removed ones.
+type FieldChange struct {
+	Field string `json:"field"` // e.g. "image", "ports", "environment.TZ"
+	Old   string `json:"old,omitempty"`
+	New   string `json:"new,omitempty"`
+}
+
+// Change is a resource added, changed or removed by a plan
+type Change struct {
+	Kind   string        `json:"kind"`   // service, volume, network or container
+	Name   string        `json:"name"`   // compose key or container name
+	Action string        `json:"action"` // add, change or remove
+	Fields []FieldChange `json:"fields,omitempty"`
+}
+
+// Plan is the difference between the current and a proposed compose file
+type Plan struct {
+	Path    string   `json:"path"`
+	Changes []Change `json:"changes"`
+}
+
+// Empty reports whether the plan changes nothing
+func (p *Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// Count returns how many changes have the given action
+func (p *Plan) Count(action string) int {
+	n := 0
+	for _, c := range p.Changes {
+		if c.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// Summary describes the plan in one line, e.g. "2 to add, 1 to change, 0 to remove"
+func (p *Plan) Summary() string {
+	return fmt.Sprintf("%d to add, %d to change, %d to remove",
+		p.Count(ActionAdd), p.Count(ActionChange), p.Count(ActionRemove))
+}
+
+// LoadComposeOrEmpty reads a compose file, treating a missing file as empty
+func LoadComposeOrEmpty(path string) (*ComposeFile, error) {
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return &ComposeFile{Services: map[string]*ComposeService{}}, nil
+	}
+	return LoadComposeFile(path)
+}
+
+// Clone returns a deep copy of the compose file, so a proposed change can be
+// built without touching the current one
+func (c *ComposeFile) Clone() (*ComposeFile, error) {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	out := &ComposeFile{}
+	if err := yaml.Unmarshal(data, out); err != nil {
+		return nil, err
+	}
+	if out.Services == nil {
+		out.Services = make(map[string]*ComposeService)
+	}
+	if out.Volumes == nil {
+		out.Volumes = make(map[string]interface{})
+	}
+	return out, nil
+}
+
+// Diff computes the plan that turns current into proposed. Services come
+// first, then volumes and networks, each sorted by name.
+func Diff(path string, current, proposed *ComposeFile) *Plan {
+	if current == nil {
+		current = &ComposeFile{}
+	}
+	plan := &Plan{Path: path, Changes: []Change{}}
+
+	for _, name := range unionKeys(current.Services, proposed.Services) {
+		old, neu := current.Services[name], proposed.Services[name]
+		switch {
+		case old == nil:
+			plan.Changes = append(plan.Changes, Change{Kind: KindService, Name: name, Action: ActionAdd, Fields: diffService(&ComposeService{}, neu)})
+		case neu == nil:
+			plan.Changes = append(plan.Changes, Change{Kind: KindService, Name: name, Action: ActionRemove})
+		default:
+			if fields := diffService(old, neu); len(fields) > 0 {
+				plan.Changes = append(plan.Changes, Change{Kind: KindService, Name: name, Action: ActionChange, Fields: fields})
+			}
+		}
+	}
+	plan.Changes = append(plan.Changes, diffTopLevel(KindVolume, current.Volumes, proposed.Volumes)...)
+	plan.Changes = append(plan.Changes, diffTopLevel(KindNetwork, current.Networks, proposed.Networks)...)
+	return plan
+}
+
+func diffTopLevel(kind string, old, neu map[string]interface{}) []Change {
+	var changes []Change
+	for _, name := range unionKeys(old, neu) {
+		o, inOld := old[name]
+		n, inNew := neu[name]
+		switch {
+		case !inOld:
+			changes = append(changes, Change{Kind: kind, Name: name, Action: ActionAdd})
+		case !inNew:
+			changes = append(changes, Change{Kind: kind, Name: name, Action: ActionRemove})
+		case render(o) != render(n):
+			changes = append(changes, Change{Kind: kind, Name: name, Action: ActionChange,
+				Fields: []FieldChange{{Field: "options", Old: render(o), New: render(n)}}})
+		}
+	}
+	return changes
+}
+
+// diffService lists the settings that differ between two services.
+// Environment variables and labels are compared key by key.
+func diffService(old, neu *ComposeService) []FieldChange {
+	var fields []FieldChange
+	add := func(field, o, n string) {
+		if o != n {
+			fields = append(fields, FieldChange{Field: field, Old: o, New: n})
+		}
+	}
+
+	add("image", old.Image, neu.Image)
+	add("container_name", old.ContainerName, neu.ContainerName)
+	add("restart", old.Restart, neu.Restart)
+	add("ports", strings.Join(old.Ports, ", "), strings.Join(neu.Ports, ", "))
+	add("volumes", strings.Join(old.Volumes, ", "), strings.Join(neu.Volumes, ", "))
+	add("env_file", strings.Join(old.EnvFile, ", "), strings.Join(neu.EnvFile, ", "))
+	add("depends_on", strings.Join(old.DependsOn, ", "), strings.Join(neu.DependsOn, ", "))
+	add("networks", strings.Join(old.Networks, ", "), strings.Join(neu.Networks, ", "))
+
+	oldEnv, newEnv := envMap(old.Environment), envMap(neu.Environment)
+	for _, k := range unionKeys(oldEnv, newEnv) {
+		add("environment."+k, oldEnv[k], newEnv[k])
+	}
+	for _, k := range unionKeys(old.Labels, neu.Labels) {
+		add("labels."+k, old.Labels[k], neu.Labels[k])
+	}
+
+	if !reflect.DeepEqual(old.HealthCheck, neu.HealthCheck) {
+		add("healthcheck", render(old.HealthCheck), render(neu.HealthCheck))
+	}
+	if !reflect.DeepEqual(old.Deploy, neu.Deploy) {
+		add("deploy", render(old.Deploy), render(neu.Deploy))
+	}
+	return fields
+}
+
+// envMap turns KEY=value entries into a map
+func envMap(env []string) map[string]string {
+	m := make(map[string]string, len(env))
+	for _, kv := range env {
+		k, v, _ := strings.Cut(kv, "=")
+		m[k] = v
+	}
+	return m
+}
+
+// render gives a compact one-line form of a nested compose value
+func render(v interface{}) string {
+	if v == nil || reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil() {
+		return ""
+	}
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return strings.Join(strings.Fields(strings.TrimSpace(string(data))), " ")
+}
+
+// unionKeys returns the keys of both maps, sorted
+func unionKeys[V any](a, b map[string]V) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	var keys []string
+	for _, m := range []map[string]V{a, b} {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// PlanOrphans lists managed containers that no service in the given compose
+// files defines. `docker compose up --remove-orphans` deletes these.
+func PlanOrphans(ctx context.Context, engine Engine, path string, composes ...*ComposeFile) (*Plan, error) {
+	containers, err := engine.ListContainers(ctx, ListOptions{
+		All:     true,
+		Filters: map[string][]string{"label": {"sovereign.managed=true"}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	defined := make(map[string]bool)
+	for _, compose := range composes {
+		for name, svc := range compose.Services {
+			defined[name] = true
+			defined[svc.ContainerName] = true
+		}
+	}
+
+	plan := &Plan{Path: path, Changes: []Change{}}
+	for _, c := range containers {
+		if !defined[c.Name()] && !defined[c.Labels["com.docker.compose.service"]] {
+			plan.Changes = append(plan.Changes, Change{Kind: KindContainer, Name: c.Name(), Action: ActionRemove})
+		}
+	}
+	sort.Slice(plan.Changes, func(i, j int) bool { return plan.Changes[i].Name < plan.Changes[j].Name })
+	return plan, nil
+}
@@ -0,0 +1,118 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	current := &ComposeFile{
+		Services: map[string]*ComposeService{
+			"caddy":    {Image: "caddy:2", Ports: []string{"80:80"}, Environment: []string{"TZ=UTC", "DEBUG=1"}},
+			"postgres": {Image: "postgres:16-alpine"},
+			"redis":    {Image: "redis:7-alpine"},
+		},
+		Volumes:  map[string]interface{}{"redis_data": nil},
+		Networks: map[string]interface{}{"sovereign": map[string]interface{}{"driver": "bridge"}},
+	}
+	proposed, err := current.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	proposed.Services["caddy"].Image = "caddy:2-alpine"
+	proposed.Services["caddy"].Environment = []string{"TZ=Europe/Berlin", "ACME=on"}
+	delete(proposed.Services, "redis")
+	delete(proposed.Volumes, "redis_data")
+	proposed.Services["nextcloud"] = &ComposeService{Image: "nextcloud:28", Ports: []string{"8080:80"}}
+	proposed.Volumes["nextcloud_data"] = nil
+
+	if current.Services["caddy"].Image != "caddy:2" {
+		t.Fatal("Clone shared state with the original")
+	}
+
+	plan := Diff("compose.yml", current, proposed)
+	var got []string
+	for _, c := range plan.Changes {
+		got = append(got, c.Action+" "+c.Kind+" "+c.Name)
+	}
+	want := "change service caddy|add service nextcloud|remove service redis|add volume nextcloud_data|remove volume redis_data"
+	if strings.Join(got, "|") != want {
+		t.Errorf("changes = %s", strings.Join(got, "|"))
+	}
+
+	var fields []string
+	for _, f := range plan.Changes[0].Fields {
+		fields = append(fields, fmt.Sprintf("%s:%s>%s", f.Field, f.Old, f.New))
+	}
+	wantFields := "image:caddy:2>caddy:2-alpine|environment.ACME:>on|environment.DEBUG:1>|environment.TZ:UTC>Europe/Berlin"
+	if strings.Join(fields, "|") != wantFields {
+		t.Errorf("caddy fields = %s", strings.Join(fields, "|"))
+	}
+	if plan.Summary() != "2 to add, 1 to change, 2 to remove" {
+		t.Errorf("summary = %s", plan.Summary())
+	}
+
+	if !Diff("compose.yml", current, current).Empty() {
+		t.Error("identical files should have an empty plan")
+	}
+}
+
+func TestPlanOrphans(t *testing.T) {
+	engine := fakeEngine(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[
+			{"Id": "1", "Names": ["/sovereign-postgres"], "Labels": {"sovereign.managed": "true"}},
+			{"Id": "2", "Names": ["/sovereign-gitea"], "Labels": {"sovereign.managed": "true", "sovereign.app": "gitea"}},
+			{"Id": "3", "Names": ["/sovereign-jellyfin"], "Labels": {"sovereign.managed": "true", "sovereign.app": "jellyfin"}}
+		]`)
+	}))
+	core := &ComposeFile{Services: map[string]*ComposeService{"postgres": {ContainerName: "sovereign-postgres"}}}
+	app := &ComposeFile{Services: map[string]*ComposeService{"jellyfin": {ContainerName: "sovereign-jellyfin"}}}
+
+	plan, err := PlanOrphans(context.Background(), engine, "compose.yml", core, app)
+	if err != nil {
+		t.Fatalf("PlanOrphans failed: %v", err)
+	}
+	if len(plan.Changes) != 1 || plan.Changes[0].Name != "sovereign-gitea" || plan.Changes[0].Action != ActionRemove {
+		t.Errorf("unexpected plan: %+v", plan.Changes)
+	}
+}
+
+func TestParseMemoryMB(t *testing.T) {
+	for in, want := range map[string]int{"512M": 512, "2g": 2048, "1GB": 1024, "1073741824": 1024, "1.5G": 1536, "2048k": 2} {
+		if got, err := ParseMemoryMB(in); err != nil || got != want {
+			t.Errorf("ParseMemoryMB(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	if _, err := ParseMemoryMB("lots"); err == nil {
+		t.Error("expected an error for an invalid size")
+	}
+	if !ValidRestart("on-failure:3") || ValidRestart("sometimes") || ValidRestart("always:2") {
+		t.Error("ValidRestart misclassified a policy")
+	}
+}
+
+func TestParsePort(t *testing.T) {
+	for spec, want := range map[string]PortSpec{
+		"8080:80":             {HostPort: 8080, ContainerPort: 80, Protocol: "tcp"},
+		"127.0.0.1:5432:5432": {HostIP: "127.0.0.1", HostPort: 5432, ContainerPort: 5432, Protocol: "tcp"},
+		"51820:51820/udp":     {HostPort: 51820, ContainerPort: 51820, Protocol: "udp"},
+		"3000":                {ContainerPort: 3000, Protocol: "tcp"},
+		"127.0.0.1::80":       {HostIP: "127.0.0.1", ContainerPort: 80, Protocol: "tcp"},
+	} {
+		got, err := ParsePort(spec)
+		if err != nil || got != want {
+			t.Errorf("ParsePort(%q) = %+v, %v", spec, got, err)
+		}
+		if got.String() != spec {
+			t.Errorf("String() = %q, want %q", got.String(), spec)
+		}
+	}
+	for _, bad := range []string{"8080:http", "8000-8010:80", "1:2:3:4", "80/sctp", "70000:80"} {
+		if _, err := ParsePort(bad); err == nil {
+			t.Errorf("ParsePort(%q) should fail", bad)
+		}
+	}
+}
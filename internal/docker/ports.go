@@ -0,0 +1,83 @@
+package docker
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PortSpec is a parsed compose port mapping such as "127.0.0.1:8080:80/udp"
+type PortSpec struct {
+	HostIP        string
+	HostPort      int // 0 if the host port is left to Docker
+	ContainerPort int
+	Protocol      string // tcp or udp
+}
+
+// ParsePort parses a compose port mapping in short syntax. Port ranges are
+// not supported.
+func ParsePort(spec string) (PortSpec, error) {
+	p := PortSpec{Protocol: "tcp"}
+	rest := spec
+	if i := strings.LastIndex(rest, "/"); i >= 0 {
+		p.Protocol = rest[i+1:]
+		rest = rest[:i]
+	}
+	if p.Protocol != "tcp" && p.Protocol != "udp" {
+		return p, fmt.Errorf("invalid port %q: unknown protocol", spec)
+	}
+
+	parts := strings.Split(rest, ":")
+	number := func(s string) (int, error) {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > 65535 {
+			return 0, fmt.Errorf("invalid port %q", spec)
+		}
+		return n, nil
+	}
+
+	var err error
+	switch len(parts) {
+	case 1:
+		p.ContainerPort, err = number(parts[0])
+	case 2:
+		if p.HostPort, err = number(parts[0]); err == nil {
+			p.ContainerPort, err = number(parts[1])
+		}
+	case 3:
+		p.HostIP = parts[0]
+		if parts[1] != "" {
+			p.HostPort, err = number(parts[1])
+		}
+		if err == nil {
+			p.ContainerPort, err = number(parts[2])
+		}
+	default:
+		err = fmt.Errorf("invalid port %q", spec)
+	}
+	return p, err
+}
+
+// String formats the mapping in compose short syntax
+func (p PortSpec) String() string {
+	var s string
+	switch {
+	case p.HostIP != "":
+		s = fmt.Sprintf("%s:%s:%d", p.HostIP, portOrEmpty(p.HostPort), p.ContainerPort)
+	case p.HostPort != 0:
+		s = fmt.Sprintf("%d:%d", p.HostPort, p.ContainerPort)
+	default:
+		s = strconv.Itoa(p.ContainerPort)
+	}
+	if p.Protocol != "" && p.Protocol != "tcp" {
+		s += "/" + p.Protocol
+	}
+	return s
+}
+
+func portOrEmpty(port int) string {
+	if port == 0 {
+		return ""
+	}
+	return strconv.Itoa(port)
+}
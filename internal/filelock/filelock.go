@@ -0,0 +1,41 @@
+// Package filelock provides advisory cross-process file locks so the CLI,
+// cron jobs and the dashboard server can safely share files in ~/.sovereign.
+package filelock
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Lock is a held exclusive lock on a lock file
+type Lock struct {
+	f *os.File
+}
+
+// Acquire blocks until it holds an exclusive lock on path, creating the
+// lock file if needed
+func Acquire(path string) (*Lock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &Lock{f: f}, nil
+}
+
+// Release drops the lock
+func (l *Lock) Release() error {
+	if l == nil || l.f == nil {
+		return nil
+	}
+	unlockFile(l.f)
+	err := l.f.Close()
+	l.f = nil
+	return err
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package filelock
+
+import "os"
+
+// Sovereign Stack targets Linux and macOS; elsewhere locks are advisory no-ops
+func lockFile(f *os.File) error   { return nil }
+func unlockFile(f *os.File) error { return nil }
@@ -1,16 +1,37 @@
 package hardware
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"os/exec"
 	"runtime"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Achilles1089/sovereign-stack/internal/config"
+	"github.com/Achilles1089/sovereign-stack/internal/runner"
 )
 
+// run runs the probing tools (df, sysctl, nvidia-smi, ...); SetRunner replaces it in tests
+var run = runner.Default
+
+// SetRunner replaces the runner used for probing and returns the previous one
+func SetRunner(r runner.Runner) runner.Runner {
+	prev := run
+	run = r
+	return prev
+}
+
+// probe runs a tool and returns its stdout
+func probe(name string, args ...string) ([]byte, error) {
+	res, err := run.Run(context.Background(), runner.Command{Name: name, Args: args, Timeout: 10 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	return res.Stdout, nil
+}
+
 // Detect scans the host hardware and returns a HardwareProfile
 func Detect() (*config.HardwareProfile, error) {
 	profile := &config.HardwareProfile{
@@ -80,7 +101,7 @@ func detectLinuxRAM(p *config.HardwareProfile) {
 }
 
 func detectLinuxDisk(p *config.HardwareProfile) {
-	out, err := exec.Command("df", "-BG", "--output=size,avail", "/").Output()
+	out, err := probe("df", "-BG", "--output=size,avail", "/")
 	if err != nil {
 		return
 	}
@@ -99,13 +120,13 @@ func detectLinuxDisk(p *config.HardwareProfile) {
 
 func detectMacOSCPU(p *config.HardwareProfile) {
 	// CPU brand string
-	out, err := exec.Command("sysctl", "-n", "machdep.cpu.brand_string").Output()
+	out, err := probe("sysctl", "-n", "machdep.cpu.brand_string")
 	if err == nil {
 		p.CPUModel = strings.TrimSpace(string(out))
 	}
 
 	// Core count
-	out, err = exec.Command("sysctl", "-n", "hw.ncpu").Output()
+	out, err = probe("sysctl", "-n", "hw.ncpu")
 	if err == nil {
 		cores, err := strconv.Atoi(strings.TrimSpace(string(out)))
 		if err == nil {
@@ -115,7 +136,7 @@ func detectMacOSCPU(p *config.HardwareProfile) {
 }
 
 func detectMacOSRAM(p *config.HardwareProfile) {
-	out, err := exec.Command("sysctl", "-n", "hw.memsize").Output()
+	out, err := probe("sysctl", "-n", "hw.memsize")
 	if err != nil {
 		return
 	}
@@ -127,7 +148,7 @@ func detectMacOSRAM(p *config.HardwareProfile) {
 }
 
 func detectMacOSDisk(p *config.HardwareProfile) {
-	out, err := exec.Command("df", "-g", "/").Output()
+	out, err := probe("df", "-g", "/")
 	if err != nil {
 		return
 	}
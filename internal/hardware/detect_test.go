@@ -127,3 +127,16 @@ func TestRecommendedModel(t *testing.T) {
 		t.Error("128GB Apple Silicon should not recommend the smallest model")
 	}
 }
+
+func TestParseVMStat(t *testing.T) {
+	out := `Mach Virtual Memory Statistics: (page size of 16384 bytes)
+Pages free:                               65536.
+Pages active:                            400000.
+Pages inactive:                           32768.
+Pages speculative:                        32768.
+`
+	mb, err := parseVMStat(out)
+	if err != nil || mb != 2048 {
+		t.Errorf("parseVMStat = %d, %v; want 2048", mb, err)
+	}
+}
@@ -0,0 +1,98 @@
+package hardware
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// AvailableRAMMB returns how much memory can be used without swapping, in MB
+func AvailableRAMMB() (int, error) {
+	switch runtime.GOOS {
+	case "linux":
+		data, err := os.ReadFile("/proc/meminfo")
+		if err != nil {
+			return 0, err
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if fields := strings.Fields(line); len(fields) >= 2 && fields[0] == "MemAvailable:" {
+				kb, err := strconv.ParseInt(fields[1], 10, 64)
+				if err != nil {
+					return 0, err
+				}
+				return int(kb / 1024), nil
+			}
+		}
+		return 0, fmt.Errorf("MemAvailable not found in /proc/meminfo")
+	case "darwin":
+		out, err := probe("vm_stat")
+		if err != nil {
+			return 0, err
+		}
+		return parseVMStat(string(out))
+	}
+	return 0, fmt.Errorf("free memory detection not supported on %s", runtime.GOOS)
+}
+
+var pageSizeRe = regexp.MustCompile(`page size of (\d+) bytes`)
+
+// parseVMStat adds up free, inactive and speculative pages from vm_stat
+func parseVMStat(out string) (int, error) {
+	m := pageSizeRe.FindStringSubmatch(out)
+	if m == nil {
+		return 0, fmt.Errorf("unexpected vm_stat output")
+	}
+	pageSize, _ := strconv.ParseInt(m[1], 10, 64)
+
+	var pages int64
+	for _, line := range strings.Split(out, "\n") {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		switch name {
+		case "Pages free", "Pages inactive", "Pages speculative":
+			n, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimSpace(value), "."), 10, 64)
+			if err == nil {
+				pages += n
+			}
+		}
+	}
+	return int(pages * pageSize / (1024 * 1024)), nil
+}
+
+// FreeDiskGB returns the free space on the filesystem holding path, in GB
+func FreeDiskGB(path string) (int, error) {
+	var out []byte
+	var err error
+	switch runtime.GOOS {
+	case "linux":
+		out, err = probe("df", "-BG", "--output=avail", path)
+	case "darwin":
+		out, err = probe("df", "-g", path)
+	default:
+		return 0, fmt.Errorf("free disk detection not supported on %s", runtime.GOOS)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) < 2 {
+		return 0, fmt.Errorf("unexpected df output")
+	}
+	fields := strings.Fields(lines[1])
+	if runtime.GOOS == "darwin" {
+		if len(fields) < 4 {
+			return 0, fmt.Errorf("unexpected df output")
+		}
+		return strconv.Atoi(fields[3])
+	}
+	if len(fields) < 1 {
+		return 0, fmt.Errorf("unexpected df output")
+	}
+	return parseGBValue(fields[0]), nil
+}
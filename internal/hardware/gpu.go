@@ -1,7 +1,6 @@
 package hardware
 
 import (
-	"os/exec"
 	"runtime"
 	"strconv"
 	"strings"
@@ -46,10 +45,10 @@ func detectGPU(p *config.HardwareProfile) {
 
 // detectNVIDIA checks for NVIDIA GPUs via nvidia-smi
 func detectNVIDIA(p *config.HardwareProfile) bool {
-	out, err := exec.Command("nvidia-smi",
+	out, err := probe("nvidia-smi",
 		"--query-gpu=name,memory.total",
 		"--format=csv,noheader,nounits",
-	).Output()
+	)
 	if err != nil {
 		return false
 	}
@@ -78,7 +77,7 @@ func detectNVIDIA(p *config.HardwareProfile) bool {
 
 // detectAMD checks for AMD GPUs via rocm-smi
 func detectAMD(p *config.HardwareProfile) bool {
-	out, err := exec.Command("rocm-smi", "--showproductname").Output()
+	out, err := probe("rocm-smi", "--showproductname")
 	if err != nil {
 		return false
 	}
@@ -101,7 +100,7 @@ func detectAMD(p *config.HardwareProfile) bool {
 	}
 
 	// Try to get memory
-	memOut, err := exec.Command("rocm-smi", "--showmeminfo", "vram").Output()
+	memOut, err := probe("rocm-smi", "--showmeminfo", "vram")
 	if err == nil {
 		for _, line := range strings.Split(string(memOut), "\n") {
 			if strings.Contains(strings.ToLower(line), "total") {
@@ -122,7 +121,7 @@ func detectAMD(p *config.HardwareProfile) bool {
 // detectIntelARC checks for Intel ARC GPUs
 func detectIntelARC(p *config.HardwareProfile) bool {
 	// Check for Intel GPU via lspci
-	out, err := exec.Command("lspci").Output()
+	out, err := probe("lspci")
 	if err != nil {
 		return false
 	}
@@ -148,7 +147,7 @@ func detectIntelARC(p *config.HardwareProfile) bool {
 
 // detectAppleSilicon detects Apple Silicon GPU via unified memory
 func detectAppleSilicon(p *config.HardwareProfile) {
-	out, err := exec.Command("sysctl", "-n", "machdep.cpu.brand_string").Output()
+	out, err := probe("sysctl", "-n", "machdep.cpu.brand_string")
 	if err != nil {
 		return
 	}
@@ -167,7 +166,7 @@ func detectAppleSilicon(p *config.HardwareProfile) {
 
 	// Apple Silicon uses unified memory — GPU memory = total RAM
 	// Model routing will use this to determine which models fit
-	memOut, err := exec.Command("sysctl", "-n", "hw.memsize").Output()
+	memOut, err := probe("sysctl", "-n", "hw.memsize")
 	if err == nil {
 		bytes, err := strconv.ParseInt(strings.TrimSpace(string(memOut)), 10, 64)
 		if err == nil {
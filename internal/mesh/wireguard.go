@@ -1,19 +1,45 @@
 package mesh
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/Achilles1089/sovereign-stack/internal/config"
+	"github.com/Achilles1089/sovereign-stack/internal/runner"
 )
 
+// run runs wg and wg-quick; SetRunner replaces it in tests
+var run = runner.Default
+
+// SetRunner replaces the runner used for WireGuard commands and returns the
+// previous one
+func SetRunner(r runner.Runner) runner.Runner {
+	prev := run
+	run = r
+	return prev
+}
+
+// wg runs a WireGuard tool and returns its output
+func wg(stdin string, name string, args ...string) (string, error) {
+	cmd := runner.Command{Name: name, Args: args, Timeout: 30 * time.Second}
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	res, err := run.Run(context.Background(), cmd)
+	if err != nil {
+		return "", err
+	}
+	return string(res.Stdout), nil
+}
+
 // MeshConfig holds the mesh network configuration
 type MeshConfig struct {
 	NetworkName string     `json:"network_name"`
@@ -41,7 +67,7 @@ type JoinToken struct {
 
 // IsWireGuardInstalled checks if WireGuard tools are available
 func IsWireGuardInstalled() bool {
-	_, err := exec.LookPath("wg")
+	_, err := run.LookPath("wg")
 	return err == nil
 }
 
@@ -178,21 +204,23 @@ func JoinNetwork(tokenStr string) (*MeshConfig, error) {
 
 // InterfaceUp brings the WireGuard interface up
 func InterfaceUp() error {
-	return exec.Command("wg-quick", "up", "sovereign0").Run()
+	_, err := wg("", "wg-quick", "up", "sovereign0")
+	return err
 }
 
 // InterfaceDown brings the WireGuard interface down
 func InterfaceDown() error {
-	return exec.Command("wg-quick", "down", "sovereign0").Run()
+	_, err := wg("", "wg-quick", "down", "sovereign0")
+	return err
 }
 
 // Status returns mesh status info
 func Status() (string, error) {
-	out, err := exec.Command("wg", "show", "sovereign0").Output()
+	out, err := wg("", "wg", "show", "sovereign0")
 	if err != nil {
 		return "", fmt.Errorf("mesh interface not active")
 	}
-	return string(out), nil
+	return out, nil
 }
 
 // --- Internal helpers ---
@@ -200,20 +228,18 @@ func Status() (string, error) {
 func generateKeyPair() (string, string, error) {
 	// Check if wg is available for key generation
 	if IsWireGuardInstalled() {
-		privOut, err := exec.Command("wg", "genkey").Output()
+		privOut, err := wg("", "wg", "genkey")
 		if err != nil {
 			return generateFallbackKeys()
 		}
-		privKey := strings.TrimSpace(string(privOut))
+		privKey := strings.TrimSpace(privOut)
 
 		// Derive public key
-		cmd := exec.Command("wg", "pubkey")
-		cmd.Stdin = strings.NewReader(privKey)
-		pubOut, err := cmd.Output()
+		pubOut, err := wg(privKey, "wg", "pubkey")
 		if err != nil {
 			return generateFallbackKeys()
 		}
-		pubKey := strings.TrimSpace(string(pubOut))
+		pubKey := strings.TrimSpace(pubOut)
 		return privKey, pubKey, nil
 	}
 
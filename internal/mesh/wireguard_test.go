@@ -0,0 +1,38 @@
+package mesh
+
+import (
+	"testing"
+
+	"github.com/Achilles1089/sovereign-stack/internal/runner"
+)
+
+func TestGenerateKeyPair(t *testing.T) {
+	fake := runner.NewFake().
+		On("wg genkey", runner.Response{Stdout: "cHJpdmF0ZQ==\n"}).
+		On("wg pubkey", runner.Response{Stdout: "cHVibGlj\n"})
+	defer SetRunner(SetRunner(fake))
+
+	priv, pub, err := generateKeyPair()
+	if err != nil {
+		t.Fatalf("generateKeyPair failed: %v", err)
+	}
+	if priv != "cHJpdmF0ZQ==" || pub != "cHVibGlj" {
+		t.Errorf("keys = %q, %q", priv, pub)
+	}
+	if calls := fake.Calls(); len(calls) != 2 || calls[1].Input != "cHJpdmF0ZQ==" {
+		t.Errorf("private key should be piped to wg pubkey: %+v", calls)
+	}
+}
+
+func TestGenerateKeyPairWithoutWireGuard(t *testing.T) {
+	fake := runner.NewFake().Missing("wg")
+	defer SetRunner(SetRunner(fake))
+
+	priv, pub, err := generateKeyPair()
+	if err != nil || priv == "" || pub == "" {
+		t.Fatalf("expected fallback keys, got %q %q %v", priv, pub, err)
+	}
+	if len(fake.Calls()) != 0 {
+		t.Errorf("wg should not run when missing: %v", fake.Lines())
+	}
+}
@@ -1,12 +1,34 @@
 package platform
 
 import (
+	"context"
 	"os"
-	"os/exec"
 	"runtime"
 	"strings"
+	"time"
+
+	"github.com/Achilles1089/sovereign-stack/internal/runner"
 )
 
+// run runs the probing tools (lsb_release); SetRunner replaces it in tests
+var run = runner.Default
+
+// SetRunner replaces the runner used for probing and returns the previous one
+func SetRunner(r runner.Runner) runner.Runner {
+	prev := run
+	run = r
+	return prev
+}
+
+// probe runs a tool and returns its stdout
+func probe(name string, args ...string) ([]byte, error) {
+	res, err := run.Run(context.Background(), runner.Command{Name: name, Args: args, Timeout: 10 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	return res.Stdout, nil
+}
+
 // Platform represents the detected operating system platform
 type Platform string
 
@@ -95,7 +117,7 @@ func detectLinuxDistro() string {
 	}
 
 	// Fallback to lsb_release
-	out, err := exec.Command("lsb_release", "-is").Output()
+	out, err := probe("lsb_release", "-is")
 	if err == nil {
 		return strings.TrimSpace(strings.ToLower(string(out)))
 	}
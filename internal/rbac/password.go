@@ -0,0 +1,178 @@
+package rbac
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Login lockout policy
+const (
+	MaxFailedLogins   = 5
+	LockoutDuration   = 15 * time.Minute
+	MinPasswordLength = 8
+)
+
+// argon2id parameters — sized to stay fast on the mini PC and phone nodes
+const (
+	argonTime    = 3
+	argonMemory  = 64 * 1024
+	argonThreads = 2
+	argonKeyLen  = 32
+	argonSaltLen = 16
+)
+
+var (
+	// ErrInvalidCredentials is returned for an unknown user or wrong password
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	// ErrAccountLocked is returned while a user is locked out after failed logins
+	ErrAccountLocked = errors.New("account locked after too many failed logins")
+)
+
+// dummyHash is verified against for unknown users so lookups take constant time
+var (
+	dummyHash     string
+	dummyHashOnce sync.Once
+)
+
+// HashPassword hashes a password with argon2id in PHC string format
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, argonSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, argonTime, argonMemory, argonThreads, argonKeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, argonMemory, argonTime, argonThreads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// VerifyPassword checks a password against an argon2id PHC hash
+func VerifyPassword(encoded, password string) bool {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return false
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false
+	}
+
+	var memory, iterations uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &threads); err != nil {
+		return false
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false
+	}
+
+	got := argon2.IDKey([]byte(password), salt, iterations, memory, threads, uint32(len(want)))
+	return subtle.ConstantTimeCompare(got, want) == 1
+}
+
+// SetPassword sets a user's password and clears any lockout
+func SetPassword(username, password string) error {
+	if len(password) < MinPasswordLength {
+		return fmt.Errorf("password must be at least %d characters", MinPasswordLength)
+	}
+
+	hash, err := HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	return UpdateConfig(func(cfg *RBACConfig) error {
+		user := cfg.findUser(username)
+		if user == nil {
+			return fmt.Errorf("user %q not found", username)
+		}
+
+		now := time.Now().UTC()
+		user.PasswordHash = hash
+		user.PasswordChangedAt = &now
+		user.FailedLogins = 0
+		user.LockedUntil = nil
+		return nil
+	})
+}
+
+// Authenticate verifies a username and password, enforcing the lockout policy
+func Authenticate(username, password string) (*User, error) {
+	cfg, err := LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	user := cfg.findUser(username)
+	if user == nil || user.PasswordHash == "" || !user.Active {
+		dummyHashOnce.Do(func() { dummyHash, _ = HashPassword("sovereign-dummy-password") })
+		VerifyPassword(dummyHash, password)
+		return nil, ErrInvalidCredentials
+	}
+
+	if user.IsLocked() {
+		return nil, ErrAccountLocked
+	}
+
+	// Hashing is slow, so it runs unlocked; the count is updated against
+	// the stored config so concurrent failures all add up
+	if !VerifyPassword(user.PasswordHash, password) {
+		err := UpdateConfig(func(cfg *RBACConfig) error {
+			u := cfg.findUser(username)
+			if u == nil {
+				return nil
+			}
+			u.FailedLogins++
+			if u.FailedLogins >= MaxFailedLogins {
+				until := time.Now().UTC().Add(LockoutDuration)
+				u.LockedUntil = &until
+				u.FailedLogins = 0
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	if user.FailedLogins != 0 || user.LockedUntil != nil {
+		err := UpdateConfig(func(cfg *RBACConfig) error {
+			if u := cfg.findUser(username); u != nil {
+				u.FailedLogins = 0
+				u.LockedUntil = nil
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		user.FailedLogins = 0
+		user.LockedUntil = nil
+	}
+
+	return user, nil
+}
+
+// IsLocked reports whether a user is currently locked out
+func (u *User) IsLocked() bool {
+	return u.LockedUntil != nil && time.Now().Before(*u.LockedUntil)
+}
@@ -0,0 +1,175 @@
+package rbac
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func setupHome(t *testing.T) {
+	t.Helper()
+	tmpDir := t.TempDir()
+	os.Setenv("HOME", tmpDir)
+	os.MkdirAll(filepath.Join(tmpDir, ".sovereign"), 0755)
+	t.Cleanup(func() { os.Unsetenv("HOME") })
+}
+
+func TestHashPassword(t *testing.T) {
+	hash, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatalf("HashPassword failed: %v", err)
+	}
+	if !VerifyPassword(hash, "correct horse") {
+		t.Error("expected password to verify")
+	}
+	if VerifyPassword(hash, "wrong horse") {
+		t.Error("wrong password should not verify")
+	}
+	if VerifyPassword("not-a-hash", "correct horse") {
+		t.Error("malformed hash should not verify")
+	}
+
+	other, _ := HashPassword("correct horse")
+	if other == hash {
+		t.Error("hashes should be salted")
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	setupHome(t)
+
+	if err := AddUser("alice", RoleOperator, ""); err != nil {
+		t.Fatalf("AddUser failed: %v", err)
+	}
+	if err := SetPassword("alice", "short"); err == nil {
+		t.Error("expected error for short password")
+	}
+	if err := SetPassword("alice", "s3cret-pass"); err != nil {
+		t.Fatalf("SetPassword failed: %v", err)
+	}
+
+	user, err := Authenticate("alice", "s3cret-pass")
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if user.Role != RoleOperator {
+		t.Errorf("expected operator role, got %s", user.Role)
+	}
+
+	if _, err := Authenticate("alice", "wrong"); err != ErrInvalidCredentials {
+		t.Errorf("expected ErrInvalidCredentials, got %v", err)
+	}
+	if _, err := Authenticate("nobody", "s3cret-pass"); err != ErrInvalidCredentials {
+		t.Errorf("expected ErrInvalidCredentials for unknown user, got %v", err)
+	}
+}
+
+func TestAuthenticateLockout(t *testing.T) {
+	setupHome(t)
+
+	AddUser("bob", RoleViewer, "")
+	SetPassword("bob", "s3cret-pass")
+
+	for i := 0; i < MaxFailedLogins; i++ {
+		Authenticate("bob", "wrong")
+	}
+
+	// Even the right password is refused while locked
+	if _, err := Authenticate("bob", "s3cret-pass"); err != ErrAccountLocked {
+		t.Fatalf("expected ErrAccountLocked, got %v", err)
+	}
+
+	// Resetting the password clears the lockout
+	SetPassword("bob", "n3w-s3cret-pass")
+	if _, err := Authenticate("bob", "n3w-s3cret-pass"); err != nil {
+		t.Errorf("expected login after reset, got %v", err)
+	}
+}
+
+func TestAuthenticateConcurrentFailures(t *testing.T) {
+	setupHome(t)
+
+	AddUser("carol", RoleViewer, "")
+	SetPassword("carol", "s3cret-pass")
+
+	// Failures sent in parallel must all count
+	var wg sync.WaitGroup
+	for i := 0; i < MaxFailedLogins-1; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			Authenticate("carol", "wrong")
+		}()
+	}
+	wg.Wait()
+
+	user, err := GetUser("carol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.FailedLogins != MaxFailedLogins-1 {
+		t.Fatalf("expected %d failed logins, got %d", MaxFailedLogins-1, user.FailedLogins)
+	}
+	Authenticate("carol", "wrong")
+	if _, err := Authenticate("carol", "s3cret-pass"); err != ErrAccountLocked {
+		t.Errorf("expected ErrAccountLocked, got %v", err)
+	}
+}
+
+func TestSessionLifecycle(t *testing.T) {
+	setupHome(t)
+
+	AddUser("carol", RoleViewer, "")
+	SetPassword("carol", "s3cret-pass")
+	user, _ := GetUser("carol")
+
+	token, _, err := IssueSession("carol", SessionTTL)
+	if err != nil {
+		t.Fatalf("IssueSession failed: %v", err)
+	}
+
+	sess, err := ParseSession(token)
+	if err != nil {
+		t.Fatalf("ParseSession failed: %v", err)
+	}
+	if !sess.ValidFor(user) {
+		t.Error("fresh session should be valid")
+	}
+
+	// Expired sessions are rejected
+	expired, _, _ := IssueSession("carol", -time.Minute)
+	if _, err := ParseSession(expired); err != ErrInvalidSession {
+		t.Errorf("expected ErrInvalidSession for expired token, got %v", err)
+	}
+
+	// Sessions issued before a password change are rejected
+	later := sess.IssuedAt.Add(time.Second)
+	user.PasswordChangedAt = &later
+	if sess.ValidFor(user) {
+		t.Error("session should be invalid after password change")
+	}
+
+	// Revoked sessions are rejected
+	if err := RevokeSession(sess); err != nil {
+		t.Fatalf("RevokeSession failed: %v", err)
+	}
+	if _, err := ParseSession(token); err != ErrInvalidSession {
+		t.Errorf("expected ErrInvalidSession after revoke, got %v", err)
+	}
+}
+
+func TestSetEnabledRequiresAdminPassword(t *testing.T) {
+	setupHome(t)
+
+	if err := SetEnabled(true); err == nil {
+		t.Fatal("expected error enabling RBAC without an admin password")
+	}
+
+	SaveConfig(&RBACConfig{Users: []User{{Username: "admin", Role: RoleAdmin, Active: true}}})
+	SetPassword("admin", "s3cret-pass")
+	if err := SetEnabled(true); err != nil {
+		t.Fatalf("SetEnabled failed: %v", err)
+	}
+}
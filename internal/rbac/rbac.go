@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/Achilles1089/sovereign-stack/internal/config"
+	"github.com/Achilles1089/sovereign-stack/internal/filelock"
 )
 
 // Role defines a set of permissions
@@ -48,15 +50,25 @@ type User struct {
 	Role     Role   `json:"role"`
 	Email    string `json:"email,omitempty"`
 	Active   bool   `json:"active"`
+
+	// Grants add resource-scoped permissions on top of the role
+	Grants []Grant `json:"grants,omitempty"`
+
+	// Credentials — argon2id hash, never the plaintext password
+	PasswordHash      string     `json:"password_hash,omitempty"`
+	PasswordChangedAt *time.Time `json:"password_changed_at,omitempty"`
+	FailedLogins      int        `json:"failed_logins,omitempty"`
+	LockedUntil       *time.Time `json:"locked_until,omitempty"`
 }
 
 // RBACConfig holds all RBAC configuration
 type RBACConfig struct {
-	Enabled bool   `json:"enabled"`
-	Users   []User `json:"users"`
+	Enabled bool             `json:"enabled"`
+	Users   []User           `json:"users"`
+	Roles   []RoleDefinition `json:"roles,omitempty"` // custom roles on top of the built-ins
 }
 
-// rolePermissions maps roles to their permissions
+// rolePermissions maps the built-in roles to their permissions
 var rolePermissions = map[Role][]Permission{
 	RoleAdmin: {
 		PermAppInstall, PermAppRemove, PermAppList,
@@ -87,28 +99,55 @@ var rolePermissions = map[Role][]Permission{
 	},
 }
 
-// HasPermission checks if a role has a specific permission
-func HasPermission(role Role, perm Permission) bool {
-	perms, ok := rolePermissions[role]
+// HasPermission checks if a built-in or custom role has a permission on a
+// resource. Pass Global for actions that don't target an app or service.
+func HasPermission(role Role, perm Permission, res Resource) bool {
+	r, ok := lookupRole(role)
 	if !ok {
 		return false
 	}
-	for _, p := range perms {
+	for _, p := range r.Permissions {
 		if p == perm {
 			return true
 		}
 	}
-	return false
+	return grantsMatch(r.Grants, perm, res)
 }
 
-// GetPermissions returns all permissions for a role
+// HasAnyPermission checks if a role holds a permission on at least one resource
+func HasAnyPermission(role Role, perm Permission) bool {
+	r, ok := lookupRole(role)
+	if !ok {
+		return false
+	}
+	for _, p := range r.Permissions {
+		if p == perm {
+			return true
+		}
+	}
+	return grantsInclude(r.Grants, perm)
+}
+
+// GetPermissions returns the role-wide permissions for a role
 func GetPermissions(role Role) []Permission {
-	return rolePermissions[role]
+	r, _ := lookupRole(role)
+	return r.Permissions
+}
+
+// GetGrants returns the resource-scoped grants for a role
+func GetGrants(role Role) []Grant {
+	r, _ := lookupRole(role)
+	return r.Grants
 }
 
-// AvailableRoles returns all defined roles
+// AvailableRoles returns the built-in roles followed by any custom roles
 func AvailableRoles() []Role {
-	return []Role{RoleAdmin, RoleOperator, RoleViewer, RoleBackup}
+	return append([]Role{RoleAdmin, RoleOperator, RoleViewer, RoleBackup}, customRoleNames()...)
+}
+
+func validRole(role Role) bool {
+	_, ok := lookupRole(role)
+	return ok
 }
 
 // LoadConfig loads RBAC configuration
@@ -117,6 +156,7 @@ func LoadConfig() (*RBACConfig, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
+			setCustomRoles(nil)
 			// Default: RBAC disabled, single admin user
 			return &RBACConfig{
 				Enabled: false,
@@ -129,66 +169,113 @@ func LoadConfig() (*RBACConfig, error) {
 	}
 
 	var cfg RBACConfig
-	return &cfg, json.Unmarshal(data, &cfg)
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+
+	resolved, err := ResolveRoles(cfg.Roles)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rbac.json: %w", err)
+	}
+	setCustomRoles(resolved)
+	return &cfg, nil
 }
 
-// SaveConfig saves RBAC configuration
+// SaveConfig saves RBAC configuration. To change the stored config, use
+// UpdateConfig, so concurrent changes are not lost.
 func SaveConfig(cfg *RBACConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
 	path := filepath.Join(config.ConfigDir(), "rbac.json")
 	data, err := json.MarshalIndent(cfg, "", "  ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(path, data, 0600)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	// Readers do not take the lock; replace the file so they never see half of it
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	resolved, _ := ResolveRoles(cfg.Roles)
+	setCustomRoles(resolved)
+	return nil
 }
 
-// AddUser adds a user with a role
-func AddUser(username string, role Role, email string) error {
+// UpdateConfig loads the RBAC configuration, applies fn and saves the
+// result, holding a lock throughout so that changes from the CLI and the
+// dashboard, e.g. concurrent failed logins, are not lost. Nothing is saved
+// if fn fails.
+func UpdateConfig(fn func(cfg *RBACConfig) error) error {
+	lock, err := filelock.Acquire(filepath.Join(config.ConfigDir(), "rbac.lock"))
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
 	cfg, err := LoadConfig()
 	if err != nil {
 		return err
 	}
+	if err := fn(cfg); err != nil {
+		return err
+	}
+	return SaveConfig(cfg)
+}
 
-	// Check for duplicates
-	for _, u := range cfg.Users {
-		if u.Username == username {
-			return fmt.Errorf("user %q already exists", username)
+// AddUser adds a user with a role
+func AddUser(username string, role Role, email string) error {
+	return UpdateConfig(func(cfg *RBACConfig) error {
+		if !validRole(role) {
+			return fmt.Errorf("unknown role %q", role)
 		}
-	}
 
-	cfg.Users = append(cfg.Users, User{
-		Username: username,
-		Role:     role,
-		Email:    email,
-		Active:   true,
-	})
+		// Check for duplicates
+		for _, u := range cfg.Users {
+			if u.Username == username {
+				return fmt.Errorf("user %q already exists", username)
+			}
+		}
 
-	return SaveConfig(cfg)
+		cfg.Users = append(cfg.Users, User{
+			Username: username,
+			Role:     role,
+			Email:    email,
+			Active:   true,
+		})
+		return nil
+	})
 }
 
 // RemoveUser removes a user
 func RemoveUser(username string) error {
-	cfg, err := LoadConfig()
-	if err != nil {
-		return err
-	}
-
-	var updated []User
-	found := false
-	for _, u := range cfg.Users {
-		if u.Username == username {
-			found = true
-			continue
+	return UpdateConfig(func(cfg *RBACConfig) error {
+		var updated []User
+		found := false
+		for _, u := range cfg.Users {
+			if u.Username == username {
+				found = true
+				continue
+			}
+			updated = append(updated, u)
 		}
-		updated = append(updated, u)
-	}
 
-	if !found {
-		return fmt.Errorf("user %q not found", username)
-	}
+		if !found {
+			return fmt.Errorf("user %q not found", username)
+		}
 
-	cfg.Users = updated
-	return SaveConfig(cfg)
+		cfg.Users = updated
+		return nil
+	})
 }
 
 // GetUser finds a user by username
@@ -198,11 +285,40 @@ func GetUser(username string) (*User, error) {
 		return nil, err
 	}
 
-	for _, u := range cfg.Users {
-		if u.Username == username {
-			return &u, nil
-		}
+	if u := cfg.findUser(username); u != nil {
+		return u, nil
 	}
 
 	return nil, fmt.Errorf("user %q not found", username)
 }
+
+// SetEnabled turns RBAC enforcement on or off. Enabling requires at least
+// one active admin with a password so the dashboard cannot be locked out.
+func SetEnabled(enabled bool) error {
+	return UpdateConfig(func(cfg *RBACConfig) error {
+		if enabled {
+			hasAdmin := false
+			for _, u := range cfg.Users {
+				if u.Active && u.Role == RoleAdmin && u.PasswordHash != "" {
+					hasAdmin = true
+					break
+				}
+			}
+			if !hasAdmin {
+				return fmt.Errorf("an active admin with a password is required before enabling RBAC")
+			}
+		}
+
+		cfg.Enabled = enabled
+		return nil
+	})
+}
+
+func (c *RBACConfig) findUser(username string) *User {
+	for i := range c.Users {
+		if c.Users[i].Username == username {
+			return &c.Users[i]
+		}
+	}
+	return nil
+}
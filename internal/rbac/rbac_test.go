@@ -37,7 +37,7 @@ func TestHasPermission(t *testing.T) {
 	for _, tt := range tests {
 		name := string(tt.role) + "/" + string(tt.perm)
 		t.Run(name, func(t *testing.T) {
-			result := HasPermission(tt.role, tt.perm)
+			result := HasPermission(tt.role, tt.perm, Global)
 			if result != tt.expected {
 				t.Errorf("HasPermission(%s, %s) = %v, want %v",
 					tt.role, tt.perm, result, tt.expected)
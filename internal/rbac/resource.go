@@ -0,0 +1,139 @@
+package rbac
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Resource kinds that permissions can be scoped to
+const (
+	ResourceApp     = "app"
+	ResourceService = "service"
+)
+
+// Resource is the target of an action. The zero value means "no specific
+// target" and only matches unscoped (role-wide) permissions.
+type Resource struct {
+	Kind     string // "app" or "service"
+	Name     string // e.g. "jellyfin", "postgres"
+	Category string // app category, e.g. "media"
+}
+
+// Global is the zero Resource used for actions without a target
+var Global = Resource{}
+
+// AppResource identifies an app by name and marketplace category
+func AppResource(name, category string) Resource {
+	return Resource{Kind: ResourceApp, Name: name, Category: category}
+}
+
+// ServiceResource identifies a compose service. Category is set when the
+// service belongs to an app so category grants cover its logs and restarts.
+func ServiceResource(name, category string) Resource {
+	return Resource{Kind: ResourceService, Name: name, Category: category}
+}
+
+func (r Resource) String() string {
+	if r.Kind == "" {
+		return "*"
+	}
+	return r.Kind + ":" + r.Name
+}
+
+// Grant gives a permission on a set of resource patterns:
+// "*", "app:*", "app:jellyfin", "service:postgres" or "category:media"
+type Grant struct {
+	Permission Permission `json:"permission"`
+	Resources  []string   `json:"resources"`
+}
+
+// Matches reports whether the grant covers perm on res
+func (g Grant) Matches(perm Permission, res Resource) bool {
+	if g.Permission != perm {
+		return false
+	}
+	for _, pattern := range g.Resources {
+		if MatchResource(pattern, res) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchResource reports whether a grant pattern covers a resource
+func MatchResource(pattern string, res Resource) bool {
+	if pattern == "*" {
+		return true
+	}
+	kind, name, ok := strings.Cut(pattern, ":")
+	if !ok || res.Kind == "" {
+		return false
+	}
+	if kind == "category" {
+		return res.Category != "" && res.Category == name
+	}
+	return kind == res.Kind && (name == "*" || name == res.Name)
+}
+
+// validateGrants checks grant permissions and resource patterns
+func validateGrants(grants []Grant) error {
+	for _, g := range grants {
+		if !IsPermission(g.Permission) {
+			return fmt.Errorf("unknown permission %q", g.Permission)
+		}
+		if len(g.Resources) == 0 {
+			return fmt.Errorf("grant for %q has no resources", g.Permission)
+		}
+		for _, pattern := range g.Resources {
+			if pattern == "*" {
+				continue
+			}
+			kind, name, ok := strings.Cut(pattern, ":")
+			if !ok || name == "" {
+				return fmt.Errorf("invalid resource pattern %q", pattern)
+			}
+			switch kind {
+			case ResourceApp, ResourceService, "category":
+			default:
+				return fmt.Errorf("invalid resource kind in %q (want app, service or category)", pattern)
+			}
+		}
+	}
+	return nil
+}
+
+func grantsMatch(grants []Grant, perm Permission, res Resource) bool {
+	for _, g := range grants {
+		if g.Matches(perm, res) {
+			return true
+		}
+	}
+	return false
+}
+
+func grantsInclude(grants []Grant, perm Permission) bool {
+	for _, g := range grants {
+		if g.Permission == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Can reports whether an active user may perform perm on res, through
+// their role or their own scoped grants
+func (u *User) Can(perm Permission, res Resource) bool {
+	if !u.Active {
+		return false
+	}
+	return HasPermission(u.Role, perm, res) || grantsMatch(u.Grants, perm, res)
+}
+
+// CanAny reports whether an active user holds perm on at least one resource.
+// Route-level checks use this; handlers then check the specific target.
+func (u *User) CanAny(perm Permission) bool {
+	if !u.Active {
+		return false
+	}
+	return HasAnyPermission(u.Role, perm) || grantsInclude(u.Grants, perm)
+}
@@ -0,0 +1,85 @@
+package rbac
+
+import "testing"
+
+func TestMatchResource(t *testing.T) {
+	jellyfin := AppResource("jellyfin", "media")
+	postgres := ServiceResource("postgres", "")
+
+	tests := []struct {
+		pattern  string
+		res      Resource
+		expected bool
+	}{
+		{"*", jellyfin, true},
+		{"*", Global, true},
+		{"app:*", jellyfin, true},
+		{"app:jellyfin", jellyfin, true},
+		{"app:gitea", jellyfin, false},
+		{"category:media", jellyfin, true},
+		{"category:media", ServiceResource("jellyfin", "media"), true},
+		{"category:dev", jellyfin, false},
+		{"service:postgres", postgres, true},
+		{"service:*", jellyfin, false},
+		{"app:*", Global, false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchResource(tt.pattern, tt.res); got != tt.expected {
+			t.Errorf("MatchResource(%q, %s) = %v, want %v", tt.pattern, tt.res, got, tt.expected)
+		}
+	}
+}
+
+func TestScopedGrants(t *testing.T) {
+	setupHome(t)
+	t.Cleanup(func() { setCustomRoles(nil) })
+
+	err := SaveConfig(&RBACConfig{
+		Users: []User{
+			{Username: "admin", Role: RoleAdmin, Active: true},
+			{Username: "dana", Role: RoleViewer, Active: true, Grants: []Grant{
+				{Permission: PermAppRemove, Resources: []string{"app:jellyfin"}},
+			}},
+		},
+		Roles: []RoleDefinition{{
+			Name:        "media-manager",
+			Permissions: []Permission{PermAppList},
+			Grants: []Grant{
+				{Permission: PermAppInstall, Resources: []string{"category:media"}},
+				{Permission: PermServiceLogs, Resources: []string{"category:media"}},
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("SaveConfig failed: %v", err)
+	}
+
+	if !HasPermission("media-manager", PermAppInstall, AppResource("jellyfin", "media")) {
+		t.Error("media-manager should install media apps")
+	}
+	if HasPermission("media-manager", PermAppInstall, AppResource("gitea", "dev")) {
+		t.Error("media-manager should not install dev apps")
+	}
+	if HasPermission("media-manager", PermAppInstall, Global) {
+		t.Error("scoped grant should not satisfy a global check")
+	}
+	if !HasAnyPermission("media-manager", PermAppInstall) {
+		t.Error("HasAnyPermission should see scoped grants")
+	}
+
+	dana, _ := GetUser("dana")
+	if !dana.Can(PermAppRemove, AppResource("jellyfin", "media")) {
+		t.Error("dana should remove jellyfin")
+	}
+	if dana.Can(PermAppRemove, AppResource("immich", "media")) {
+		t.Error("dana should not remove immich")
+	}
+
+	bad := &RBACConfig{Users: []User{{Username: "x", Role: RoleViewer, Grants: []Grant{
+		{Permission: PermAppRemove, Resources: []string{"volume:data"}},
+	}}}}
+	if err := bad.Validate(); err == nil {
+		t.Error("expected error for invalid resource kind")
+	}
+}
@@ -0,0 +1,187 @@
+package rbac
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// RoleDefinition is a custom role declared in rbac.json
+type RoleDefinition struct {
+	Name        Role         `json:"name"`
+	Description string       `json:"description,omitempty"`
+	Inherits    []Role       `json:"inherits,omitempty"` // built-in or custom roles whose permissions are included
+	Permissions []Permission `json:"permissions,omitempty"`
+	Grants      []Grant      `json:"grants,omitempty"` // permissions limited to specific apps/services
+}
+
+// ResolvedRole is a role with inheritance flattened
+type ResolvedRole struct {
+	Permissions []Permission // apply to every resource
+	Grants      []Grant      // apply only to matching resources
+}
+
+// allPermissions lists every permission the stack knows about
+var allPermissions = []Permission{
+	PermAppInstall, PermAppRemove, PermAppList,
+	PermServiceStart, PermServiceStop, PermServiceLogs,
+	PermBackupCreate, PermBackupRestore, PermBackupList,
+	PermConfigRead, PermConfigWrite,
+	PermMeshManage, PermAIChat, PermAIManage,
+	PermRBACManage, PermAuditRead, PermDashboard,
+}
+
+// customRoles holds the resolved custom roles from the last loaded rbac.json
+var (
+	customMu    sync.RWMutex
+	customRoles = map[Role]ResolvedRole{}
+)
+
+// AllPermissions returns every known permission
+func AllPermissions() []Permission {
+	return append([]Permission(nil), allPermissions...)
+}
+
+// IsPermission reports whether p is a known permission
+func IsPermission(p Permission) bool {
+	for _, known := range allPermissions {
+		if known == p {
+			return true
+		}
+	}
+	return false
+}
+
+// IsBuiltinRole reports whether a role is one of the immutable built-in roles
+func IsBuiltinRole(role Role) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// ResolveRoles validates custom role definitions and flattens inheritance
+func ResolveRoles(defs []RoleDefinition) (map[Role]ResolvedRole, error) {
+	byName := make(map[Role]*RoleDefinition, len(defs))
+	for i := range defs {
+		d := &defs[i]
+		if d.Name == "" {
+			return nil, fmt.Errorf("role %d: name is required", i)
+		}
+		if IsBuiltinRole(d.Name) {
+			return nil, fmt.Errorf("role %q: built-in roles cannot be redefined", d.Name)
+		}
+		if _, dup := byName[d.Name]; dup {
+			return nil, fmt.Errorf("role %q: defined more than once", d.Name)
+		}
+		for _, p := range d.Permissions {
+			if !IsPermission(p) {
+				return nil, fmt.Errorf("role %q: unknown permission %q", d.Name, p)
+			}
+		}
+		if err := validateGrants(d.Grants); err != nil {
+			return nil, fmt.Errorf("role %q: %w", d.Name, err)
+		}
+		byName[d.Name] = d
+	}
+
+	resolved := make(map[Role]ResolvedRole, len(defs))
+	visiting := make(map[Role]bool)
+
+	var resolve func(role Role, path []Role) (ResolvedRole, error)
+	resolve = func(role Role, path []Role) (ResolvedRole, error) {
+		if perms, ok := rolePermissions[role]; ok {
+			return ResolvedRole{Permissions: perms}, nil
+		}
+		if r, ok := resolved[role]; ok {
+			return r, nil
+		}
+		def, ok := byName[role]
+		if !ok {
+			return ResolvedRole{}, fmt.Errorf("role %q: inherits unknown role %q", path[len(path)-1], role)
+		}
+		if visiting[role] {
+			return ResolvedRole{}, fmt.Errorf("role %q: inheritance cycle %v", role, append(path, role))
+		}
+		visiting[role] = true
+
+		var r ResolvedRole
+		seen := make(map[Permission]bool)
+		add := func(parent ResolvedRole) {
+			for _, p := range parent.Permissions {
+				if !seen[p] {
+					seen[p] = true
+					r.Permissions = append(r.Permissions, p)
+				}
+			}
+			r.Grants = append(r.Grants, parent.Grants...)
+		}
+		for _, parent := range def.Inherits {
+			inherited, err := resolve(parent, append(path, role))
+			if err != nil {
+				return ResolvedRole{}, err
+			}
+			add(inherited)
+		}
+		add(ResolvedRole{Permissions: def.Permissions, Grants: def.Grants})
+
+		visiting[role] = false
+		resolved[role] = r
+		return r, nil
+	}
+
+	for _, d := range defs {
+		if _, err := resolve(d.Name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return resolved, nil
+}
+
+// Validate checks custom roles and that every user references a defined role
+func (c *RBACConfig) Validate() error {
+	resolved, err := ResolveRoles(c.Roles)
+	if err != nil {
+		return err
+	}
+	for _, u := range c.Users {
+		if _, ok := resolved[u.Role]; !ok && !IsBuiltinRole(u.Role) {
+			return fmt.Errorf("user %q: unknown role %q", u.Username, u.Role)
+		}
+		if err := validateGrants(u.Grants); err != nil {
+			return fmt.Errorf("user %q: %w", u.Username, err)
+		}
+	}
+	return nil
+}
+
+// setCustomRoles replaces the custom role table consulted by HasPermission
+func setCustomRoles(resolved map[Role]ResolvedRole) {
+	customMu.Lock()
+	defer customMu.Unlock()
+	if resolved == nil {
+		resolved = map[Role]ResolvedRole{}
+	}
+	customRoles = resolved
+}
+
+// lookupRole finds a role in the merged built-in + custom table
+func lookupRole(role Role) (ResolvedRole, bool) {
+	if perms, ok := rolePermissions[role]; ok {
+		return ResolvedRole{Permissions: perms}, true
+	}
+	customMu.RLock()
+	defer customMu.RUnlock()
+	r, ok := customRoles[role]
+	return r, ok
+}
+
+// customRoleNames returns the loaded custom roles in name order
+func customRoleNames() []Role {
+	customMu.RLock()
+	defer customMu.RUnlock()
+	names := make([]Role, 0, len(customRoles))
+	for r := range customRoles {
+		names = append(names, r)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
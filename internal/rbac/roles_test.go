@@ -0,0 +1,85 @@
+package rbac
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolveRoles(t *testing.T) {
+	resolved, err := ResolveRoles([]RoleDefinition{
+		{Name: "media-manager", Inherits: []Role{"log-reader"}, Permissions: []Permission{PermAppInstall, PermAppRemove}},
+		{Name: "log-reader", Inherits: []Role{RoleBackup}, Permissions: []Permission{PermServiceLogs}},
+	})
+	if err != nil {
+		t.Fatalf("ResolveRoles failed: %v", err)
+	}
+
+	perms := resolved["media-manager"].Permissions
+	for _, want := range []Permission{PermAppInstall, PermServiceLogs, PermBackupList, PermDashboard} {
+		found := false
+		for _, p := range perms {
+			if p == want {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("media-manager missing %s", want)
+		}
+	}
+}
+
+func TestResolveRolesInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		defs []RoleDefinition
+		want string
+	}{
+		{"builtin", []RoleDefinition{{Name: RoleAdmin}}, "built-in"},
+		{"unknown permission", []RoleDefinition{{Name: "x", Permissions: []Permission{"app.explode"}}}, "unknown permission"},
+		{"unknown parent", []RoleDefinition{{Name: "x", Inherits: []Role{"ghost"}}}, "unknown role"},
+		{"cycle", []RoleDefinition{{Name: "a", Inherits: []Role{"b"}}, {Name: "b", Inherits: []Role{"a"}}}, "cycle"},
+		{"duplicate", []RoleDefinition{{Name: "a"}, {Name: "a"}}, "more than once"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ResolveRoles(tt.defs)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestCustomRolesFromConfig(t *testing.T) {
+	setupHome(t)
+	t.Cleanup(func() { setCustomRoles(nil) })
+
+	err := SaveConfig(&RBACConfig{
+		Users: []User{{Username: "admin", Role: RoleAdmin, Active: true}},
+		Roles: []RoleDefinition{{Name: "media-manager", Permissions: []Permission{PermAppInstall, PermServiceLogs}}},
+	})
+	if err != nil {
+		t.Fatalf("SaveConfig failed: %v", err)
+	}
+	if _, err := LoadConfig(); err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if !HasPermission("media-manager", PermAppInstall, Global) {
+		t.Error("media-manager should have app.install")
+	}
+	if HasPermission("media-manager", PermConfigWrite, Global) {
+		t.Error("media-manager should not have config.write")
+	}
+	if len(AvailableRoles()) != 5 {
+		t.Errorf("expected 5 roles, got %d", len(AvailableRoles()))
+	}
+
+	if err := AddUser("jo", "media-manager", ""); err != nil {
+		t.Errorf("AddUser with custom role failed: %v", err)
+	}
+	if err := AddUser("kim", "no-such-role", ""); err == nil {
+		t.Error("expected error for unknown role")
+	}
+}
@@ -0,0 +1,181 @@
+package rbac
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Achilles1089/sovereign-stack/internal/config"
+)
+
+// SessionTTL is how long a dashboard session stays valid
+const SessionTTL = 12 * time.Hour
+
+// ErrInvalidSession is returned for malformed, tampered or expired session tokens
+var ErrInvalidSession = errors.New("invalid or expired session")
+
+// Session is the signed payload carried in a session cookie or bearer header
+type Session struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"u"`
+	IssuedAt  time.Time `json:"iat"`
+	ExpiresAt time.Time `json:"exp"`
+}
+
+// IssueSession creates a signed session token for a user
+func IssueSession(username string, ttl time.Duration) (string, *Session, error) {
+	key, err := sessionKey()
+	if err != nil {
+		return "", nil, err
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	sess := &Session{
+		ID:        hex.EncodeToString(id),
+		Username:  username,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(ttl),
+	}
+
+	payload, err := json.Marshal(sess)
+	if err != nil {
+		return "", nil, err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + signSession(key, encoded), sess, nil
+}
+
+// ParseSession verifies a session token's signature and expiry
+func ParseSession(token string) (*Session, error) {
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok || encoded == "" || sig == "" {
+		return nil, ErrInvalidSession
+	}
+
+	key, err := sessionKey()
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal([]byte(sig), []byte(signSession(key, encoded))) {
+		return nil, ErrInvalidSession
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidSession
+	}
+
+	var sess Session
+	if err := json.Unmarshal(payload, &sess); err != nil {
+		return nil, ErrInvalidSession
+	}
+	if sess.Username == "" || time.Now().After(sess.ExpiresAt) {
+		return nil, ErrInvalidSession
+	}
+
+	revoked, err := loadRevokedSessions()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := revoked[sess.ID]; ok {
+		return nil, ErrInvalidSession
+	}
+
+	return &sess, nil
+}
+
+// ValidFor reports whether the session may still act for the user — sessions
+// issued before the user's last password change are rejected
+func (s *Session) ValidFor(u *User) bool {
+	if s.Username != u.Username {
+		return false
+	}
+	return u.PasswordChangedAt == nil || !s.IssuedAt.Before(u.PasswordChangedAt.Truncate(time.Second))
+}
+
+// RevokeSession invalidates a session before its natural expiry (logout)
+func RevokeSession(sess *Session) error {
+	revoked, err := loadRevokedSessions()
+	if err != nil {
+		return err
+	}
+
+	// Drop entries that have expired on their own
+	now := time.Now()
+	for id, exp := range revoked {
+		if now.After(exp) {
+			delete(revoked, id)
+		}
+	}
+	revoked[sess.ID] = sess.ExpiresAt
+
+	data, err := json.MarshalIndent(revoked, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(revokedSessionsPath(), data, 0600)
+}
+
+func revokedSessionsPath() string {
+	return filepath.Join(config.ConfigDir(), "revoked_sessions.json")
+}
+
+func loadRevokedSessions() (map[string]time.Time, error) {
+	revoked := make(map[string]time.Time)
+	data, err := os.ReadFile(revokedSessionsPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return revoked, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &revoked); err != nil {
+		return nil, fmt.Errorf("failed to parse revoked sessions: %w", err)
+	}
+	return revoked, nil
+}
+
+func signSession(key []byte, encoded string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// sessionKey loads the HMAC key used to sign sessions, creating it on first use
+func sessionKey() ([]byte, error) {
+	path := filepath.Join(config.ConfigDir(), "session.key")
+	data, err := os.ReadFile(path)
+	if err == nil && len(data) >= 32 {
+		return data, nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read session key: %w", err)
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, key, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write session key: %w", err)
+	}
+	return key, nil
+}
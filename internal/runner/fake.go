@@ -0,0 +1,129 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Response is what a Fake returns for a matching command
+type Response struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int   // non-zero makes Run fail like a real exit status
+	Err      error // makes Run fail with this error
+}
+
+// Call is a command a Fake received
+type Call struct {
+	Command
+	Input string // everything read from Stdin
+}
+
+// Fake is a Runner that records commands instead of running them. Commands
+// without a matching response succeed with no output.
+type Fake struct {
+	mu        sync.Mutex
+	calls     []Call
+	responses []fakeResponse
+	missing   map[string]bool
+}
+
+type fakeResponse struct {
+	prefix string
+	resp   Response
+}
+
+// NewFake returns an empty Fake
+func NewFake() *Fake {
+	return &Fake{missing: make(map[string]bool)}
+}
+
+// On sets the response for commands whose line starts with prefix, e.g.
+// "docker compose" or "wg genkey". The most recently added match wins.
+func (f *Fake) On(prefix string, resp Response) *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.responses = append(f.responses, fakeResponse{prefix: prefix, resp: resp})
+	return f
+}
+
+// Missing makes LookPath, and running the program, fail as if it were not installed
+func (f *Fake) Missing(name string) *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.missing[name] = true
+	return f
+}
+
+// Calls returns the commands run so far
+func (f *Fake) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+// Lines returns the command lines run so far
+func (f *Fake) Lines() []string {
+	var lines []string
+	for _, c := range f.Calls() {
+		lines = append(lines, c.String())
+	}
+	return lines
+}
+
+// Run records cmd and replays the matching response
+func (f *Fake) Run(ctx context.Context, cmd Command) (*Result, error) {
+	call := Call{Command: cmd}
+	if cmd.Stdin != nil {
+		data, _ := io.ReadAll(cmd.Stdin)
+		call.Input = string(data)
+	}
+
+	f.mu.Lock()
+	f.calls = append(f.calls, call)
+	missing := f.missing[cmd.Name]
+	var resp Response
+	line := cmd.String()
+	for i := len(f.responses) - 1; i >= 0; i-- {
+		if strings.HasPrefix(line, f.responses[i].prefix) {
+			resp = f.responses[i].resp
+			break
+		}
+	}
+	f.mu.Unlock()
+
+	if missing {
+		return &Result{ExitCode: -1}, &Error{Command: line, ExitCode: -1, Err: fmt.Errorf("%s %w", cmd.Name, ErrNotInstalled)}
+	}
+	if err := ctx.Err(); err != nil {
+		return &Result{ExitCode: -1}, &Error{Command: line, ExitCode: -1, Err: err}
+	}
+
+	if cmd.Stdout != nil {
+		io.WriteString(cmd.Stdout, resp.Stdout)
+	}
+	if cmd.Stderr != nil {
+		io.WriteString(cmd.Stderr, resp.Stderr)
+	}
+	res := &Result{Stdout: []byte(resp.Stdout), Stderr: []byte(resp.Stderr), ExitCode: resp.ExitCode}
+	switch {
+	case resp.Err != nil:
+		return res, &Error{Command: line, ExitCode: -1, Stderr: resp.Stderr, Err: resp.Err}
+	case resp.ExitCode != 0:
+		return res, &Error{Command: line, ExitCode: resp.ExitCode, Stderr: resp.Stderr, Err: fmt.Errorf("exit status %d", resp.ExitCode)}
+	}
+	return res, nil
+}
+
+// LookPath reports every program as installed unless marked Missing
+func (f *Fake) LookPath(name string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.missing[name] {
+		return "", fmt.Errorf("%s %w", name, ErrNotInstalled)
+	}
+	return "/usr/bin/" + name, nil
+}
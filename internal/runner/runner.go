@@ -0,0 +1,193 @@
+// Package runner runs external commands (docker, restic, wg, adb, crontab)
+// behind an interface, so the code that drives them can be tested with a Fake
+// instead of the real binaries.
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Command describes one invocation of an external program
+type Command struct {
+	Name    string
+	Args    []string
+	Env     []string // added to the current environment, "KEY=value"
+	Dir     string
+	Stdin   io.Reader
+	Stdout  io.Writer     // output is streamed here as well as captured
+	Stderr  io.Writer     // likewise for errors
+	Timeout time.Duration // 0 = only ctx limits the run
+}
+
+// Cmd is a shorthand for a Command with just a name and arguments
+func Cmd(name string, args ...string) Command {
+	return Command{Name: name, Args: args}
+}
+
+// String renders the command line for logs and errors. The environment is
+// left out, since it may carry passwords.
+func (c Command) String() string {
+	return strings.TrimSpace(c.Name + " " + strings.Join(c.Args, " "))
+}
+
+// Result is the outcome of a finished command
+type Result struct {
+	Stdout   []byte
+	Stderr   []byte
+	ExitCode int
+	Duration time.Duration
+}
+
+// Runner runs commands to completion. Long-lived processes that outlive the
+// call, such as llama-server, are started with os/exec directly.
+type Runner interface {
+	Run(ctx context.Context, cmd Command) (*Result, error)
+	LookPath(name string) (string, error)
+}
+
+var (
+	// ErrNotInstalled is returned when the program is not on $PATH
+	ErrNotInstalled = errors.New("not installed")
+	// ErrTimeout is returned when Command.Timeout elapses
+	ErrTimeout = errors.New("timed out")
+)
+
+// Error reports a command that could not run or exited non-zero
+type Error struct {
+	Command  string
+	ExitCode int    // -1 if the command did not run to completion
+	Stderr   string // trimmed error output
+	Err      error
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%s: %v", e.Command, e.Err)
+	if line := lastLine(e.Stderr); line != "" {
+		msg += ": " + line
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// ExitCode returns the exit status carried by err, or -1 if there is none
+func ExitCode(err error) int {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.ExitCode
+	}
+	return -1
+}
+
+func lastLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		s = s[i+1:]
+	}
+	return strings.TrimSpace(s)
+}
+
+// Exec runs commands with os/exec
+type Exec struct{}
+
+// Default is the Runner used when none is injected
+var Default Runner = Exec{}
+
+// streamCapture bounds how much streamed output is kept for the Result
+const streamCapture = 64 * 1024
+
+// Run executes cmd and waits for it. Output is captured in full unless it is
+// also streamed, in which case only the last 64 KiB is kept.
+func (Exec) Run(ctx context.Context, cmd Command) (*Result, error) {
+	if cmd.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cmd.Timeout)
+		defer cancel()
+	}
+
+	proc := exec.CommandContext(ctx, cmd.Name, cmd.Args...)
+	proc.Dir = cmd.Dir
+	proc.Stdin = cmd.Stdin
+	if len(cmd.Env) > 0 {
+		proc.Env = append(os.Environ(), cmd.Env...)
+	}
+	stdout, stderr := capture(cmd.Stdout), capture(cmd.Stderr)
+	proc.Stdout, proc.Stderr = stdout.writer(), stderr.writer()
+
+	start := time.Now()
+	err := proc.Run()
+	res := &Result{
+		Stdout:   stdout.bytes(),
+		Stderr:   stderr.bytes(),
+		ExitCode: proc.ProcessState.ExitCode(),
+		Duration: time.Since(start),
+	}
+	if err == nil {
+		return res, nil
+	}
+
+	e := &Error{Command: cmd.String(), ExitCode: res.ExitCode, Stderr: string(res.Stderr), Err: err}
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(err, exec.ErrNotFound):
+		e.Err = fmt.Errorf("%s %w", cmd.Name, ErrNotInstalled)
+	case ctx.Err() == context.DeadlineExceeded && cmd.Timeout > 0:
+		e.Err = fmt.Errorf("%w after %s", ErrTimeout, cmd.Timeout)
+	case ctx.Err() != nil:
+		e.Err = ctx.Err()
+	case errors.As(err, &exitErr):
+		e.Err = fmt.Errorf("exit status %d", res.ExitCode)
+	}
+	return res, e
+}
+
+// LookPath finds name on $PATH
+func (Exec) LookPath(name string) (string, error) {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return "", fmt.Errorf("%s %w", name, ErrNotInstalled)
+	}
+	return path, nil
+}
+
+// captured collects a command's output, optionally streaming it too
+type captured struct {
+	stream io.Writer
+	buf    bytes.Buffer
+}
+
+func capture(stream io.Writer) *captured {
+	return &captured{stream: stream}
+}
+
+func (c *captured) writer() io.Writer {
+	if c.stream == nil {
+		return &c.buf
+	}
+	return io.MultiWriter(c.stream, &tail{buf: &c.buf})
+}
+
+func (c *captured) bytes() []byte {
+	return c.buf.Bytes()
+}
+
+// tail keeps only the last streamCapture bytes written to it
+type tail struct {
+	buf *bytes.Buffer
+}
+
+func (t *tail) Write(p []byte) (int, error) {
+	t.buf.Write(p)
+	if extra := t.buf.Len() - streamCapture; extra > 0 {
+		t.buf.Next(extra)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,96 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecCaptures(t *testing.T) {
+	res, err := Exec{}.Run(context.Background(), Command{
+		Name: "sh", Args: []string{"-c", `echo "$GREETING"; echo oops >&2`},
+		Env: []string{"GREETING=hello"},
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if string(res.Stdout) != "hello\n" || string(res.Stderr) != "oops\n" || res.ExitCode != 0 {
+		t.Errorf("unexpected result: %+v", res)
+	}
+}
+
+func TestExecStreams(t *testing.T) {
+	var out bytes.Buffer
+	res, err := Exec{}.Run(context.Background(), Command{Name: "sh", Args: []string{"-c", "echo streamed"}, Stdout: &out})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if out.String() != "streamed\n" || string(res.Stdout) != "streamed\n" {
+		t.Errorf("streamed %q, captured %q", out.String(), res.Stdout)
+	}
+}
+
+func TestExecErrors(t *testing.T) {
+	_, err := Exec{}.Run(context.Background(), Command{Name: "sh", Args: []string{"-c", "echo first >&2; echo 'no such volume' >&2; exit 3"}})
+	var e *Error
+	if !errors.As(err, &e) || e.ExitCode != 3 || ExitCode(err) != 3 {
+		t.Fatalf("expected exit code 3, got %v", err)
+	}
+	if !strings.HasSuffix(err.Error(), "exit status 3: no such volume") {
+		t.Errorf("error should end with the last stderr line: %v", err)
+	}
+
+	_, err = Exec{}.Run(context.Background(), Cmd("sovereign-no-such-binary"))
+	if !errors.Is(err, ErrNotInstalled) {
+		t.Errorf("expected ErrNotInstalled, got %v", err)
+	}
+	if _, err := (Exec{}).LookPath("sovereign-no-such-binary"); !errors.Is(err, ErrNotInstalled) {
+		t.Errorf("LookPath: expected ErrNotInstalled, got %v", err)
+	}
+
+	start := time.Now()
+	_, err = Exec{}.Run(context.Background(), Command{Name: "sleep", Args: []string{"5"}, Timeout: 50 * time.Millisecond})
+	if !errors.Is(err, ErrTimeout) || time.Since(start) > 3*time.Second {
+		t.Errorf("expected ErrTimeout, got %v after %s", err, time.Since(start))
+	}
+}
+
+func TestEnvNotInError(t *testing.T) {
+	_, err := Exec{}.Run(context.Background(), Command{Name: "sh", Args: []string{"-c", "exit 1"}, Env: []string{"RESTIC_PASSWORD=hunter22"}})
+	if err == nil || strings.Contains(err.Error(), "hunter22") {
+		t.Errorf("environment leaked into error: %v", err)
+	}
+}
+
+func TestFake(t *testing.T) {
+	f := NewFake().
+		On("docker compose", Response{Stdout: "ok"}).
+		On("docker compose -f x pull", Response{Stderr: "manifest unknown", ExitCode: 1}).
+		Missing("wg")
+
+	res, err := f.Run(context.Background(), Cmd("docker", "compose", "-f", "x", "up", "-d"))
+	if err != nil || string(res.Stdout) != "ok" {
+		t.Errorf("up: %v %q", err, res.Stdout)
+	}
+	_, err = f.Run(context.Background(), Cmd("docker", "compose", "-f", "x", "pull"))
+	if ExitCode(err) != 1 || !strings.Contains(err.Error(), "manifest unknown") {
+		t.Errorf("pull: expected exit 1, got %v", err)
+	}
+	if _, err := f.Run(context.Background(), Command{Name: "wg", Args: []string{"pubkey"}, Stdin: strings.NewReader("priv")}); !errors.Is(err, ErrNotInstalled) {
+		t.Errorf("wg: expected ErrNotInstalled, got %v", err)
+	}
+	if _, err := f.Run(context.Background(), Cmd("crontab", "-l")); err != nil {
+		t.Errorf("unmatched commands should succeed: %v", err)
+	}
+
+	want := "docker compose -f x up -d|docker compose -f x pull|wg pubkey|crontab -l"
+	if got := strings.Join(f.Lines(), "|"); got != want {
+		t.Errorf("recorded %s", got)
+	}
+	if f.Calls()[2].Input != "priv" {
+		t.Errorf("stdin not recorded: %q", f.Calls()[2].Input)
+	}
+}
@@ -0,0 +1,382 @@
+// Package secrets keeps passwords and keys out of config.yaml and the compose
+// file. Each secret is stored encrypted with AES-256-GCM under
+// ~/.sovereign/secrets and referenced from config as secret://<name>.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/big"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+
+	"github.com/Achilles1089/sovereign-stack/internal/config"
+	"github.com/Achilles1089/sovereign-stack/internal/filelock"
+)
+
+// RefPrefix marks a config value that names a secret instead of holding it
+const RefPrefix = "secret://"
+
+// PassphraseEnv, when set, derives the store key from a passphrase instead
+// of the key file, so the key never touches the disk
+const PassphraseEnv = "SOVEREIGN_SECRETS_PASSPHRASE"
+
+// DefaultLength is the length of generated secrets
+const DefaultLength = 32
+
+// ErrNotFound is returned for a secret that has not been set
+var ErrNotFound = errors.New("secret not found")
+
+// argon2id parameters for passphrase-derived keys, as in rbac
+const (
+	argonTime    = 3
+	argonMemory  = 64 * 1024
+	argonThreads = 2
+	keyLen       = 32
+	saltLen      = 16
+)
+
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*(/[a-z0-9][a-z0-9._-]*)*$`)
+
+// Store is an encrypted secret store rooted at a directory
+type Store struct {
+	dir string
+
+	mu  sync.Mutex
+	key []byte
+}
+
+// NewStore opens the store in dir. The key is loaded on first use.
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Default returns the store under ~/.sovereign/secrets
+func Default() *Store {
+	return NewStore(filepath.Join(config.ConfigDir(), "secrets"))
+}
+
+// Dir returns the directory holding the store
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Ref returns the config reference for a secret name
+func Ref(name string) string {
+	return RefPrefix + name
+}
+
+// IsRef reports whether a config value is a secret reference
+func IsRef(value string) bool {
+	return strings.HasPrefix(value, RefPrefix)
+}
+
+// ValidateName checks a secret name such as "backup/password"
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid secret name %q: use lowercase letters, digits, '.', '_' and '-', separated by '/'", name)
+	}
+	return nil
+}
+
+// Resolve returns value unchanged, or the secret it references
+func (s *Store) Resolve(value string) (string, error) {
+	if !IsRef(value) {
+		return value, nil
+	}
+	return s.Get(strings.TrimPrefix(value, RefPrefix))
+}
+
+// Get decrypts a secret
+func (s *Store) Get(name string) (string, error) {
+	if err := ValidateName(name); err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(s.path(name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret %s: %w", name, err)
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return "", fmt.Errorf("secret %s is corrupt: %w", name, err)
+	}
+	gcm, err := s.cipher()
+	if err != nil {
+		return "", err
+	}
+	if len(raw) < gcm.NonceSize() {
+		return "", fmt.Errorf("secret %s is corrupt", name)
+	}
+	// The name is authenticated so secret files cannot be swapped around
+	plain, err := gcm.Open(nil, raw[:gcm.NonceSize()], raw[gcm.NonceSize():], []byte(name))
+	if err != nil {
+		return "", fmt.Errorf("cannot decrypt secret %s: wrong key or passphrase", name)
+	}
+	return string(plain), nil
+}
+
+// Set encrypts and stores a secret, replacing any previous value
+func (s *Store) Set(name, value string) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	gcm, err := s.cipher()
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(value), []byte(name))
+
+	path := s.path(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create secrets directory: %w", err)
+	}
+	return writePrivate(path, []byte(base64.StdEncoding.EncodeToString(sealed)+"\n"))
+}
+
+// Ensure returns a secret, generating a random one of length n first if it
+// does not exist yet. Concurrent callers agree on a single value.
+func (s *Store) Ensure(name string, n int) (string, error) {
+	if v, err := s.Get(name); !errors.Is(err, ErrNotFound) {
+		return v, err
+	}
+
+	lock, err := filelock.Acquire(filepath.Join(s.dir, ".lock"))
+	if err != nil {
+		return "", err
+	}
+	defer lock.Release()
+
+	if v, err := s.Get(name); !errors.Is(err, ErrNotFound) {
+		return v, err
+	}
+	v, err := Generate(n)
+	if err != nil {
+		return "", err
+	}
+	return v, s.Set(name, v)
+}
+
+// Delete removes a secret
+func (s *Store) Delete(name string) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	if err := os.Remove(s.path(name)); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("%w: %s", ErrNotFound, name)
+		}
+		return err
+	}
+	return nil
+}
+
+// List returns the names of all stored secrets, sorted
+func (s *Store) List() ([]string, error) {
+	var names []string
+	err := filepath.WalkDir(s.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".enc") {
+			return nil
+		}
+		rel, err := filepath.Rel(s.dir, path)
+		if err != nil {
+			return err
+		}
+		names = append(names, strings.TrimSuffix(filepath.ToSlash(rel), ".enc"))
+		return nil
+	})
+	sort.Strings(names)
+	return names, err
+}
+
+// placeholder matches {{secret:name}} or {{secret:name:length}}
+var placeholder = regexp.MustCompile(`\{\{secret:([a-z0-9._/-]+)(?::([0-9]+))?\}\}`)
+
+// HasPlaceholder reports whether text contains a {{secret:...}} placeholder
+func HasPlaceholder(text string) bool {
+	return placeholder.MatchString(text)
+}
+
+// Expand replaces {{secret:name[:length]}} placeholders in text, generating
+// any secret that does not exist yet. Names without a slash are scoped to
+// scope, e.g. "db_password" in scope "apps/nextcloud" is
+// apps/nextcloud/db_password; names with a slash are global.
+func (s *Store) Expand(scope, text string) (string, error) {
+	var firstErr error
+	out := placeholder.ReplaceAllStringFunc(text, func(m string) string {
+		sub := placeholder.FindStringSubmatch(m)
+		name := sub[1]
+		if !strings.Contains(name, "/") && scope != "" {
+			name = scope + "/" + name
+		}
+		n := DefaultLength
+		if sub[2] != "" {
+			n, _ = strconv.Atoi(sub[2])
+		}
+		v, err := s.Ensure(name, n)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		return v
+	})
+	return out, firstErr
+}
+
+// Generate returns a random alphanumeric string of length n. Letters and
+// digits only, so it is safe in URLs, env files and connection strings.
+func Generate(n int) (string, error) {
+	const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	if n <= 0 {
+		n = DefaultLength
+	}
+	b := make([]byte, n)
+	max := big.NewInt(int64(len(alphabet)))
+	for i := range b {
+		k, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = alphabet[k.Int64()]
+	}
+	return string(b), nil
+}
+
+func (s *Store) path(name string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(name)+".enc")
+}
+
+func (s *Store) cipher() (cipher.AEAD, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.key == nil {
+		key, err := s.loadKey()
+		if err != nil {
+			return nil, err
+		}
+		s.key = key
+	}
+	block, err := aes.NewCipher(s.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// loadKey derives the key from $SOVEREIGN_SECRETS_PASSPHRASE when set,
+// otherwise reads the key file, creating it on first use. A store already
+// using a key file refuses a passphrase, which could not decrypt it.
+func (s *Store) loadKey() ([]byte, error) {
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create secrets directory: %w", err)
+	}
+	if pass := os.Getenv(PassphraseEnv); pass != "" {
+		if keyFile := filepath.Join(s.dir, ".key"); fileExists(keyFile) {
+			return nil, fmt.Errorf("%s is set, but the secret store is encrypted with the key file %s: unset %s to use it", PassphraseEnv, keyFile, PassphraseEnv)
+		}
+		salt, err := s.readOrCreate(".salt", saltLen)
+		if err != nil {
+			return nil, err
+		}
+		return argon2.IDKey([]byte(pass), salt, argonTime, argonMemory, argonThreads, keyLen), nil
+	}
+	if _, err := os.Stat(filepath.Join(s.dir, ".salt")); err == nil {
+		if _, err := os.Stat(filepath.Join(s.dir, ".key")); err != nil {
+			return nil, fmt.Errorf("secret store is passphrase-protected: set %s", PassphraseEnv)
+		}
+	}
+	return s.readOrCreate(".key", keyLen)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// readOrCreate returns the random bytes in a file under the store,
+// generating them if the file does not exist
+func (s *Store) readOrCreate(file string, n int) ([]byte, error) {
+	path := filepath.Join(s.dir, file)
+
+	lock, err := filelock.Acquire(filepath.Join(s.dir, ".key.lock"))
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Release()
+
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if len(data) != n {
+			return nil, fmt.Errorf("%s has the wrong length", path)
+		}
+		return data, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	data = make([]byte, n)
+	if _, err := rand.Read(data); err != nil {
+		return nil, err
+	}
+	if err := writePrivate(path, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// writePrivate atomically writes a file readable only by its owner
+func writePrivate(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Chmod(tmp, 0600); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// WriteEnvFile writes KEY=value lines to an env file readable only by its
+// owner, for use as a compose env_file
+func WriteEnvFile(path string, env []string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create env directory: %w", err)
+	}
+	var sb strings.Builder
+	sb.WriteString("# Generated by sovereign — contains credentials, keep private\n")
+	for _, kv := range env {
+		sb.WriteString(kv)
+		sb.WriteByte('\n')
+	}
+	return writePrivate(path, []byte(sb.String()))
+}
@@ -0,0 +1,155 @@
+package secrets
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetGet(t *testing.T) {
+	s := NewStore(t.TempDir())
+
+	if err := s.Set("backup/password", "hunter22"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	got, err := s.Get("backup/password")
+	if err != nil || got != "hunter22" {
+		t.Fatalf("Get = %q, %v", got, err)
+	}
+
+	// Stored encrypted, owner-only
+	path := filepath.Join(s.Dir(), "backup", "password.enc")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "hunter22") {
+		t.Error("secret stored in plaintext")
+	}
+	for _, p := range []string{path, filepath.Join(s.Dir(), ".key")} {
+		info, err := os.Stat(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("%s has mode %o, want 0600", filepath.Base(p), info.Mode().Perm())
+		}
+	}
+
+	// A fresh Store on the same directory reads the key file
+	if got, err := NewStore(s.Dir()).Get("backup/password"); err != nil || got != "hunter22" {
+		t.Errorf("reopened Get = %q, %v", got, err)
+	}
+
+	if _, err := s.Get("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestSwappedFileRejected(t *testing.T) {
+	s := NewStore(t.TempDir())
+	s.Set("a", "one")
+	s.Set("b", "two")
+
+	data, _ := os.ReadFile(filepath.Join(s.Dir(), "a.enc"))
+	os.WriteFile(filepath.Join(s.Dir(), "b.enc"), data, 0600)
+	if _, err := s.Get("b"); err == nil {
+		t.Error("a secret copied to another name should not decrypt")
+	}
+}
+
+func TestPassphrase(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(PassphraseEnv, "correct horse")
+	if err := NewStore(dir).Set("x", "value"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".key")); err == nil {
+		t.Error("passphrase mode should not write a key file")
+	}
+	if got, err := NewStore(dir).Get("x"); err != nil || got != "value" {
+		t.Errorf("Get = %q, %v", got, err)
+	}
+
+	t.Setenv(PassphraseEnv, "wrong")
+	if _, err := NewStore(dir).Get("x"); err == nil {
+		t.Error("wrong passphrase should fail")
+	}
+
+	os.Unsetenv(PassphraseEnv)
+	if _, err := NewStore(dir).Get("x"); err == nil || !strings.Contains(err.Error(), PassphraseEnv) {
+		t.Errorf("expected a passphrase-required error, got %v", err)
+	}
+}
+
+func TestPassphraseWithKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := NewStore(dir).Set("x", "value"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	t.Setenv(PassphraseEnv, "correct horse")
+	if _, err := NewStore(dir).Get("x"); err == nil || !strings.Contains(err.Error(), "key file") {
+		t.Errorf("a passphrase next to a key file should be refused, got %v", err)
+	}
+}
+
+func TestExpand(t *testing.T) {
+	s := NewStore(t.TempDir())
+
+	out, err := s.Expand("apps/plausible", "postgres://sovereign:{{secret:postgres/password}}@db/x?k={{secret:key:64}}")
+	if err != nil {
+		t.Fatalf("Expand failed: %v", err)
+	}
+	if HasPlaceholder(out) {
+		t.Fatalf("placeholders left in %q", out)
+	}
+
+	shared, err := s.Get("postgres/password")
+	if err != nil || len(shared) != DefaultLength {
+		t.Errorf("shared secret = %q, %v", shared, err)
+	}
+	key, err := s.Get("apps/plausible/key")
+	if err != nil || len(key) != 64 {
+		t.Errorf("scoped secret = %q, %v", key, err)
+	}
+	if !strings.Contains(out, shared) || !strings.Contains(out, key) {
+		t.Errorf("expanded text does not contain the stored secrets: %q", out)
+	}
+
+	// Expanding again reuses the stored values
+	again, _ := s.Expand("apps/plausible", "{{secret:key:64}}")
+	if again != key {
+		t.Error("existing secret was regenerated")
+	}
+}
+
+func TestValidateName(t *testing.T) {
+	for _, name := range []string{"backup/password", "apps/code-server/password", "a.b_c"} {
+		if err := ValidateName(name); err != nil {
+			t.Errorf("%q: %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "../etc/passwd", "a//b", "/abs", "Upper", "a/"} {
+		if err := ValidateName(name); err == nil {
+			t.Errorf("%q should be rejected", name)
+		}
+	}
+}
+
+func TestWriteEnvFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "env", "app.env")
+	if err := WriteEnvFile(path, []string{"A=1", "B=2"}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("env file mode = %v, %v", info.Mode().Perm(), err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "A=1\nB=2\n") {
+		t.Errorf("unexpected env file:\n%s", data)
+	}
+}
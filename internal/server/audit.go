@@ -0,0 +1,79 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Achilles1089/sovereign-stack/internal/audit"
+)
+
+const maxAuditPage = 1000
+
+// handleAudit serves GET /api/audit — filtered, cursor-paginated audit events.
+// Query params: since, until, actor, target, severity, success, action, limit, cursor, order=asc|desc
+func (s *Server) handleAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	now := time.Now()
+
+	since, err := audit.ParseTime(q.Get("since"), now)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	until, err := audit.ParseTime(q.Get("until"), now)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	opts := audit.QueryOptions{
+		Since:        since,
+		Until:        until,
+		Actor:        q.Get("actor"),
+		TargetPrefix: q.Get("target"),
+		Severity:     q.Get("severity"),
+		Action:       q.Get("action"),
+		Cursor:       q.Get("cursor"),
+		Descending:   q.Get("order") != "asc",
+		Limit:        100,
+	}
+
+	if v := q.Get("success"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid success")
+			return
+		}
+		opts.Success = &b
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		opts.Limit = min(n, maxAuditPage)
+	}
+
+	result, err := s.audit.Search(opts)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, result)
+}
+
+// handleAuditSinks serves GET /api/audit/sinks — forwarding queue depth and failures
+func (s *Server) handleAuditSinks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, map[string]interface{}{"sinks": s.audit.SinkStatus()})
+}
@@ -0,0 +1,547 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Achilles1089/sovereign-stack/internal/apps"
+	"github.com/Achilles1089/sovereign-stack/internal/audit"
+	"github.com/Achilles1089/sovereign-stack/internal/config"
+	"github.com/Achilles1089/sovereign-stack/internal/rbac"
+	"github.com/Achilles1089/sovereign-stack/internal/tokens"
+)
+
+// sessionCookie is the name of the dashboard session cookie
+const sessionCookie = "sovereign_session"
+
+// routePermissions maps each API route to the permission required to call it.
+// Routes ending in "/" match any path below them.
+var routePermissions = map[string]rbac.Permission{
+	"/api/status":         rbac.PermDashboard,
+	"/api/resources":      rbac.PermDashboard,
+	"/api/resources/live": rbac.PermDashboard,
+	"/api/envy/sysinfo":   rbac.PermDashboard,
+
+	"/api/apps":         rbac.PermAppList,
+	"/api/apps/install": rbac.PermAppInstall,
+	"/api/apps/remove":  rbac.PermAppRemove,
+
+	"/api/ai/models":       rbac.PermDashboard,
+	"/api/ai/catalog":      rbac.PermDashboard,
+	"/api/ai/status":       rbac.PermDashboard,
+	"/api/ai/phone-status": rbac.PermDashboard,
+	"/api/ai/phone-models": rbac.PermDashboard,
+	"/api/ai/image-status": rbac.PermDashboard,
+	"/api/ai/voice-status": rbac.PermDashboard,
+	"/api/ai/music-status": rbac.PermDashboard,
+	"/api/ai/rag-status":   rbac.PermDashboard,
+
+	"/api/ai/chat":           rbac.PermAIChat,
+	"/api/ai/server-chat":    rbac.PermAIChat,
+	"/api/ai/image-generate": rbac.PermAIChat,
+	"/api/ai/transcribe":     rbac.PermAIChat,
+	"/api/ai/speak":          rbac.PermAIChat,
+	"/api/ai/voice-chat":     rbac.PermAIChat,
+	"/api/ai/music-generate": rbac.PermAIChat,
+	"/api/ai/rag-search":     rbac.PermAIChat,
+	"/api/ai/rag-documents":  rbac.PermAIChat,
+
+	"/api/ai/pull":         rbac.PermAIManage,
+	"/api/ai/delete":       rbac.PermAIManage,
+	"/api/ai/switch":       rbac.PermAIManage,
+	"/api/ai/phone-switch": rbac.PermAIManage,
+	"/api/ai/phone-start":  rbac.PermAIManage,
+	"/api/ai/rag-upload":   rbac.PermAIManage,
+	"/api/ai/rag-delete":   rbac.PermAIManage,
+
+	"/api/gallery":         rbac.PermDashboard,
+	"/api/gallery/image/":  rbac.PermDashboard,
+	"/api/gallery/delete/": rbac.PermAIManage,
+
+	"/api/news/feeds":    rbac.PermDashboard,
+	"/api/news/articles": rbac.PermDashboard,
+	"/api/news/search":   rbac.PermDashboard,
+	"/api/news/refresh":  rbac.PermDashboard,
+	"/api/news/status":   rbac.PermDashboard,
+
+	"/api/agent/chat":   rbac.PermAIChat,
+	"/api/agent/status": rbac.PermDashboard,
+	"/api/agent/clear":  rbac.PermAIChat,
+
+	"/api/audit":       rbac.PermAuditRead,
+	"/api/audit/sinks": rbac.PermAuditRead,
+
+	// PATCH additionally requires config.write, checked in the handler
+	"/api/config":        rbac.PermConfigRead,
+	"/api/config/status": rbac.PermConfigRead,
+}
+
+// publicRoutes are reachable without logging in
+var publicRoutes = map[string]bool{
+	"/api/auth/login":  true,
+	"/api/auth/logout": true,
+}
+
+// authenticatedRoutes need a logged-in user but no particular permission
+var authenticatedRoutes = map[string]bool{
+	"/api/auth/me":       true,
+	"/api/tokens":        true,
+	"/api/tokens/revoke": true,
+}
+
+// routePermission returns the permission required for an API path.
+// Unmapped API paths require rbac.manage so new routes are closed by default.
+func routePermission(path string) rbac.Permission {
+	if perm, ok := routePermissions[path]; ok {
+		return perm
+	}
+
+	best := ""
+	for route := range routePermissions {
+		if strings.HasSuffix(route, "/") && strings.HasPrefix(path, route) && len(route) > len(best) {
+			best = route
+		}
+	}
+	if best != "" {
+		return routePermissions[best]
+	}
+	return rbac.PermRBACManage
+}
+
+type principalKey struct{}
+
+// principal is the authenticated caller of an API request
+type principal struct {
+	User  *rbac.User
+	Token *tokens.Token // set when the caller used a personal API token
+}
+
+// can reports whether the caller holds a permission on at least one resource.
+// API tokens are limited to the intersection of the user's and the token's scope.
+func (p *principal) can(perm rbac.Permission) bool {
+	if !p.User.CanAny(perm) {
+		return false
+	}
+	return p.Token == nil || p.Token.Allows(perm)
+}
+
+// canOn reports whether the caller holds a permission on a specific resource
+func (p *principal) canOn(perm rbac.Permission, res rbac.Resource) bool {
+	if !p.User.Can(perm, res) {
+		return false
+	}
+	return p.Token == nil || p.Token.Allows(perm)
+}
+
+// principalFrom returns the authenticated caller, or nil when RBAC is disabled
+func principalFrom(r *http.Request) *principal {
+	p, _ := r.Context().Value(principalKey{}).(*principal)
+	return p
+}
+
+// authMiddleware authenticates /api/ requests and enforces route permissions.
+// It is a no-op while RBAC is disabled in rbac.json.
+func (s *Server) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/") || publicRoutes[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rbacCfg, err := rbac.LoadConfig()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to load RBAC config")
+			return
+		}
+		if !rbacCfg.Enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		p := authenticate(r, rbacCfg)
+		if p == nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="sovereign"`)
+			writeError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+
+		if !authenticatedRoutes[r.URL.Path] && !p.can(routePermission(r.URL.Path)) {
+			writeError(w, http.StatusForbidden, "permission denied")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), principalKey{}, p)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// authenticate resolves the caller from an API token, bearer session or
+// session cookie. Deactivated users are rejected here, since some routes
+// check no permission.
+func authenticate(r *http.Request, rbacCfg *rbac.RBACConfig) *principal {
+	token := sessionToken(r)
+	if token == "" {
+		return nil
+	}
+
+	if tokens.IsToken(token) {
+		tok, err := tokens.Authenticate(token)
+		if err != nil {
+			return nil
+		}
+		user := findUser(rbacCfg, tok.Username)
+		if user == nil || !user.Active {
+			return nil
+		}
+		return &principal{User: user, Token: tok}
+	}
+
+	sess, err := rbac.ParseSession(token)
+	if err != nil {
+		return nil
+	}
+
+	user := findUser(rbacCfg, sess.Username)
+	if user == nil || !user.Active || !sess.ValidFor(user) {
+		return nil
+	}
+	return &principal{User: user}
+}
+
+// sessionToken returns the raw session token presented with a request
+func sessionToken(r *http.Request) string {
+	if token := bearerToken(r); token != "" {
+		return token
+	}
+	if c, err := r.Cookie(sessionCookie); err == nil {
+		return c.Value
+	}
+	return ""
+}
+
+func bearerToken(r *http.Request) string {
+	h := r.Header.Get("Authorization")
+	if len(h) > 7 && strings.EqualFold(h[:7], "bearer ") {
+		return strings.TrimSpace(h[7:])
+	}
+	return ""
+}
+
+func findUser(rbacCfg *rbac.RBACConfig, username string) *rbac.User {
+	for i := range rbacCfg.Users {
+		if rbacCfg.Users[i].Username == username {
+			return &rbacCfg.Users[i]
+		}
+	}
+	return nil
+}
+
+// writeError writes a JSON error body with the given status code
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+// --- Auth Handlers ---
+
+// handleLogin verifies a username/password and sets a signed session cookie
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	user, err := rbac.Authenticate(req.Username, req.Password)
+	s.audit.LogAuthEvent(audit.Actor{Name: req.Username, SourceIP: s.clientIP(r)}, err)
+	if err != nil {
+		if errors.Is(err, rbac.ErrAccountLocked) || errors.Is(err, rbac.ErrInvalidCredentials) {
+			writeError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "login failed")
+		return
+	}
+
+	token, sess, err := rbac.IssueSession(user.Username, rbac.SessionTTL)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create session")
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  sess.ExpiresAt,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	writeJSON(w, map[string]interface{}{
+		"ok":         true,
+		"user":       userResponse(user),
+		"expires_at": sess.ExpiresAt,
+	})
+}
+
+// handleLogout revokes the caller's session and clears the cookie
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if sess, err := rbac.ParseSession(sessionToken(r)); err == nil {
+		if err := rbac.RevokeSession(sess); err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to revoke session")
+			return
+		}
+		s.audit.LogLogout(audit.Actor{Name: sess.Username, SourceIP: s.clientIP(r)})
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    "",
+		Path:     "/",
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	writeJSON(w, map[string]interface{}{"ok": true})
+}
+
+// handleMe returns the logged-in user and their effective permissions
+func (s *Server) handleMe(w http.ResponseWriter, r *http.Request) {
+	p := principalFrom(r)
+	if p == nil {
+		// RBAC disabled — every caller acts as admin
+		writeJSON(w, map[string]interface{}{
+			"rbac_enabled": false,
+			"user": map[string]interface{}{
+				"username":    "admin",
+				"role":        rbac.RoleAdmin,
+				"permissions": rbac.GetPermissions(rbac.RoleAdmin),
+			},
+		})
+		return
+	}
+
+	resp := map[string]interface{}{
+		"rbac_enabled": true,
+		"user":         userResponse(p.User),
+	}
+	if p.Token != nil {
+		resp["token"] = map[string]interface{}{
+			"id":          p.Token.ID,
+			"name":        p.Token.Name,
+			"permissions": p.Token.Permissions,
+		}
+	}
+	writeJSON(w, resp)
+}
+
+// callerName returns the username acting on a request — "admin" while RBAC is disabled
+func callerName(r *http.Request) string {
+	if p := principalFrom(r); p != nil {
+		return p.User.Username
+	}
+	return "admin"
+}
+
+// actor identifies the caller of a request for the audit log
+func (s *Server) actor(r *http.Request) audit.Actor {
+	return audit.Actor{Name: callerName(r), SourceIP: s.clientIP(r)}
+}
+
+// clientIP returns the caller's address. X-Forwarded-For is only believed
+// from a proxy listed in dashboard.trusted_proxies, and only its last
+// untrusted hop, since earlier entries come from the client.
+func (s *Server) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	cfg := s.config()
+	if cfg == nil || !trustedProxy(cfg.Dashboard.TrustedProxies, host) {
+		return host
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		host = hop
+		if !trustedProxy(cfg.Dashboard.TrustedProxies, hop) {
+			break
+		}
+	}
+	return host
+}
+
+// trustedProxy reports whether addr is one of the configured proxies
+func trustedProxy(proxies []string, addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, p := range proxies {
+		if n, err := config.ParseProxy(p); err == nil && n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// callerCan reports whether the caller holds a role-wide permission — always true while RBAC is disabled
+func callerCan(r *http.Request, perm rbac.Permission) bool {
+	return callerCanOn(r, perm, rbac.Global)
+}
+
+// callerCanOn reports whether the caller holds a permission on a resource — always true while RBAC is disabled
+func callerCanOn(r *http.Request, perm rbac.Permission, res rbac.Resource) bool {
+	p := principalFrom(r)
+	return p == nil || p.canOn(perm, res)
+}
+
+// appResource builds the RBAC resource for an app, including its category when known
+func appResource(name string) rbac.Resource {
+	if app := apps.FindApp(name); app != nil {
+		return rbac.AppResource(app.Name, app.Category)
+	}
+	return rbac.AppResource(name, "")
+}
+
+// userResponse is the public view of a user — never includes credentials
+func userResponse(u *rbac.User) map[string]interface{} {
+	return map[string]interface{}{
+		"username":    u.Username,
+		"role":        u.Role,
+		"email":       u.Email,
+		"permissions": rbac.GetPermissions(u.Role),
+		"grants":      append(rbac.GetGrants(u.Role), u.Grants...),
+	}
+}
+
+// --- API Token Handlers ---
+
+// handleTokens lists (GET) or creates (POST) personal API tokens for the caller
+func (s *Server) handleTokens(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		owner := callerName(r)
+		if r.URL.Query().Get("all") == "true" && callerCan(r, rbac.PermRBACManage) {
+			owner = ""
+		}
+		list, err := tokens.List(owner)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if list == nil {
+			list = []tokens.Token{}
+		}
+		for i := range list {
+			list[i].Hash = ""
+		}
+		writeJSON(w, map[string]interface{}{"tokens": list})
+
+	case "POST":
+		// Tokens can't mint further tokens — creation needs an interactive login
+		if p := principalFrom(r); p != nil && p.Token != nil {
+			writeError(w, http.StatusForbidden, "API tokens cannot create tokens")
+			return
+		}
+
+		var req struct {
+			Name        string            `json:"name"`
+			Permissions []rbac.Permission `json:"permissions"`
+			ExpiresIn   string            `json:"expires_in"` // Go duration, e.g. "720h"
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request")
+			return
+		}
+
+		var ttl time.Duration
+		if req.ExpiresIn != "" {
+			d, err := time.ParseDuration(req.ExpiresIn)
+			if err != nil || d <= 0 {
+				writeError(w, http.StatusBadRequest, "invalid expires_in")
+				return
+			}
+			ttl = d
+		}
+
+		username := callerName(r)
+		raw, tok, err := tokens.Create(username, req.Name, req.Permissions, ttl)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		s.audit.LogTokenEvent(s.actor(r), "create", tok.ID, tok.Name)
+
+		tok.Hash = ""
+		writeJSON(w, map[string]interface{}{
+			"token": raw,
+			"info":  tok,
+		})
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleTokenRevoke revokes one of the caller's tokens (or any token for RBAC admins)
+func (s *Server) handleTokenRevoke(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ID == "" {
+		writeError(w, http.StatusBadRequest, "invalid request")
+		return
+	}
+
+	tok, err := tokens.Get(req.ID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	username := callerName(r)
+	if tok.Username != username && !callerCan(r, rbac.PermRBACManage) {
+		writeError(w, http.StatusForbidden, "permission denied")
+		return
+	}
+
+	if err := tokens.Revoke(tok.ID); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.audit.LogTokenEvent(s.actor(r), "revoke", tok.ID, tok.Name)
+
+	writeJSON(w, map[string]interface{}{"revoked": tok.ID})
+}
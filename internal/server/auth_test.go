@@ -0,0 +1,335 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Achilles1089/sovereign-stack/internal/audit"
+	"github.com/Achilles1089/sovereign-stack/internal/config"
+	"github.com/Achilles1089/sovereign-stack/internal/rbac"
+	"github.com/Achilles1089/sovereign-stack/internal/tokens"
+)
+
+func setupRBAC(t *testing.T, enabled bool) {
+	t.Helper()
+	tmpDir := t.TempDir()
+	os.Setenv("HOME", tmpDir)
+	os.MkdirAll(filepath.Join(tmpDir, ".sovereign"), 0755)
+	t.Cleanup(func() { os.Unsetenv("HOME") })
+
+	err := rbac.SaveConfig(&rbac.RBACConfig{
+		Enabled: enabled,
+		Users: []rbac.User{
+			{Username: "admin", Role: rbac.RoleAdmin, Active: true},
+			{Username: "viewer", Role: rbac.RoleViewer, Active: true},
+			{Username: "disabled", Role: rbac.RoleAdmin, Active: false},
+		},
+	})
+	if err != nil {
+		t.Fatalf("SaveConfig failed: %v", err)
+	}
+}
+
+func sessionFor(t *testing.T, username string) string {
+	t.Helper()
+	token, _, err := rbac.IssueSession(username, rbac.SessionTTL)
+	if err != nil {
+		t.Fatalf("IssueSession failed: %v", err)
+	}
+	return token
+}
+
+func serveAuth(r *http.Request) int {
+	s := &Server{}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	rec := httptest.NewRecorder()
+	s.authMiddleware(ok).ServeHTTP(rec, r)
+	return rec.Code
+}
+
+func TestAuthMiddlewareDisabled(t *testing.T) {
+	setupRBAC(t, false)
+
+	r := httptest.NewRequest("POST", "/api/apps/install", nil)
+	if code := serveAuth(r); code != http.StatusOK {
+		t.Errorf("expected 200 with RBAC disabled, got %d", code)
+	}
+}
+
+func TestAuthMiddlewareEnforces(t *testing.T) {
+	setupRBAC(t, true)
+
+	tests := []struct {
+		name     string
+		path     string
+		user     string
+		cookie   bool
+		expected int
+	}{
+		{"anonymous", "/api/status", "", false, http.StatusUnauthorized},
+		{"admin install", "/api/apps/install", "admin", false, http.StatusOK},
+		{"viewer install", "/api/apps/install", "viewer", false, http.StatusForbidden},
+		{"viewer status", "/api/status", "viewer", false, http.StatusOK},
+		{"viewer via cookie", "/api/apps", "viewer", true, http.StatusOK},
+		{"viewer model delete", "/api/ai/delete", "viewer", false, http.StatusForbidden},
+		{"viewer gallery image", "/api/gallery/image/123", "viewer", false, http.StatusOK},
+		{"viewer gallery delete", "/api/gallery/delete/123", "viewer", false, http.StatusForbidden},
+		{"inactive user", "/api/status", "disabled", false, http.StatusUnauthorized},
+		{"inactive user me", "/api/auth/me", "disabled", false, http.StatusUnauthorized},
+		{"unknown route", "/api/not-mapped", "viewer", false, http.StatusForbidden},
+		{"static files", "/index.html", "", false, http.StatusOK},
+		{"login is public", "/api/auth/login", "", false, http.StatusOK},
+		{"me needs login", "/api/auth/me", "", false, http.StatusUnauthorized},
+		{"me for viewer", "/api/auth/me", "viewer", false, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", tt.path, nil)
+			if tt.user != "" {
+				token := sessionFor(t, tt.user)
+				if tt.cookie {
+					r.AddCookie(&http.Cookie{Name: sessionCookie, Value: token})
+				} else {
+					r.Header.Set("Authorization", "Bearer "+token)
+				}
+			}
+			if code := serveAuth(r); code != tt.expected {
+				t.Errorf("%s %s: got %d, want %d", tt.user, tt.path, code, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAuthMiddlewareRejectsTamperedToken(t *testing.T) {
+	setupRBAC(t, true)
+
+	token := sessionFor(t, "viewer")
+	r := httptest.NewRequest("GET", "/api/status", nil)
+	r.Header.Set("Authorization", "Bearer "+token+"x")
+	if code := serveAuth(r); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for tampered token, got %d", code)
+	}
+}
+
+// patternRecorder collects the patterns Server.routes registers
+type patternRecorder []string
+
+func (p *patternRecorder) HandleFunc(pattern string, _ func(http.ResponseWriter, *http.Request)) {
+	*p = append(*p, pattern)
+}
+
+func TestRoutePermissionsCoverRegisteredRoutes(t *testing.T) {
+	var registered patternRecorder
+	(&Server{}).routes(&registered)
+	if len(registered) == 0 {
+		t.Fatal("no routes registered")
+	}
+
+	for _, pattern := range registered {
+		perm, mapped := routePermissions[pattern]
+		switch {
+		case publicRoutes[pattern] || authenticatedRoutes[pattern]:
+		case !mapped:
+			t.Errorf("route %s has no entry in routePermissions", pattern)
+		case perm == "":
+			t.Errorf("route %s has no permission", pattern)
+		}
+	}
+	for route := range routePermissions {
+		if !slices.Contains(registered, route) {
+			t.Errorf("routePermissions lists %s, which is not registered", route)
+		}
+	}
+
+	if routePermission("/api/apps/install") != rbac.PermAppInstall {
+		t.Error("install should require app.install")
+	}
+	if routePermission("/api/ai/delete") != rbac.PermAIManage {
+		t.Error("model delete should require ai.manage")
+	}
+}
+
+func TestAuthMiddlewareAPITokenScope(t *testing.T) {
+	setupRBAC(t, true)
+
+	raw, _, err := tokens.Create("admin", "ios", []rbac.Permission{rbac.PermDashboard, rbac.PermAppList}, 0)
+	if err != nil {
+		t.Fatalf("tokens.Create failed: %v", err)
+	}
+
+	tests := []struct {
+		path     string
+		expected int
+	}{
+		{"/api/status", http.StatusOK},
+		{"/api/apps", http.StatusOK},
+		{"/api/apps/install", http.StatusForbidden}, // admin role, but outside token scope
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", tt.path, nil)
+		r.Header.Set("Authorization", "Bearer "+raw)
+		if code := serveAuth(r); code != tt.expected {
+			t.Errorf("%s: got %d, want %d", tt.path, code, tt.expected)
+		}
+	}
+}
+
+func TestAuthMiddlewareRejectsInactiveTokenOwner(t *testing.T) {
+	setupRBAC(t, true)
+
+	raw, _, err := tokens.Create("disabled", "old laptop", nil, 0)
+	if err != nil {
+		t.Fatalf("tokens.Create failed: %v", err)
+	}
+	for _, path := range []string{"/api/auth/me", "/api/tokens", "/api/status"} {
+		r := httptest.NewRequest("GET", path, nil)
+		r.Header.Set("Authorization", "Bearer "+raw)
+		if code := serveAuth(r); code != http.StatusUnauthorized {
+			t.Errorf("%s: got %d, want 401 for a deactivated user's token", path, code)
+		}
+	}
+}
+
+func TestAppRemoveScopedGrant(t *testing.T) {
+	setupRBAC(t, true)
+
+	cfg, _ := rbac.LoadConfig()
+	cfg.Users = append(cfg.Users, rbac.User{
+		Username: "media", Role: rbac.RoleViewer, Active: true,
+		Grants: []rbac.Grant{{Permission: rbac.PermAppRemove, Resources: []string{"app:jellyfin"}}},
+	})
+	if err := rbac.SaveConfig(cfg); err != nil {
+		t.Fatalf("SaveConfig failed: %v", err)
+	}
+
+	s := &Server{}
+	handler := s.authMiddleware(http.HandlerFunc(s.handleAppRemove))
+	token := sessionFor(t, "media")
+
+	r := httptest.NewRequest("POST", "/api/apps/remove", strings.NewReader(`{"name":"gitea"}`))
+	r.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 removing gitea, got %d", rec.Code)
+	}
+
+	// Route-level check passes because the user holds app.remove on something
+	r = httptest.NewRequest("POST", "/api/apps/remove", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	if code := serveAuth(r); code != http.StatusOK {
+		t.Errorf("expected route check to pass, got %d", code)
+	}
+}
+
+func TestLoginLogout(t *testing.T) {
+	setupRBAC(t, true)
+	if err := rbac.SetPassword("viewer", "s3cret-pass"); err != nil {
+		t.Fatalf("SetPassword failed: %v", err)
+	}
+
+	s := &Server{audit: audit.NewLogger()}
+
+	// Wrong password
+	rec := httptest.NewRecorder()
+	s.handleLogin(rec, httptest.NewRequest("POST", "/api/auth/login",
+		strings.NewReader(`{"username":"viewer","password":"nope"}`)))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for bad password, got %d", rec.Code)
+	}
+
+	// Correct password sets an HttpOnly session cookie
+	rec = httptest.NewRecorder()
+	s.handleLogin(rec, httptest.NewRequest("POST", "/api/auth/login",
+		strings.NewReader(`{"username":"viewer","password":"s3cret-pass"}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for login, got %d", rec.Code)
+	}
+	var cookie *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == sessionCookie {
+			cookie = c
+		}
+	}
+	if cookie == nil || !cookie.HttpOnly {
+		t.Fatal("expected HttpOnly session cookie")
+	}
+
+	r := httptest.NewRequest("GET", "/api/status", nil)
+	r.AddCookie(cookie)
+	if code := serveAuth(r); code != http.StatusOK {
+		t.Errorf("expected 200 with session cookie, got %d", code)
+	}
+
+	// Logout revokes the session
+	r = httptest.NewRequest("POST", "/api/auth/logout", nil)
+	r.AddCookie(cookie)
+	s.handleLogout(httptest.NewRecorder(), r)
+
+	r = httptest.NewRequest("GET", "/api/status", nil)
+	r.AddCookie(cookie)
+	if code := serveAuth(r); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 after logout, got %d", code)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	s := &Server{cfg: &config.Config{Dashboard: config.DashboardConfig{TrustedProxies: []string{"172.18.0.0/16"}}}}
+	tests := []struct {
+		remote   string
+		forward  string
+		expected string
+	}{
+		{"192.168.1.20:51234", "", "192.168.1.20"},
+		{"192.168.1.20:51234", "10.0.0.1", "192.168.1.20"},          // untrusted forwarder
+		{"127.0.0.1:40000", "203.0.113.9", "127.0.0.1"},             // loopback is not a proxy unless listed
+		{"172.18.0.2:40000", "203.0.113.9", "203.0.113.9"},          // Caddy
+		{"172.18.0.2:40000", "6.6.6.6, 203.0.113.9", "203.0.113.9"}, // client-supplied hops are ignored
+		{"172.18.0.2:40000", "203.0.113.9, 172.18.0.3", "203.0.113.9"},
+		{"172.18.0.2:40000", "junk", "172.18.0.2"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/api/status", nil)
+		r.RemoteAddr = tt.remote
+		if tt.forward != "" {
+			r.Header.Set("X-Forwarded-For", tt.forward)
+		}
+		if got := s.clientIP(r); got != tt.expected {
+			t.Errorf("clientIP(%s, %q) = %s, want %s", tt.remote, tt.forward, got, tt.expected)
+		}
+	}
+}
+
+func TestCORSAllowsConfiguredOriginsOnly(t *testing.T) {
+	s := &Server{cfg: &config.Config{Dashboard: config.DashboardConfig{AllowedOrigins: []string{"https://admin.example.com"}}}}
+	h := s.corsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]string{"ok": "yes"})
+	}))
+
+	for origin, want := range map[string]string{
+		"https://admin.example.com": "https://admin.example.com",
+		"https://evil.example.net":  "",
+		"":                          "",
+	} {
+		r := httptest.NewRequest("GET", "/api/status", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != want {
+			t.Errorf("origin %q: Access-Control-Allow-Origin = %q, want %q", origin, got, want)
+		}
+		if w.Header().Get("Vary") != "Origin" {
+			t.Errorf("origin %q: missing Vary: Origin", origin)
+		}
+	}
+}
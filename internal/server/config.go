@@ -0,0 +1,289 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/Achilles1089/sovereign-stack/internal/backup"
+	"github.com/Achilles1089/sovereign-stack/internal/config"
+	"github.com/Achilles1089/sovereign-stack/internal/rbac"
+	"github.com/Achilles1089/sovereign-stack/internal/secrets"
+)
+
+// liveConfigPath reports whether a change to path takes effect without
+// restarting the server
+func liveConfigPath(path string) bool {
+	return strings.HasPrefix(path, "ai.") || strings.HasPrefix(path, "apps.") || strings.HasPrefix(path, "dashboard.") ||
+		path == "backup.schedule"
+}
+
+// configPatch is the result of PATCH /api/config
+type configPatch struct {
+	Changed         []string `json:"changed"`
+	Applied         []string `json:"applied"`                    // in effect now
+	RestartRequired []string `json:"restart_required,omitempty"` // saved, used after a restart
+	Overridden      []string `json:"overridden,omitempty"`       // saved, but an env var or flag wins
+	Errors          []string `json:"errors,omitempty"`           // saved, but applying failed
+}
+
+// handleConfig serves GET /api/config (secrets redacted) and PATCH /api/config,
+// which takes {"values": {"ai.rag_host": "10.0.0.5:8093", ...}}
+func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		s.writeConfig(w)
+	case "PATCH":
+		if !callerCan(r, rbac.PermConfigWrite) {
+			writeError(w, http.StatusForbidden, "permission denied")
+			return
+		}
+		s.patchConfig(w, r)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) writeConfig(w http.ResponseWriter) {
+	s.cfgMu.RLock()
+	cfg, path, origins := s.cfg, s.cfgPath, s.origins
+	s.cfgMu.RUnlock()
+
+	values := make(map[string]interface{})
+	sources := make(map[string]config.Source)
+	var live []string
+	for _, p := range config.Paths() {
+		v, err := config.Get(cfg, p)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		values[p] = config.RedactValue(p, v)
+		if o, ok := origins[p]; ok {
+			sources[p] = o.Source
+		}
+		if liveConfigPath(p) {
+			live = append(live, p)
+		}
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"path":     path,
+		"writable": path != "",
+		"values":   values,
+		"sources":  sources,
+		"live":     live,
+	})
+}
+
+func (s *Server) patchConfig(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Values map[string]json.RawMessage `json:"values"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if len(req.Values) == 0 {
+		writeError(w, http.StatusBadRequest, "no values to change")
+		return
+	}
+
+	// One writer at a time; readers keep using the current config meanwhile
+	s.cfgWriteMu.Lock()
+	defer s.cfgWriteMu.Unlock()
+
+	s.cfgMu.RLock()
+	path, origins := s.cfgPath, s.origins
+	s.cfgMu.RUnlock()
+	if path == "" {
+		writeError(w, http.StatusConflict, "config file location unknown; config is read-only")
+		return
+	}
+
+	// Edit the file layer only, so env and flag overrides are not persisted
+	file, err := config.Load(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		file, err = config.DefaultConfig(), nil
+	}
+	if err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+
+	type change struct {
+		path     string
+		old, new interface{}
+	}
+	paths := make([]string, 0, len(req.Values))
+	for p := range req.Values {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var changes []change
+	for _, p := range paths {
+		raw := req.Values[p]
+		old, err := config.Get(file, p)
+		if err != nil || p == "version" {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown config path %q", p))
+			return
+		}
+		if err := config.Set(file, p, rawText(raw)); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		v, _ := config.Get(file, p)
+		v = config.Unredact(p, v, old)
+		if err := config.SetValue(file, p, v); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if pw, ok := v.(string); ok && p == "backup.password" && pw != old && pw != "" && !secrets.IsRef(pw) {
+			writeError(w, http.StatusBadRequest, "backup.password must be a secret:// reference: store the password with "+
+				"`sovereign secret set backup/password` and set secret://backup/password")
+			return
+		}
+		if !reflect.DeepEqual(old, v) {
+			changes = append(changes, change{path: p, old: old, new: v})
+		}
+	}
+
+	if err := file.Validate(); err != nil {
+		var problems config.ValidationErrors
+		if errors.As(err, &problems) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "invalid config", "problems": problems})
+			return
+		}
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result := configPatch{Changed: []string{}, Applied: []string{}}
+	if len(changes) == 0 {
+		writeJSON(w, result)
+		return
+	}
+
+	if err := file.Save(path); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	actor := s.actor(r)
+	var apply []string
+	for _, c := range changes {
+		s.audit.LogConfigChange(actor, c.path, auditValue(c.path, c.old), auditValue(c.path, c.new))
+		result.Changed = append(result.Changed, c.path)
+		if o := origins[c.path]; o.Source == config.SourceEnv || o.Source == config.SourceFlag {
+			result.Overridden = append(result.Overridden, c.path)
+		} else {
+			apply = append(apply, c.path)
+		}
+	}
+
+	s.cfgMu.Lock()
+	s.cfgStamp = stampOf(path) // our own write; the watcher need not reload it
+	resolved := *s.fileConfig()
+	for _, p := range apply {
+		v, _ := config.Get(file, p)
+		config.SetValue(&resolved, p, v)
+	}
+	s.resolved = &resolved
+	applied, restart := s.applyLive(file, apply)
+	s.cfgMu.Unlock()
+	result.Applied = append(result.Applied, applied...)
+	result.RestartRequired = restart
+	result.Errors = s.afterApply(applied)
+
+	writeJSON(w, result)
+}
+
+// applyLive copies the live-applicable paths from src into a new running
+// config and returns the paths applied and those that need a restart.
+// The caller holds cfgMu.
+func (s *Server) applyLive(src *config.Config, paths []string) (applied, restart []string) {
+	next := *s.cfg
+	aiChanged := false
+	for _, p := range paths {
+		if !liveConfigPath(p) {
+			restart = append(restart, p)
+			continue
+		}
+		v, err := config.Get(src, p)
+		if err != nil {
+			continue
+		}
+		config.SetValue(&next, p, v)
+		applied = append(applied, p)
+		aiChanged = aiChanged || strings.HasPrefix(p, "ai.")
+	}
+	s.cfg = &next
+	if aiChanged {
+		s.client = s.client.WithConfig(&next)
+	}
+	return applied, restart
+}
+
+// afterApply carries out side effects of applied changes outside the
+// server itself, returning any failures
+func (s *Server) afterApply(applied []string) []string {
+	var errs []string
+	for _, p := range applied {
+		if p == "backup.schedule" && s.backups().IsCronInstalled() {
+			if err := s.rescheduleBackups(s.config().Backup.Schedule); err != nil {
+				errs = append(errs, "backup.schedule: "+err.Error())
+			}
+		}
+	}
+	return errs
+}
+
+// backups returns a backup manager that runs commands through s.runner
+func (s *Server) backups() *backup.Manager {
+	m := backup.NewManager(config.ConfigDir())
+	m.Runner = s.runner()
+	return m
+}
+
+// rescheduleBackups rewrites the backup cron entry for a new schedule
+func (s *Server) rescheduleBackups(schedule string) error {
+	bin, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	return s.backups().SetupCron(schedule, bin)
+}
+
+// rawText turns a JSON value into the text form config.Set parses. JSON
+// numbers, booleans, arrays and objects are valid YAML as they are.
+func rawText(raw json.RawMessage) string {
+	var str string
+	if json.Unmarshal(raw, &str) == nil {
+		return str
+	}
+	if string(raw) == "null" {
+		return ""
+	}
+	return string(raw)
+}
+
+// auditValue renders a config value for the audit log, hiding secrets
+func auditValue(path string, v interface{}) string {
+	v = config.RedactValue(path, v)
+	if str, ok := v.(string); ok {
+		return str
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
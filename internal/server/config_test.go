@@ -0,0 +1,180 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Achilles1089/sovereign-stack/internal/ai"
+	"github.com/Achilles1089/sovereign-stack/internal/audit"
+	"github.com/Achilles1089/sovereign-stack/internal/config"
+)
+
+// newConfigServer returns a server backed by a config file in a temp HOME
+func newConfigServer(t *testing.T, file string) (*Server, string) {
+	t.Helper()
+	setupRBAC(t, false)
+
+	path := filepath.Join(config.ConfigDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(file), 0600); err != nil {
+		t.Fatal(err)
+	}
+	eff, err := config.Resolve(path, nil)
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+	s := &Server{cfg: eff.Config, client: ai.NewClientFromConfig(eff.Config), audit: audit.NewLoggerFromConfig(eff.Config)}
+	s.SetConfigSource(path, eff.Origins)
+	return s, path
+}
+
+func patchConfig(s *Server, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	s.handleConfig(rec, httptest.NewRequest("PATCH", "/api/config", strings.NewReader(body)))
+	return rec
+}
+
+func TestConfigGetRedacts(t *testing.T) {
+	s, _ := newConfigServer(t, "version: 2\nbackup:\n    password: hunter22\naudit:\n    sinks:\n        - type: webhook\n          url: https://siem.example.com/in\n          headers:\n              Authorization: Bearer abc\n")
+
+	rec := httptest.NewRecorder()
+	s.handleConfig(rec, httptest.NewRequest("GET", "/api/config", nil))
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET returned %d: %s", rec.Code, body)
+	}
+	if strings.Contains(body, "hunter22") || strings.Contains(body, "Bearer abc") {
+		t.Errorf("secret leaked in GET /api/config: %s", body)
+	}
+
+	var resp struct {
+		Values  map[string]interface{}   `json:"values"`
+		Sources map[string]config.Source `json:"sources"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if resp.Values["backup.password"] != config.Redacted || resp.Sources["backup.password"] != config.SourceFile {
+		t.Errorf("backup.password = %v from %s", resp.Values["backup.password"], resp.Sources["backup.password"])
+	}
+}
+
+func TestConfigPatch(t *testing.T) {
+	s, path := newConfigServer(t, "version: 2\nbackup:\n    password: hunter22\n")
+
+	rec := patchConfig(s, `{"values": {"ai.rag_host": "10.0.0.5:8093", "ai.llama_host": "10.0.0.6:8085", "port": 9090, "backup.password": "********"}}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PATCH returned %d: %s", rec.Code, rec.Body.String())
+	}
+	var result configPatch
+	json.Unmarshal(rec.Body.Bytes(), &result)
+
+	if strings.Join(result.Changed, ",") != "ai.llama_host,ai.rag_host,port" {
+		t.Errorf("changed = %v", result.Changed)
+	}
+	if strings.Join(result.RestartRequired, ",") != "port" {
+		t.Errorf("restart_required = %v", result.RestartRequired)
+	}
+
+	// Applied live
+	if s.config().AI.RAGHost != "10.0.0.5:8093" || s.llama().Host != "10.0.0.6:8085" {
+		t.Errorf("live change not applied: rag=%s llama=%s", s.config().AI.RAGHost, s.llama().Host)
+	}
+	if s.config().Port == 9090 {
+		t.Error("port should only change after a restart")
+	}
+
+	// Persisted, and the redacted password left alone
+	saved, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if saved.Port != 9090 || saved.AI.RAGHost != "10.0.0.5:8093" || saved.Backup.Password != "hunter22" {
+		t.Errorf("unexpected saved config: port=%d rag=%s password=%q", saved.Port, saved.AI.RAGHost, saved.Backup.Password)
+	}
+
+	// One audit event per changed field
+	events, err := s.audit.Query("config.change", 0)
+	if err != nil || len(events) != 3 {
+		t.Fatalf("expected 3 config.change events, got %d (%v)", len(events), err)
+	}
+}
+
+func TestConfigPatchRejectsInvalid(t *testing.T) {
+	s, path := newConfigServer(t, "version: 2\n")
+	before, _ := os.ReadFile(path)
+
+	for _, body := range []string{
+		`{"values": {"port": 70000}}`,
+		`{"values": {"ai.rag_host": "no-port"}}`,
+		`{"values": {"nope": 1}}`,
+		`{"values": {"port": "eighty"}}`,
+	} {
+		rec := patchConfig(s, body)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, rec.Code)
+		}
+	}
+
+	after, _ := os.ReadFile(path)
+	if string(before) != string(after) {
+		t.Error("rejected PATCH modified the config file")
+	}
+}
+
+func TestConfigPatchBackupPasswordNeedsSecretRef(t *testing.T) {
+	s, path := newConfigServer(t, "version: 2\n")
+
+	rec := patchConfig(s, `{"values": {"backup.password": "hunter22"}}`)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "sovereign secret set") {
+		t.Errorf("plaintext password: expected 400 naming sovereign secret set, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if data, _ := os.ReadFile(path); strings.Contains(string(data), "hunter22") {
+		t.Errorf("plaintext password saved: %s", data)
+	}
+
+	rec = patchConfig(s, `{"values": {"backup.password": "secret://backup/password"}}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("secret ref: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if saved, _ := config.Load(path); saved.Backup.Password != "secret://backup/password" {
+		t.Errorf("saved password = %q", saved.Backup.Password)
+	}
+}
+
+func TestConfigPatchOverridden(t *testing.T) {
+	s, _ := newConfigServer(t, "version: 2\n")
+	s.origins["ai.rag_host"] = config.Origin{Source: config.SourceEnv, Detail: "SOVEREIGN_AI_RAG_HOST"}
+	running := s.config().AI.RAGHost
+
+	rec := patchConfig(s, `{"values": {"ai.rag_host": "10.0.0.5:8093"}}`)
+	var result configPatch
+	json.Unmarshal(rec.Body.Bytes(), &result)
+	if strings.Join(result.Overridden, ",") != "ai.rag_host" || s.config().AI.RAGHost != running {
+		t.Errorf("env override should win: overridden=%v rag=%s", result.Overridden, s.config().AI.RAGHost)
+	}
+}
+
+func TestConfigPatchRequiresWrite(t *testing.T) {
+	setupRBAC(t, true)
+	s := &Server{cfg: config.DefaultConfig()}
+	handler := s.authMiddleware(http.HandlerFunc(s.handleConfig))
+
+	for _, tc := range []struct {
+		method string
+		want   int
+	}{
+		{"GET", http.StatusOK},
+		{"PATCH", http.StatusForbidden},
+	} {
+		r := httptest.NewRequest(tc.method, "/api/config", strings.NewReader(`{"values": {"port": 9090}}`))
+		r.Header.Set("Authorization", "Bearer "+sessionFor(t, "viewer"))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		if rec.Code != tc.want {
+			t.Errorf("viewer %s: expected %d, got %d", tc.method, tc.want, rec.Code)
+		}
+	}
+}
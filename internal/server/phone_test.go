@@ -0,0 +1,65 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Achilles1089/sovereign-stack/internal/runner"
+)
+
+func startPhone(s *Server) map[string]interface{} {
+	rec := httptest.NewRecorder()
+	s.handlePhoneStart(rec, httptest.NewRequest("POST", "/api/ai/phone-start", strings.NewReader(`{"model": "tiny.gguf"}`)))
+	var resp map[string]interface{}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	return resp
+}
+
+func TestPhoneStartNoDevice(t *testing.T) {
+	s, _ := newConfigServer(t, "version: 2\n")
+	fake := runner.NewFake().On("adb devices", runner.Response{Stdout: "List of devices attached\n\n"})
+	s.run = fake
+
+	if resp := startPhone(s); resp["ok"] != false {
+		t.Errorf("expected failure without a device: %v", resp)
+	}
+	if lines := fake.Lines(); len(lines) != 1 {
+		t.Errorf("nothing should run after adb devices: %v", lines)
+	}
+}
+
+func TestPhoneStart(t *testing.T) {
+	s, _ := newConfigServer(t, "version: 2\n")
+	fake := runner.NewFake().On("adb devices", runner.Response{Stdout: "List of devices attached\nR58M123\tdevice\n\n"})
+	s.run = fake
+
+	if resp := startPhone(s); resp["ok"] != true || resp["model"] != "tiny.gguf" {
+		t.Fatalf("unexpected response: %v", resp)
+	}
+	lines := fake.Lines()
+	if len(lines) != 5 || lines[1] != "adb forward tcp:8085 tcp:8085" || lines[2] != "adb forward tcp:8086 tcp:8086" {
+		t.Fatalf("unexpected adb calls: %v", lines)
+	}
+	if !strings.Contains(lines[4], "models/tiny.gguf") {
+		t.Errorf("llama-server not started with the requested model: %s", lines[4])
+	}
+}
+
+func TestPhoneStartFailure(t *testing.T) {
+	s, _ := newConfigServer(t, "version: 2\n")
+	fake := runner.NewFake().
+		On("adb devices", runner.Response{Stdout: "List of devices attached\nR58M123\tdevice\n\n"}).
+		On("adb shell run-as com.termux sh -c export PATH", runner.Response{Stderr: "run-as: package not debuggable", ExitCode: 1})
+	s.run = fake
+
+	resp := startPhone(s)
+	if resp["ok"] != false || !strings.Contains(resp["error"].(string), "failed to start sysinfo") {
+		t.Fatalf("a failed adb call should be reported: %v", resp)
+	}
+	events, _ := s.audit.Query("phone.start", 1)
+	if len(events) != 1 || events[0].Success || events[0].Error == "" {
+		t.Errorf("the failure should be audited: %+v", events)
+	}
+}
@@ -0,0 +1,202 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+	"time"
+
+	"github.com/Achilles1089/sovereign-stack/internal/audit"
+	"github.com/Achilles1089/sovereign-stack/internal/config"
+)
+
+// configPollInterval is how often the watcher checks config.yaml for changes
+var configPollInterval = 2 * time.Second
+
+// Reload triggers
+const (
+	ReloadFile   = "file"
+	ReloadSignal = "signal"
+)
+
+// ReloadStatus describes the most recent config reload
+type ReloadStatus struct {
+	Time            time.Time `json:"time"`
+	Trigger         string    `json:"trigger"` // "file" or "signal"
+	Success         bool      `json:"success"`
+	Error           string    `json:"error,omitempty"` // the previous config stays in effect
+	Changed         []string  `json:"changed"`
+	Applied         []string  `json:"applied"`
+	RestartRequired []string  `json:"restart_required,omitempty"`
+}
+
+// fileStamp identifies a version of the config file on disk
+type fileStamp struct {
+	mod  time.Time
+	size int64
+}
+
+func stampOf(path string) fileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{mod: info.ModTime(), size: info.Size()}
+}
+
+// SetConfigResolver sets how a reload rebuilds the effective config, so it
+// keeps the environment and command-line overrides the server started with.
+// Without it reloads use the config file and environment only.
+func (s *Server) SetConfigResolver(resolve func() (*config.Effective, error)) {
+	s.cfgMu.Lock()
+	defer s.cfgMu.Unlock()
+	s.resolve = resolve
+}
+
+// Reload re-reads and validates the config, then swaps in the values that
+// apply live. An invalid config is rejected and the current one kept.
+func (s *Server) Reload(trigger string) error {
+	s.cfgWriteMu.Lock()
+	defer s.cfgWriteMu.Unlock()
+	return s.reloadLocked(trigger)
+}
+
+// reloadLocked is Reload for callers holding cfgWriteMu
+func (s *Server) reloadLocked(trigger string) error {
+	s.cfgMu.Lock()
+	path, resolve := s.cfgPath, s.resolve
+	s.cfgStamp = stampOf(path)
+	s.cfgMu.Unlock()
+
+	if resolve == nil {
+		resolve = func() (*config.Effective, error) { return config.Resolve(path, nil) }
+	}
+
+	status := &ReloadStatus{Time: time.Now().UTC(), Trigger: trigger, Changed: []string{}, Applied: []string{}}
+	eff, err := resolve()
+	if err != nil {
+		status.Error = err.Error()
+		s.cfgMu.Lock()
+		s.lastReload = status
+		s.cfgMu.Unlock()
+		fmt.Printf("  [config] Reload (%s) rejected, keeping current config: %v\n", trigger, err)
+		s.audit.Record(audit.SystemActor, "config.reload", "config/"+trigger, "Rejected invalid config", err)
+		return err
+	}
+
+	s.cfgMu.Lock()
+	// Diff against the file as last read, not the running config: paths that
+	// need a restart never reach it, and would otherwise show up every time
+	status.Changed = changedPaths(s.fileConfig(), eff.Config)
+	s.resolved = eff.Config
+	s.origins = eff.Origins
+	applied, restart := s.applyLive(eff.Config, status.Changed)
+	s.cfgMu.Unlock()
+
+	status.Applied = append(status.Applied, applied...)
+	status.RestartRequired = restart
+	errs := s.afterApply(applied)
+	status.Success = len(errs) == 0
+	if !status.Success {
+		status.Error = fmt.Sprint(errs)
+	}
+
+	s.cfgMu.Lock()
+	s.lastReload = status
+	s.cfgMu.Unlock()
+
+	fmt.Printf("  [config] Reloaded (%s): %d applied, %d need a restart\n", trigger, len(applied), len(restart))
+	if len(status.Changed) > 0 || !status.Success {
+		var failure error
+		if !status.Success {
+			failure = fmt.Errorf("%s", status.Error)
+		}
+		s.audit.Record(audit.SystemActor, "config.reload", "config/"+trigger,
+			fmt.Sprintf("Reloaded config: applied %v, restart required for %v", applied, restart), failure)
+	}
+	return nil
+}
+
+// fileConfig returns the config last resolved from the file, or the running
+// config before the first reload. The caller holds cfgMu.
+func (s *Server) fileConfig() *config.Config {
+	if s.resolved != nil {
+		return s.resolved
+	}
+	return s.cfg
+}
+
+// changedPaths lists the config paths whose values differ
+func changedPaths(a, b *config.Config) []string {
+	var changed []string
+	for _, p := range config.Paths() {
+		va, _ := config.Get(a, p)
+		vb, _ := config.Get(b, p)
+		if !reflect.DeepEqual(va, vb) {
+			changed = append(changed, p)
+		}
+	}
+	return changed
+}
+
+// watchConfig reloads when the config file changes on disk or on SIGHUP.
+// The file is polled so it works the same on every platform and with
+// editors that replace the file rather than write it in place. It returns
+// when ctx is cancelled.
+func (s *Server) watchConfig(ctx context.Context) {
+	s.cfgMu.Lock()
+	path := s.cfgPath
+	s.cfgStamp = stampOf(path)
+	s.cfgMu.Unlock()
+	if path == "" {
+		return
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	tick := time.NewTicker(configPollInterval)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			s.Reload(ReloadSignal)
+		case <-tick.C:
+			st := stampOf(path)
+			if st == (fileStamp{}) {
+				continue // mid-replace or deleted; keep the running config
+			}
+			s.cfgWriteMu.Lock()
+			s.cfgMu.RLock()
+			changed := st != s.cfgStamp
+			s.cfgMu.RUnlock()
+			if changed {
+				s.reloadLocked(ReloadFile)
+			}
+			s.cfgWriteMu.Unlock()
+		}
+	}
+}
+
+// handleConfigStatus serves GET /api/config/status — the config file being
+// watched and the outcome of the last reload
+func (s *Server) handleConfigStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.cfgMu.RLock()
+	defer s.cfgMu.RUnlock()
+	writeJSON(w, map[string]interface{}{
+		"path":        s.cfgPath,
+		"watching":    s.cfgPath != "",
+		"last_reload": s.lastReload,
+	})
+}
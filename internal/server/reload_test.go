@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReloadAppliesLiveChanges(t *testing.T) {
+	s, path := newConfigServer(t, "version: 2\nport: 8080\n")
+
+	os.WriteFile(path, []byte("version: 2\nport: 9090\nai:\n    llama_host: 10.0.0.6:8085\n    voice_host: 10.0.0.7:8088\n"), 0600)
+	if err := s.Reload(ReloadSignal); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	if s.llama().Host != "10.0.0.6:8085" || s.config().AI.VoiceHost != "10.0.0.7:8088" {
+		t.Errorf("AI hosts not reloaded: llama=%s voice=%s", s.llama().Host, s.config().AI.VoiceHost)
+	}
+	if s.config().Port != 8080 {
+		t.Error("port should only change after a restart")
+	}
+
+	st := s.lastReload
+	if !st.Success || st.Trigger != ReloadSignal || strings.Join(st.RestartRequired, ",") != "port" {
+		t.Errorf("unexpected status: %+v", st)
+	}
+
+	// Reloading the same file again changes nothing, even though the running
+	// port still differs from the file's
+	if err := s.Reload(ReloadSignal); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+	if st := s.lastReload; len(st.Changed) != 0 || len(st.RestartRequired) != 0 {
+		t.Errorf("an unchanged file should report no changes: %+v", st)
+	}
+}
+
+func TestReloadRejectsInvalid(t *testing.T) {
+	s, path := newConfigServer(t, "version: 2\nai:\n    rag_host: localhost:8093\n")
+
+	os.WriteFile(path, []byte("version: 2\nai:\n    rag_host: no-port\n"), 0600)
+	if err := s.Reload(ReloadFile); err == nil {
+		t.Fatal("expected an invalid config to be rejected")
+	}
+	if s.config().AI.RAGHost != "localhost:8093" {
+		t.Errorf("running config changed to %s", s.config().AI.RAGHost)
+	}
+
+	rec := httptest.NewRecorder()
+	s.handleConfigStatus(rec, httptest.NewRequest("GET", "/api/config/status", nil))
+	var resp struct {
+		LastReload *ReloadStatus `json:"last_reload"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if resp.LastReload == nil || resp.LastReload.Success || !strings.Contains(resp.LastReload.Error, "rag_host") {
+		t.Errorf("status should report the rejected reload: %s", rec.Body.String())
+	}
+}
+
+func TestWatchConfig(t *testing.T) {
+	old := configPollInterval
+	configPollInterval = 20 * time.Millisecond
+	defer func() { configPollInterval = old }()
+
+	s, path := newConfigServer(t, "version: 2\n")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.watchConfig(ctx)
+		close(done)
+	}()
+	// Let any in-flight reload finish before the temp HOME is removed
+	defer func() {
+		cancel()
+		<-done
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	os.WriteFile(path, []byte("version: 2\nai:\n    agent_host: 10.0.0.9:8095\n"), 0600)
+	deadline := time.Now().Add(2 * time.Second)
+	for s.config().AI.AgentHost != "10.0.0.9:8095" {
+		if time.Now().After(deadline) {
+			t.Fatal("watcher did not reload the changed file")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
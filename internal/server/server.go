@@ -2,48 +2,109 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Achilles1089/sovereign-stack/internal/ai"
 	"github.com/Achilles1089/sovereign-stack/internal/apps"
+	"github.com/Achilles1089/sovereign-stack/internal/audit"
 	"github.com/Achilles1089/sovereign-stack/internal/config"
 	"github.com/Achilles1089/sovereign-stack/internal/docker"
 	"github.com/Achilles1089/sovereign-stack/internal/hardware"
+	"github.com/Achilles1089/sovereign-stack/internal/rbac"
+	"github.com/Achilles1089/sovereign-stack/internal/runner"
 )
 
 // Server is the Sovereign Stack API + dashboard server
 type Server struct {
-	cfg       *config.Config
-	client    *ai.Client
+	// cfgMu guards the config and the values derived from it. A *config.Config
+	// is never modified once published; changes swap in a new one.
+	cfgMu      sync.RWMutex
+	cfgWriteMu sync.Mutex // serialises config file updates
+	cfg        *config.Config
+	client     *ai.Client
+	audit      *audit.Logger
+
+	// Where cfg came from, so PATCH /api/config writes the file layer only
+	// and reloads keep the same overrides
+	cfgPath    string
+	origins    map[string]config.Origin
+	resolve    func() (*config.Effective, error)
+	cfgStamp   fileStamp
+	resolved   *config.Config // last config resolved from the file; reloads diff against it
+	lastReload *ReloadStatus
+
 	addr      string
 	staticDir string
+
+	run runner.Runner // runs df, ssh, adb and crontab; runner.Default if nil
 }
 
 // New creates a new dashboard server
 func New(cfg *config.Config, addr string) *Server {
-	host := cfg.AI.Host
-	if host == "" {
-		host = "localhost:8085"
-	}
 	return &Server{
 		cfg:    cfg,
-		client: ai.NewClient(host),
+		client: ai.NewClientFromConfig(cfg),
+		audit:  audit.NewLoggerFromConfig(cfg),
 		addr:   addr,
 	}
 }
 
+// SetConfigSource records the config file and the origin of each effective
+// value. Without it the config API is read-only.
+func (s *Server) SetConfigSource(path string, origins map[string]config.Origin) {
+	s.cfgMu.Lock()
+	defer s.cfgMu.Unlock()
+	s.cfgPath = path
+	s.origins = origins
+}
+
+// runner returns the runner for external commands
+func (s *Server) runner() runner.Runner {
+	if s.run == nil {
+		return runner.Default
+	}
+	return s.run
+}
+
+// output runs a command with a timeout and returns its stdout
+func (s *Server) output(timeout time.Duration, name string, args ...string) ([]byte, error) {
+	res, err := s.runner().Run(context.Background(), runner.Command{Name: name, Args: args, Timeout: timeout})
+	if err != nil {
+		return nil, err
+	}
+	return res.Stdout, nil
+}
+
+// config returns the current config. Callers must not modify it.
+func (s *Server) config() *config.Config {
+	s.cfgMu.RLock()
+	defer s.cfgMu.RUnlock()
+	return s.cfg
+}
+
+// llama returns the llama-server client for the current config
+func (s *Server) llama() *ai.Client {
+	s.cfgMu.RLock()
+	defer s.cfgMu.RUnlock()
+	return s.client
+}
+
 // SetStaticDir sets the path to the built dashboard frontend
 func (s *Server) SetStaticDir(dir string) {
 	s.staticDir = dir
@@ -52,6 +113,52 @@ func (s *Server) SetStaticDir(dir string) {
 // Start begins serving
 func (s *Server) Start() error {
 	mux := http.NewServeMux()
+	s.routes(mux)
+
+	// Serve static dashboard files (SPA fallback)
+	if s.staticDir != "" {
+		mux.Handle("/", spaHandler(s.staticDir))
+	} else {
+		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]string{
+				"name":    "Sovereign Stack API",
+				"version": "0.1.0",
+				"status":  "running",
+			})
+		})
+	}
+
+	fmt.Printf("  [WEB] Dashboard: http://%s\n", s.addr)
+	fmt.Printf("  [API] API:       http://%s/api/\n", s.addr)
+
+	// Warm models in background — primes LLM, image gen pipeline, and TTS
+	go s.warmModels()
+
+	// Pick up edits to config.yaml and SIGHUP without a restart
+	go s.watchConfig(context.Background())
+
+	return http.ListenAndServe(s.addr, s.corsMiddleware(s.authMiddleware(mux)))
+}
+
+// apiMux is where routes registers the API; an *http.ServeMux in Start
+type apiMux interface {
+	HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request))
+}
+
+// routes registers the API handlers. Each route needs an entry in
+// routePermissions, publicRoutes or authenticatedRoutes.
+func (s *Server) routes(mux apiMux) {
+	// Auth routes
+	mux.HandleFunc("/api/auth/login", s.handleLogin)
+	mux.HandleFunc("/api/auth/logout", s.handleLogout)
+	mux.HandleFunc("/api/auth/me", s.handleMe)
+	mux.HandleFunc("/api/tokens", s.handleTokens)
+	mux.HandleFunc("/api/tokens/revoke", s.handleTokenRevoke)
+	mux.HandleFunc("/api/audit", s.handleAudit)
+	mux.HandleFunc("/api/audit/sinks", s.handleAuditSinks)
+	mux.HandleFunc("/api/config", s.handleConfig)
+	mux.HandleFunc("/api/config/status", s.handleConfigStatus)
 
 	// API routes
 	mux.HandleFunc("/api/status", s.handleStatus)
@@ -97,28 +204,6 @@ func (s *Server) Start() error {
 	mux.HandleFunc("/api/agent/chat", s.handleAgentChat)
 	mux.HandleFunc("/api/agent/status", s.handleAgentStatus)
 	mux.HandleFunc("/api/agent/clear", s.handleAgentClear)
-
-	// Serve static dashboard files (SPA fallback)
-	if s.staticDir != "" {
-		mux.Handle("/", spaHandler(s.staticDir))
-	} else {
-		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(map[string]string{
-				"name":    "Sovereign Stack API",
-				"version": "0.1.0",
-				"status":  "running",
-			})
-		})
-	}
-
-	fmt.Printf("  [WEB] Dashboard: http://%s\n", s.addr)
-	fmt.Printf("  [API] API:       http://%s/api/\n", s.addr)
-
-	// Warm models in background — primes LLM, image gen pipeline, and TTS
-	go s.warmModels()
-
-	return http.ListenAndServe(s.addr, corsMiddleware(mux))
 }
 
 // warmModels sends tiny requests to each inference service to prime their pipelines.
@@ -140,7 +225,7 @@ func (s *Server) warmModels() {
 	}
 
 	// 2. Warm image gen on Envy (compile OpenVINO pipeline)
-	imageHost := s.cfg.AI.ImageGenHost
+	imageHost := s.config().AI.ImageGenHost
 	if imageHost != "" {
 		fmt.Printf("[warm] Warming image gen at %s...\n", imageHost)
 		imgBody := `{"prompt":"warmup","width":256,"height":256,"steps":1}`
@@ -155,7 +240,7 @@ func (s *Server) warmModels() {
 	}
 
 	// 3. Warm TTS (load piper ONNX model)
-	voiceHost := s.cfg.AI.VoiceHost
+	voiceHost := s.config().AI.VoiceHost
 	if voiceHost != "" {
 		fmt.Printf("[warm] Warming TTS at %s...\n", voiceHost)
 		ttsBody := `{"text":"ready"}`
@@ -172,11 +257,16 @@ func (s *Server) warmModels() {
 	fmt.Println("[warm] Model warming complete")
 }
 
-func corsMiddleware(next http.Handler) http.Handler {
+// corsMiddleware lets browsers on dashboard.allowed_origins call the API.
+// The dashboard is served from the same origin and needs no CORS headers.
+func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Add("Vary", "Origin")
+		if origin := r.Header.Get("Origin"); origin != "" && s.allowedOrigin(origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		}
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(204)
 			return
@@ -185,6 +275,12 @@ func corsMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// allowedOrigin reports whether origin is listed in dashboard.allowed_origins
+func (s *Server) allowedOrigin(origin string) bool {
+	cfg := s.config()
+	return cfg != nil && slices.Contains(cfg.Dashboard.AllowedOrigins, origin)
+}
+
 // handleResourcesLive returns real-time system stats for Brain Net
 func (s *Server) handleResourcesLive(w http.ResponseWriter, r *http.Request) {
 	result := map[string]interface{}{}
@@ -242,7 +338,7 @@ func (s *Server) handleResourcesLive(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Disk usage for /
-	out, err := exec.Command("df", "-B1", "/").Output()
+	out, err := s.output(5*time.Second, "df", "-B1", "/")
 	if err == nil {
 		lines := strings.Split(string(out), "\n")
 		if len(lines) >= 2 {
@@ -264,7 +360,7 @@ func (s *Server) handleResourcesLive(w http.ResponseWriter, r *http.Request) {
 // handleEnvySysinfo proxies the Envy's sysinfo server
 func (s *Server) handleEnvySysinfo(w http.ResponseWriter, r *http.Request) {
 	// Envy sysinfo server runs on port 8092 at the same host as image gen
-	imageHost := s.cfg.AI.ImageGenHost
+	imageHost := s.config().AI.ImageGenHost
 	if imageHost == "" {
 		writeJSON(w, map[string]interface{}{"online": false})
 		return
@@ -292,7 +388,6 @@ func (s *Server) handleEnvySysinfo(w http.ResponseWriter, r *http.Request) {
 
 func writeJSON(w http.ResponseWriter, v interface{}) {
 	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 	json.NewEncoder(w).Encode(v)
 }
 
@@ -318,12 +413,16 @@ func (s *Server) handleResources(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	// Fallback to local detection
-	hw := &s.cfg.Hardware
+	hw := &s.config().Hardware
 	if hw.CPUCores == 0 || hw.RAMTotalMB == 0 {
 		detected, err := hardware.Detect()
 		if err == nil {
 			hw = detected
-			s.cfg.Hardware = *detected
+			s.cfgMu.Lock()
+			next := *s.cfg
+			next.Hardware = *detected
+			s.cfg = &next
+			s.cfgMu.Unlock()
 		}
 	}
 	writeJSON(w, map[string]interface{}{
@@ -339,10 +438,9 @@ func (s *Server) handleResources(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) detectRemoteHardware() map[string]interface{} {
-	cmd := exec.Command("ssh", "-p", "2222", "-o", "ConnectTimeout=2", "-o", "StrictHostKeyChecking=no",
+	out, err := s.output(10*time.Second, "ssh", "-p", "2222", "-o", "ConnectTimeout=2", "-o", "StrictHostKeyChecking=no",
 		"achilles1089@localhost",
 		`cat /proc/cpuinfo | grep 'model name' | head -1 | cut -d: -f2 | xargs && nproc && free -m | grep Mem | awk '{print $2, $7}' && df -BG / | tail -1 | awk '{print $2, $4}' | tr -d G`)
-	out, err := cmd.Output()
 	if err != nil {
 		return nil
 	}
@@ -389,18 +487,26 @@ func (s *Server) handleApps(w http.ResponseWriter, r *http.Request) {
 		Category    string `json:"category"`
 		Version     string `json:"version"`
 		Installed   bool   `json:"installed"`
+		URL         string `json:"url,omitempty"` // web UI of an installed app
 	}
 
+	cfg := s.config()
 	var result []appResponse
 	for _, app := range apps.BuiltinApps {
-		result = append(result, appResponse{
+		resp := appResponse{
 			Name:        app.Name,
 			DisplayName: app.DisplayName,
 			Description: app.Description,
 			Category:    app.Category,
 			Version:     app.Version,
 			Installed:   installedMap[app.Name],
-		})
+		}
+		if resp.Installed {
+			if state, err := apps.LoadState(app.Name); err == nil {
+				resp.URL = state.URL(cfg)
+			}
+		}
+		result = append(result, resp)
 	}
 
 	writeJSON(w, map[string]interface{}{"apps": result})
@@ -412,7 +518,9 @@ func (s *Server) handleAppInstall(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var req struct {
-		Name string `json:"name"`
+		Name          string `json:"name"`
+		DryRun        bool   `json:"dry_run"`        // return the compose plan without installing
+		AllocatePorts bool   `json:"allocate_ports"` // move taken host ports into apps.port_range
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeJSON(w, map[string]interface{}{"error": "invalid request"})
@@ -423,11 +531,118 @@ func (s *Server) handleAppInstall(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, map[string]interface{}{"error": "app not found"})
 		return
 	}
-	if err := apps.InstallApp(app); err != nil {
+	// The apps it needs are installed first
+	order, err := apps.InstallOrder(app)
+	if err != nil {
 		writeJSON(w, map[string]interface{}{"error": err.Error()})
 		return
 	}
-	writeJSON(w, map[string]interface{}{"ok": true, "message": fmt.Sprintf("%s installed successfully", app.DisplayName)})
+	for _, a := range order {
+		if !callerCanOn(r, rbac.PermAppInstall, rbac.AppResource(a.Name, a.Category)) {
+			writeError(w, http.StatusForbidden, "permission denied")
+			return
+		}
+	}
+	// A copy, since the install enables required services in it
+	cfg := *s.config()
+	if req.AllocatePorts || cfg.Apps.AllocatePorts {
+		for i := range order {
+			if order[i], err = apps.AllocatePorts(&cfg, order[i], order[:i]...); err != nil {
+				writeJSON(w, map[string]interface{}{"error": err.Error()})
+				return
+			}
+		}
+	}
+	app = order[len(order)-1]
+	deps := order[:len(order)-1]
+
+	plan, err := apps.PlanInstall(&cfg, app)
+	if err != nil || req.DryRun {
+		resp := planResponse(plan, apps.RunPreflight(&cfg, app, deps...), err)
+		resp["ports"] = app.PortMappings()
+		resp["install"] = apps.Names(order)
+		writeJSON(w, resp)
+		return
+	}
+	pres, err := apps.InstallAll(&cfg, order)
+	// Record the services enabled before any failure, too
+	var enabled []string
+	for _, pre := range pres {
+		for _, name := range pre.Enable {
+			v, _ := config.Get(&cfg, "services."+name)
+			if on, _ := v.(bool); on {
+				enabled = append(enabled, name)
+			}
+		}
+	}
+	if len(enabled) > 0 {
+		if saveErr := s.enableServices(enabled); err == nil {
+			err = saveErr
+		}
+	}
+	installed, _ := apps.InstalledApps()
+	for _, a := range order {
+		logErr := err
+		if a != app && slices.Contains(installed, a.Name) {
+			logErr = nil
+		}
+		s.audit.LogAppInstall(s.actor(r), a.Name, logErr)
+	}
+	pre := pres[len(pres)-1]
+	if err != nil {
+		writeJSON(w, map[string]interface{}{"error": err.Error(), "preflight": pre, "dependencies": pres[:len(pres)-1]})
+		return
+	}
+	resp := map[string]interface{}{
+		"ok":           true,
+		"message":      fmt.Sprintf("%s installed successfully", app.DisplayName),
+		"plan":         plan,
+		"preflight":    pre,
+		"dependencies": pres[:len(pres)-1], // preflights of the apps installed first
+		"installed":    apps.Names(order),
+		"ports":        app.PortMappings(),
+	}
+	if state, err := apps.LoadState(app.Name); err == nil && state.URL(&cfg) != "" {
+		resp["url"] = state.URL(&cfg)
+	}
+	writeJSON(w, resp)
+}
+
+// enableServices records core services an app install enabled in the
+// config file and the running config
+func (s *Server) enableServices(names []string) error {
+	// PATCH and reloads rewrite the file too; one writer at a time
+	s.cfgWriteMu.Lock()
+	defer s.cfgWriteMu.Unlock()
+
+	s.cfgMu.RLock()
+	path := s.cfgPath
+	s.cfgMu.RUnlock()
+
+	file, err := config.Load(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		file, err = config.DefaultConfig(), nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		config.SetValue(file, "services."+name, true)
+	}
+	if err := file.Save(path); err != nil {
+		return err
+	}
+
+	s.cfgMu.Lock()
+	defer s.cfgMu.Unlock()
+	s.cfgStamp = stampOf(path) // our own write; the watcher need not reload it
+	next, resolved := *s.cfg, *s.fileConfig()
+	for _, name := range names {
+		config.SetValue(&next, "services."+name, true)
+		config.SetValue(&resolved, "services."+name, true)
+	}
+	s.cfg, s.resolved = &next, &resolved
+	return nil
 }
 
 func (s *Server) handleAppRemove(w http.ResponseWriter, r *http.Request) {
@@ -436,21 +651,65 @@ func (s *Server) handleAppRemove(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var req struct {
-		Name string `json:"name"`
+		Name    string `json:"name"`
+		DryRun  bool   `json:"dry_run"` // return the compose plan without removing
+		Cascade bool   `json:"cascade"` // also remove the installed apps that need it
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeJSON(w, map[string]interface{}{"error": "invalid request"})
 		return
 	}
-	if err := apps.RemoveApp(req.Name); err != nil {
+	if !callerCanOn(r, rbac.PermAppRemove, appResource(req.Name)) {
+		writeError(w, http.StatusForbidden, "permission denied")
+		return
+	}
+	// Apps that need it go first, and only with cascade
+	order, err := apps.RemoveOrder(req.Name, req.Cascade)
+	if err != nil {
 		writeJSON(w, map[string]interface{}{"error": err.Error()})
 		return
 	}
-	writeJSON(w, map[string]interface{}{"ok": true, "message": fmt.Sprintf("%s removed successfully", req.Name)})
+	for _, name := range order {
+		if !callerCanOn(r, rbac.PermAppRemove, appResource(name)) {
+			writeError(w, http.StatusForbidden, "permission denied")
+			return
+		}
+	}
+	plan, err := apps.PlanRemove(req.Name)
+	if err != nil || req.DryRun {
+		resp := planResponse(plan, nil, err)
+		resp["remove"] = order
+		writeJSON(w, resp)
+		return
+	}
+	var removed []string
+	for _, name := range order {
+		err = apps.RemoveApp(name)
+		s.audit.LogAppRemove(s.actor(r), name, err)
+		if err != nil {
+			writeJSON(w, map[string]interface{}{"error": err.Error(), "removed": removed})
+			return
+		}
+		removed = append(removed, name)
+	}
+	writeJSON(w, map[string]interface{}{"ok": true, "message": fmt.Sprintf("%s removed successfully", req.Name), "plan": plan, "removed": removed})
+}
+
+// planResponse answers a dry run with the compose changes it would make
+// and, for installs, the preflight checks
+func planResponse(plan *docker.Plan, pre *apps.Preflight, err error) map[string]interface{} {
+	resp := map[string]interface{}{"ok": true, "dry_run": true, "plan": plan}
+	if err != nil {
+		resp = map[string]interface{}{"error": err.Error()}
+	}
+	if pre != nil {
+		resp["preflight"] = pre
+	}
+	return resp
 }
 
 func (s *Server) handleAIModels(w http.ResponseWriter, r *http.Request) {
-	models, err := s.client.ListModels()
+	models, err := s.llama().ListModels()
 	if err != nil {
 		writeJSON(w, map[string]interface{}{"models": []interface{}{}, "error": err.Error()})
 		return
@@ -460,7 +719,7 @@ func (s *Server) handleAIModels(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleAICatalog(w http.ResponseWriter, r *http.Request) {
 	// Return all available models from the catalog (not just installed)
-	installed, _ := s.client.ListModels()
+	installed, _ := s.llama().ListModels()
 	installedMap := make(map[string]bool)
 	for _, m := range installed {
 		installedMap[m.Name] = true
@@ -482,7 +741,7 @@ func (s *Server) handleAICatalog(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleAIStatus(w http.ResponseWriter, r *http.Request) {
-	tier := hardware.GetGPUTier(&s.cfg.Hardware)
+	tier := hardware.GetGPUTier(&s.config().Hardware)
 	tierNames := map[hardware.GPUTier]string{
 		hardware.GPUTierNone:  "cpu",
 		hardware.GPUTierBasic: "basic",
@@ -492,14 +751,14 @@ func (s *Server) handleAIStatus(w http.ResponseWriter, r *http.Request) {
 		hardware.GPUTierApex:  "apex",
 	}
 	writeJSON(w, map[string]interface{}{
-		"running":     s.client.IsRunning(),
-		"host":        s.client.Host,
+		"running":     s.llama().IsRunning(),
+		"host":        s.llama().Host,
 		"mode":        "native",
-		"model":       s.client.ActiveModel(),
+		"model":       s.llama().ActiveModel(),
 		"gpu_tier":    tierNames[tier],
-		"recommended": hardware.RecommendedModel(&s.cfg.Hardware),
+		"recommended": hardware.RecommendedModel(&s.config().Hardware),
 		"engine":      "llama-server",
-		"models_dir":  s.client.ModelsDir,
+		"models_dir":  s.llama().ModelsDir,
 	})
 }
 
@@ -519,7 +778,7 @@ func (s *Server) handleAIChat(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if req.Model == "" {
-		req.Model = s.cfg.AI.DefaultModel
+		req.Model = s.config().AI.DefaultModel
 	}
 
 	// Stream the response — anti-buffering headers are critical for Caddy/proxy
@@ -529,7 +788,7 @@ func (s *Server) handleAIChat(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Connection", "keep-alive")
 	flusher, ok := w.(http.Flusher)
 
-	err := s.client.Chat(req.Model, req.Messages, func(content string, done bool) {
+	err := s.llama().Chat(req.Model, req.Messages, func(content string, done bool) {
 		fmt.Fprint(w, content)
 		if ok {
 			flusher.Flush()
@@ -558,11 +817,11 @@ func (s *Server) handleServerChat(w http.ResponseWriter, r *http.Request) {
 
 	model := req.Model
 	if model == "" {
-		model = s.cfg.AI.DefaultModel
+		model = s.config().AI.DefaultModel
 	}
 
 	// Build live server context
-	ctx := ai.BuildServerContext(s.cfg)
+	ctx := ai.BuildServerContext(s.config())
 	systemPrompt := ai.SystemPrompt(ctx)
 
 	// Construct messages with system prompt
@@ -578,7 +837,7 @@ func (s *Server) handleServerChat(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Connection", "keep-alive")
 	flusher, ok := w.(http.Flusher)
 
-	err := s.client.Chat(model, messages, func(content string, done bool) {
+	err := s.llama().Chat(model, messages, func(content string, done bool) {
 		fmt.Fprint(w, content)
 		if ok {
 			flusher.Flush()
@@ -609,7 +868,7 @@ func (s *Server) handleAIPull(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Transfer-Encoding", "chunked")
 	flusher, ok := w.(http.Flusher)
 
-	err := s.client.PullModel(req.Model, func(status string, completed, total int64) {
+	err := s.llama().PullModel(req.Model, func(status string, completed, total int64) {
 		if total > 0 {
 			pct := float64(completed) / float64(total) * 100
 			fmt.Fprintf(w, "%s: %.0f%%\n", status, pct)
@@ -620,6 +879,7 @@ func (s *Server) handleAIPull(w http.ResponseWriter, r *http.Request) {
 			flusher.Flush()
 		}
 	})
+	s.audit.LogModelEvent(s.actor(r), "pull", req.Model, err)
 
 	if err != nil {
 		fmt.Fprintf(w, "ERROR: %s\n", err.Error())
@@ -642,7 +902,8 @@ func (s *Server) handleAIDelete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := s.client.DeleteModel(req.Model)
+	err := s.llama().DeleteModel(req.Model)
+	s.audit.LogModelEvent(s.actor(r), "delete", req.Model, err)
 	if err != nil {
 		writeJSON(w, map[string]interface{}{"error": err.Error()})
 		return
@@ -664,7 +925,8 @@ func (s *Server) handleAISwitch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := s.client.SwitchModel(req.Model)
+	err := s.llama().SwitchModel(req.Model)
+	s.audit.LogModelEvent(s.actor(r), "switch", req.Model, err)
 	if err != nil {
 		writeJSON(w, map[string]interface{}{"error": err.Error()})
 		return
@@ -675,7 +937,6 @@ func (s *Server) handleAISwitch(w http.ResponseWriter, r *http.Request) {
 // handleImageGenerate proxies image generation requests to the Envy's sd_server
 func (s *Server) handleImageGenerate(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "OPTIONS" {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 		w.WriteHeader(200)
@@ -686,7 +947,7 @@ func (s *Server) handleImageGenerate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	imageHost := s.cfg.AI.ImageGenHost
+	imageHost := s.config().AI.ImageGenHost
 	if imageHost == "" {
 		writeJSON(w, map[string]interface{}{"error": "image_gen_host not configured"})
 		return
@@ -736,7 +997,7 @@ func (s *Server) handleImageGenerate(w http.ResponseWriter, r *http.Request) {
 
 // handleImageStatus checks if the Envy image gen node is online
 func (s *Server) handleImageStatus(w http.ResponseWriter, r *http.Request) {
-	imageHost := s.cfg.AI.ImageGenHost
+	imageHost := s.config().AI.ImageGenHost
 	if imageHost == "" {
 		writeJSON(w, map[string]interface{}{"online": false, "model": ""})
 		return
@@ -886,14 +1147,12 @@ func (s *Server) handlePhoneModels(w http.ResponseWriter, r *http.Request) {
 
 	body, _ := io.ReadAll(resp.Body)
 	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Write(body)
 }
 
 // handlePhoneSwitch switches the active model on the phone by restarting llama-server
 func (s *Server) handlePhoneSwitch(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "OPTIONS" {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 		w.WriteHeader(200)
@@ -906,6 +1165,11 @@ func (s *Server) handlePhoneSwitch(w http.ResponseWriter, r *http.Request) {
 
 	// ADB forward maps localhost:8086 → phone:8086
 	body, _ := io.ReadAll(r.Body)
+	var target struct {
+		Model string `json:"model"`
+	}
+	json.Unmarshal(body, &target)
+
 	client := &http.Client{Timeout: 10 * time.Second}
 	req, err := http.NewRequest("POST", "http://127.0.0.1:8086/switch", strings.NewReader(string(body)))
 	if err != nil {
@@ -916,21 +1180,26 @@ func (s *Server) handlePhoneSwitch(w http.ResponseWriter, r *http.Request) {
 
 	resp, err := client.Do(req)
 	if err != nil {
+		s.audit.LogPhoneEvent(s.actor(r), "switch", target.Model, fmt.Errorf("sysinfo companion not reachable: %w", err))
 		writeJSON(w, map[string]interface{}{"ok": false, "error": "sysinfo companion not reachable"})
 		return
 	}
 	defer resp.Body.Close()
 
+	var switchErr error
+	if resp.StatusCode >= 400 {
+		switchErr = fmt.Errorf("phone returned %s", resp.Status)
+	}
+	s.audit.LogPhoneEvent(s.actor(r), "switch", target.Model, switchErr)
+
 	respBody, _ := io.ReadAll(resp.Body)
 	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Write(respBody)
 }
 
 // handlePhoneStart uses ADB to start llama-server and sysinfo on the USB-connected phone
 func (s *Server) handlePhoneStart(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "OPTIONS" {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 		w.WriteHeader(200)
@@ -953,15 +1222,24 @@ func (s *Server) handlePhoneStart(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Step 1: Check ADB device
-	out, err := exec.Command("adb", "devices").CombinedOutput()
-	if err != nil || !strings.Contains(string(out), "device") {
-		writeJSON(w, map[string]interface{}{"ok": false, "error": "No phone connected via USB", "detail": string(out)})
+	res, err := s.runner().Run(r.Context(), runner.Command{Name: "adb", Args: []string{"devices"}, Timeout: adbTimeout})
+	detail := ""
+	if res != nil {
+		detail = strings.TrimSpace(string(res.Stdout) + string(res.Stderr))
+	}
+	if err != nil || !adbDeviceAttached(string(res.Stdout)) {
+		s.audit.LogPhoneEvent(s.actor(r), "start", req.Model, fmt.Errorf("no phone connected via USB"))
+		writeJSON(w, map[string]interface{}{"ok": false, "error": "No phone connected via USB", "detail": detail})
 		return
 	}
 
 	// Step 2: Set up ADB port forwarding
-	exec.Command("adb", "forward", "tcp:8085", "tcp:8085").Run()
-	exec.Command("adb", "forward", "tcp:8086", "tcp:8086").Run()
+	for _, port := range []string{"tcp:8085", "tcp:8086"} {
+		if err := s.adb(r, "forward", port, port); err != nil {
+			s.phoneStartFailed(w, r, req.Model, fmt.Errorf("port forwarding failed: %w", err))
+			return
+		}
+	}
 
 	// Step 3: Start sysinfo_server.py
 	sysinfoCmd := `export PATH=/data/data/com.termux/files/usr/bin:$PATH; ` +
@@ -970,7 +1248,10 @@ func (s *Server) handlePhoneStart(w http.ResponseWriter, r *http.Request) {
 		`export TMPDIR=$HOME/tmp; mkdir -p $TMPDIR; ` +
 		`pkill -f sysinfo_server 2>/dev/null; sleep 1; ` +
 		`nohup python3 $HOME/sysinfo_server.py > /dev/null 2>&1 &`
-	exec.Command("adb", "shell", "run-as", "com.termux", "sh", "-c", sysinfoCmd).Run()
+	if err := s.adb(r, "shell", "run-as", "com.termux", "sh", "-c", sysinfoCmd); err != nil {
+		s.phoneStartFailed(w, r, req.Model, fmt.Errorf("failed to start sysinfo: %w", err))
+		return
+	}
 
 	// Step 4: Start llama-server with optimized flags for T616 (2x A75 big + 6x A55 little)
 	// Pinned to big cores 6,7 via taskset. KV cache quantized to q8_0 for 50% memory savings.
@@ -988,11 +1269,42 @@ func (s *Server) handlePhoneStart(w http.ResponseWriter, r *http.Request) {
 			`> /dev/null 2>&1 &`,
 		req.Model,
 	)
-	exec.Command("adb", "shell", "run-as", "com.termux", "sh", "-c", llamaCmd).Run()
+	if err := s.adb(r, "shell", "run-as", "com.termux", "sh", "-c", llamaCmd); err != nil {
+		s.phoneStartFailed(w, r, req.Model, fmt.Errorf("failed to start llama-server: %w", err))
+		return
+	}
+	s.audit.LogPhoneEvent(s.actor(r), "start", req.Model, nil)
 
 	writeJSON(w, map[string]interface{}{"ok": true, "model": req.Model, "status": "starting"})
 }
 
+// phoneStartFailed audits a failed phone start and reports it to the caller
+func (s *Server) phoneStartFailed(w http.ResponseWriter, r *http.Request, model string, err error) {
+	s.audit.LogPhoneEvent(s.actor(r), "start", model, err)
+	writeJSON(w, map[string]interface{}{"ok": false, "error": err.Error(), "model": model})
+}
+
+// adbTimeout bounds each adb call so a hung device cannot stall the handler
+const adbTimeout = 15 * time.Second
+
+// adb runs an adb command for a request
+func (s *Server) adb(r *http.Request, args ...string) error {
+	_, err := s.runner().Run(r.Context(), runner.Command{Name: "adb", Args: args, Timeout: adbTimeout})
+	return err
+}
+
+// adbDeviceAttached reports whether `adb devices` lists a device that is
+// ready, skipping the "List of devices attached" header
+func adbDeviceAttached(out string) bool {
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[1] == "device" {
+			return true
+		}
+	}
+	return false
+}
+
 // deriveModelDisplayName converts a GGUF filename/model ID into a readable display name
 // Examples: "rwkv7-0.4B-world-q8_0.gguf" → "RWKV-7 0.4B"
 //
@@ -1057,7 +1369,7 @@ func (s *Server) handleTranscribe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	voiceHost := s.cfg.AI.VoiceHost
+	voiceHost := s.config().AI.VoiceHost
 	if voiceHost == "" {
 		writeJSON(w, map[string]interface{}{"error": "voice_host not configured"})
 		return
@@ -1097,7 +1409,7 @@ func (s *Server) handleSpeak(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	voiceHost := s.cfg.AI.VoiceHost
+	voiceHost := s.config().AI.VoiceHost
 	if voiceHost == "" {
 		writeJSON(w, map[string]interface{}{"error": "voice_host not configured"})
 		return
@@ -1133,7 +1445,6 @@ func (s *Server) handleSpeak(w http.ResponseWriter, r *http.Request) {
 // Accepts raw audio blob, returns {transcript, response, audio}.
 func (s *Server) handleVoiceChat(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "OPTIONS" {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 		w.WriteHeader(200)
@@ -1144,7 +1455,7 @@ func (s *Server) handleVoiceChat(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	voiceHost := s.cfg.AI.VoiceHost
+	voiceHost := s.config().AI.VoiceHost
 	if voiceHost == "" {
 		writeJSON(w, map[string]interface{}{"error": "voice_host not configured"})
 		return
@@ -1194,7 +1505,7 @@ func (s *Server) handleVoiceChat(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var llmResponse strings.Builder
-	err = s.client.Chat("", messages, func(content string, done bool) {
+	err = s.llama().Chat("", messages, func(content string, done bool) {
 		llmResponse.WriteString(content)
 	})
 	if err != nil {
@@ -1249,7 +1560,7 @@ func (s *Server) handleVoiceChat(w http.ResponseWriter, r *http.Request) {
 	})
 }
 func (s *Server) handleVoiceStatus(w http.ResponseWriter, r *http.Request) {
-	voiceHost := s.cfg.AI.VoiceHost
+	voiceHost := s.config().AI.VoiceHost
 	if voiceHost == "" {
 		writeJSON(w, map[string]interface{}{"stt_online": false, "tts_online": false})
 		return
@@ -1277,7 +1588,7 @@ func (s *Server) handleMusicGenerate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	musicHost := s.cfg.AI.MusicGenHost
+	musicHost := s.config().AI.MusicGenHost
 	if musicHost == "" {
 		writeJSON(w, map[string]interface{}{"error": "music_gen_host not configured"})
 		return
@@ -1311,7 +1622,7 @@ func (s *Server) handleMusicGenerate(w http.ResponseWriter, r *http.Request) {
 
 // handleMusicStatus checks if the music gen server is online
 func (s *Server) handleMusicStatus(w http.ResponseWriter, r *http.Request) {
-	musicHost := s.cfg.AI.MusicGenHost
+	musicHost := s.config().AI.MusicGenHost
 	if musicHost == "" {
 		writeJSON(w, map[string]interface{}{"online": false})
 		return
@@ -1334,14 +1645,13 @@ func (s *Server) handleMusicStatus(w http.ResponseWriter, r *http.Request) {
 // Maps: rag-upload→/upload, rag-search→/search, rag-documents→/documents, rag-delete→/document, rag-status→/status
 func (s *Server) handleRAGProxy(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "OPTIONS" {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 		w.WriteHeader(200)
 		return
 	}
 
-	ragHost := s.cfg.AI.RAGHost
+	ragHost := s.config().AI.RAGHost
 	if ragHost == "" {
 		ragHost = "localhost:8093"
 	}
@@ -1390,16 +1700,34 @@ func (s *Server) handleRAGProxy(w http.ResponseWriter, r *http.Request) {
 
 	resp, err := client.Do(proxyReq)
 	if err != nil {
+		s.auditRAG(r, targetPath, err)
 		writeJSON(w, map[string]interface{}{"error": fmt.Sprintf("RAG server unreachable: %s", err.Error())})
 		return
 	}
 	defer resp.Body.Close()
 
+	var ragErr error
+	if resp.StatusCode >= 400 {
+		ragErr = fmt.Errorf("RAG server returned %s", resp.Status)
+	}
+	s.auditRAG(r, targetPath, ragErr)
+
 	respBody, _ := io.ReadAll(resp.Body)
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(respBody)
 }
 
+// auditRAG records document uploads and deletions; searches and reads are not audited
+func (s *Server) auditRAG(r *http.Request, targetPath string, err error) {
+	switch targetPath {
+	case "/upload":
+		s.audit.Record(s.actor(r), "rag.upload", "rag/documents", "Uploaded document to RAG index", err)
+	case "/document":
+		name := r.URL.Query().Get("name")
+		s.audit.Record(s.actor(r), "rag.delete", "rag/"+name, fmt.Sprintf("Deleted RAG document %s", name), err)
+	}
+}
+
 // ─── Gallery ────────────────────────────────────────────────────────────────
 
 const galleryDir = "/home/achilles1089/gallery"
@@ -1443,7 +1771,6 @@ func (s *Server) saveToGallery(imageB64, prompt string, width, height int) {
 
 // handleGalleryList returns metadata for all gallery images, newest first.
 func (s *Server) handleGalleryList(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 	entries, err := os.ReadDir(galleryDir)
 	if err != nil {
 		writeJSON(w, map[string]interface{}{"images": []interface{}{}})
@@ -1484,7 +1811,6 @@ func (s *Server) handleGalleryList(w http.ResponseWriter, r *http.Request) {
 
 // handleGalleryImage serves a gallery image file directly.
 func (s *Server) handleGalleryImage(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 	id := strings.TrimPrefix(r.URL.Path, "/api/gallery/image/")
 	if id == "" {
 		http.Error(w, "id required", 400)
@@ -1504,7 +1830,6 @@ func (s *Server) handleGalleryImage(w http.ResponseWriter, r *http.Request) {
 
 // handleGalleryDelete removes a gallery image and its metadata.
 func (s *Server) handleGalleryDelete(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 	if r.Method == "OPTIONS" {
 		w.Header().Set("Access-Control-Allow-Methods", "DELETE, POST, OPTIONS")
 		w.WriteHeader(200)
@@ -1512,20 +1837,20 @@ func (s *Server) handleGalleryDelete(w http.ResponseWriter, r *http.Request) {
 	}
 
 	id := strings.TrimPrefix(r.URL.Path, "/api/gallery/delete/")
-	if id == "" {
+	if id == "" || id != filepath.Base(id) {
 		writeJSON(w, map[string]interface{}{"error": "id required"})
 		return
 	}
 
-	os.Remove(filepath.Join(galleryDir, id+".png"))
+	err := os.Remove(filepath.Join(galleryDir, id+".png"))
 	os.Remove(filepath.Join(galleryDir, id+".json"))
+	s.audit.Record(s.actor(r), "gallery.delete", "gallery/"+id, fmt.Sprintf("Deleted gallery image %s", id), err)
 	writeJSON(w, map[string]interface{}{"deleted": id})
 }
 
 // handleNewsProxy proxies /api/news/* to the local rss_server.
 func (s *Server) handleNewsProxy(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "OPTIONS" {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 		w.WriteHeader(200)
@@ -1580,7 +1905,6 @@ func (s *Server) handleNewsProxy(w http.ResponseWriter, r *http.Request) {
 // handleAgentChat proxies chat requests to the Achilles agent daemon and streams the response.
 func (s *Server) handleAgentChat(w http.ResponseWriter, r *http.Request) {
 	if r.Method == "OPTIONS" {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 		w.WriteHeader(204)
@@ -1591,7 +1915,7 @@ func (s *Server) handleAgentChat(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	agentHost := s.cfg.AI.AgentHost
+	agentHost := s.config().AI.AgentHost
 	if agentHost == "" {
 		agentHost = "localhost:8095"
 	}
@@ -1643,7 +1967,7 @@ func (s *Server) handleAgentChat(w http.ResponseWriter, r *http.Request) {
 
 // handleAgentStatus returns the agent daemon's health status.
 func (s *Server) handleAgentStatus(w http.ResponseWriter, r *http.Request) {
-	agentHost := s.cfg.AI.AgentHost
+	agentHost := s.config().AI.AgentHost
 	if agentHost == "" {
 		agentHost = "localhost:8095"
 	}
@@ -1666,13 +1990,14 @@ func (s *Server) handleAgentStatus(w http.ResponseWriter, r *http.Request) {
 
 // handleAgentClear clears the agent's conversation memory.
 func (s *Server) handleAgentClear(w http.ResponseWriter, r *http.Request) {
-	agentHost := s.cfg.AI.AgentHost
+	agentHost := s.config().AI.AgentHost
 	if agentHost == "" {
 		agentHost = "localhost:8095"
 	}
 
 	client := &http.Client{Timeout: 3 * time.Second}
 	resp, err := client.Get(fmt.Sprintf("http://%s/clear", agentHost))
+	s.audit.Record(s.actor(r), "agent.clear", "agent/memory", "Cleared agent conversation memory", err)
 	if err != nil {
 		writeJSON(w, map[string]interface{}{"error": err.Error()})
 		return
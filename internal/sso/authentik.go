@@ -1,8 +1,6 @@
 package sso
 
 import (
-	"fmt"
-
 	"github.com/Achilles1089/sovereign-stack/internal/apps"
 	"github.com/Achilles1089/sovereign-stack/internal/config"
 	"github.com/Achilles1089/sovereign-stack/internal/docker"
@@ -25,7 +23,7 @@ func AuthentikApp() *apps.AppManifest {
 		Category:    "security",
 		Version:     "2024.12",
 		Website:     "https://goauthentik.io",
-		Requires:    apps.AppRequirements{Services: []string{"postgres"}, MinRAMMB: 1024},
+		Requires:    apps.AppRequirements{Services: []string{"postgres"}, Apps: []string{"redis"}, Database: "authentik", MinRAMMB: 1024},
 		Compose: apps.AppCompose{
 			Image: "ghcr.io/goauthentik/server:2024.12",
 			Ports: []string{"9443:9443", "9080:9000"},
@@ -36,10 +34,10 @@ func AuthentikApp() *apps.AppManifest {
 			Environment: []string{
 				"AUTHENTIK_REDIS__HOST=sovereign-redis",
 				"AUTHENTIK_POSTGRESQL__HOST=sovereign-postgres",
-				"AUTHENTIK_POSTGRESQL__USER=sovereign",
+				"AUTHENTIK_POSTGRESQL__USER=authentik",
 				"AUTHENTIK_POSTGRESQL__NAME=authentik",
-				"AUTHENTIK_POSTGRESQL__PASSWORD=sovereign",
-				"AUTHENTIK_SECRET_KEY=sovereign-secret-change-me",
+				"AUTHENTIK_POSTGRESQL__PASSWORD={{secret:db_password}}",
+				"AUTHENTIK_SECRET_KEY={{secret:secret_key:50}}",
 			},
 		},
 		CaddyRoute: &apps.CaddyRoute{Path: "/authentik", Port: 9080},
@@ -86,28 +84,18 @@ func GenerateOIDCConfig(appName string, authentikURL string) map[string]string {
 	return nil
 }
 
-// InstallAuthentik installs Authentik and configures Redis dependency
-func InstallAuthentik(cfg *config.Config) error {
-	composePath := config.ConfigDir() + "/docker-compose.yml"
+// PlanAuthentik returns the compose changes InstallAuthentik would make
+func PlanAuthentik(cfg *config.Config) (*docker.Plan, error) {
+	return apps.PlanInstall(cfg, AuthentikApp())
+}
 
-	compose, err := docker.LoadComposeFile(composePath)
+// InstallAuthentik installs Authentik after the Redis app it needs, unless
+// Redis is already installed. Core services enabled in cfg are listed in the
+// returned preflights' Enable; the caller saves cfg.
+func InstallAuthentik(cfg *config.Config) ([]*apps.Preflight, error) {
+	order, err := apps.InstallOrder(AuthentikApp())
 	if err != nil {
-		return fmt.Errorf("failed to load compose: %w", err)
+		return nil, err
 	}
-
-	// Add Redis if not present (Authentik requires it)
-	if _, exists := compose.Services["redis"]; !exists {
-		redis := &docker.ComposeService{
-			Image:         "redis:7-alpine",
-			ContainerName: "sovereign-redis",
-			Restart:       "unless-stopped",
-			Ports:         []string{"6379:6379"},
-			Volumes:       []string{"redis_data:/data"},
-		}
-		docker.AddAppToCompose(compose, "redis", redis)
-		compose.Volumes["redis_data"] = nil
-	}
-
-	// Install Authentik via the app installer
-	return apps.InstallApp(AuthentikApp())
+	return apps.InstallAll(cfg, order)
 }
@@ -0,0 +1,290 @@
+package tokens
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Achilles1089/sovereign-stack/internal/config"
+	"github.com/Achilles1089/sovereign-stack/internal/rbac"
+)
+
+// Prefix marks a personal API token so it can be told apart from a session
+const Prefix = "sov_"
+
+// lastUsedResolution limits how often LastUsedAt is written back to disk
+const lastUsedResolution = time.Minute
+
+// ErrInvalidToken is returned for unknown, revoked, malformed or expired tokens
+var ErrInvalidToken = errors.New("invalid or expired API token")
+
+// Token is a personal API token bound to a user. Only a hash of the secret is stored.
+type Token struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Username    string            `json:"username"`
+	Hash        string            `json:"hash"`
+	Permissions []rbac.Permission `json:"permissions,omitempty"` // empty = all of the user's permissions
+	CreatedAt   time.Time         `json:"created_at"`
+	ExpiresAt   *time.Time        `json:"expires_at,omitempty"`
+	LastUsedAt  *time.Time        `json:"last_used_at,omitempty"`
+}
+
+// Allows reports whether the token's scope includes a permission.
+// The user's role must still grant it separately.
+func (t *Token) Allows(perm rbac.Permission) bool {
+	if len(t.Permissions) == 0 {
+		return true
+	}
+	for _, p := range t.Permissions {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Expired reports whether the token is past its expiry
+func (t *Token) Expired() bool {
+	return t.ExpiresAt != nil && time.Now().After(*t.ExpiresAt)
+}
+
+// mu serialises load-modify-save of tokens.json within this process
+var mu sync.Mutex
+
+// Create issues a new token for a user and returns the plaintext once.
+// A zero ttl means the token never expires.
+func Create(username, name string, perms []rbac.Permission, ttl time.Duration) (string, *Token, error) {
+	if name == "" {
+		return "", nil, fmt.Errorf("token name is required")
+	}
+
+	user, err := rbac.GetUser(username)
+	if err != nil {
+		return "", nil, err
+	}
+	for _, p := range perms {
+		if !user.CanAny(p) {
+			return "", nil, fmt.Errorf("user %s does not have permission %q", username, p)
+		}
+	}
+
+	idBytes := make([]byte, 8)
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", nil, err
+	}
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", nil, err
+	}
+	id := hex.EncodeToString(idBytes)
+	secret := base64.RawURLEncoding.EncodeToString(secretBytes)
+
+	tok := &Token{
+		ID:          id,
+		Name:        name,
+		Username:    username,
+		Hash:        hashSecret(secret),
+		Permissions: perms,
+		CreatedAt:   time.Now().UTC(),
+	}
+	if ttl > 0 {
+		exp := tok.CreatedAt.Add(ttl)
+		tok.ExpiresAt = &exp
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	all, err := load()
+	if err != nil {
+		return "", nil, err
+	}
+	all = append(all, *tok)
+	if err := save(all); err != nil {
+		return "", nil, err
+	}
+
+	return Prefix + id + "_" + secret, tok, nil
+}
+
+// List returns tokens for a user, or every token when username is empty
+func List(username string) ([]Token, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	all, err := load()
+	if err != nil {
+		return nil, err
+	}
+	if username == "" {
+		return all, nil
+	}
+
+	var result []Token
+	for _, t := range all {
+		if t.Username == username {
+			result = append(result, t)
+		}
+	}
+	return result, nil
+}
+
+// Get finds a token by ID
+func Get(id string) (*Token, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	all, err := load()
+	if err != nil {
+		return nil, err
+	}
+	for i := range all {
+		if all[i].ID == id {
+			return &all[i], nil
+		}
+	}
+	return nil, fmt.Errorf("token %q not found", id)
+}
+
+// Revoke deletes a token by ID
+func Revoke(id string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	all, err := load()
+	if err != nil {
+		return err
+	}
+
+	var updated []Token
+	found := false
+	for _, t := range all {
+		if t.ID == id {
+			found = true
+			continue
+		}
+		updated = append(updated, t)
+	}
+	if !found {
+		return fmt.Errorf("token %q not found", id)
+	}
+	return save(updated)
+}
+
+// Authenticate resolves a plaintext token and records its use
+func Authenticate(raw string) (*Token, error) {
+	id, secret, ok := parse(raw)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	all, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range all {
+		t := &all[i]
+		if t.ID != id {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(t.Hash), []byte(hashSecret(secret))) != 1 || t.Expired() {
+			return nil, ErrInvalidToken
+		}
+
+		now := time.Now().UTC()
+		if t.LastUsedAt == nil || now.Sub(*t.LastUsedAt) >= lastUsedResolution {
+			t.LastUsedAt = &now
+			if err := save(all); err != nil {
+				return nil, err
+			}
+		}
+
+		tok := *t
+		return &tok, nil
+	}
+	return nil, ErrInvalidToken
+}
+
+// IsToken reports whether a bearer credential looks like an API token
+func IsToken(raw string) bool {
+	return strings.HasPrefix(raw, Prefix)
+}
+
+// ParsePermissions converts a comma-separated list into known permissions
+func ParsePermissions(list string) ([]rbac.Permission, error) {
+	var perms []rbac.Permission
+	for _, p := range strings.Split(list, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if !rbac.IsPermission(rbac.Permission(p)) {
+			return nil, fmt.Errorf("unknown permission %q", p)
+		}
+		perms = append(perms, rbac.Permission(p))
+	}
+	return perms, nil
+}
+
+func parse(raw string) (id, secret string, ok bool) {
+	rest, found := strings.CutPrefix(raw, Prefix)
+	if !found {
+		return "", "", false
+	}
+	id, secret, ok = strings.Cut(rest, "_")
+	if !ok || id == "" || secret == "" {
+		return "", "", false
+	}
+	return id, secret, true
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func tokensPath() string {
+	return filepath.Join(config.ConfigDir(), "tokens.json")
+}
+
+func load() ([]Token, error) {
+	data, err := os.ReadFile(tokensPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var all []Token
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, fmt.Errorf("failed to parse tokens: %w", err)
+	}
+	return all, nil
+}
+
+func save(all []Token) error {
+	data, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(config.ConfigDir(), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(tokensPath(), data, 0600)
+}
@@ -0,0 +1,100 @@
+package tokens
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Achilles1089/sovereign-stack/internal/rbac"
+)
+
+func setupHome(t *testing.T) {
+	t.Helper()
+	tmpDir := t.TempDir()
+	os.Setenv("HOME", tmpDir)
+	os.MkdirAll(filepath.Join(tmpDir, ".sovereign"), 0755)
+	t.Cleanup(func() { os.Unsetenv("HOME") })
+
+	rbac.SaveConfig(&rbac.RBACConfig{
+		Enabled: true,
+		Users: []rbac.User{
+			{Username: "admin", Role: rbac.RoleAdmin, Active: true},
+			{Username: "viewer", Role: rbac.RoleViewer, Active: true},
+		},
+	})
+}
+
+func TestCreateAndAuthenticate(t *testing.T) {
+	setupHome(t)
+
+	raw, tok, err := Create("admin", "ci", []rbac.Permission{rbac.PermAppList}, 0)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if !strings.HasPrefix(raw, Prefix) {
+		t.Errorf("token should start with %s", Prefix)
+	}
+
+	// The plaintext must never reach disk
+	data, _ := os.ReadFile(tokensPath())
+	if strings.Contains(string(data), raw[len(Prefix)+len(tok.ID)+1:]) {
+		t.Error("tokens.json contains the plaintext secret")
+	}
+
+	got, err := Authenticate(raw)
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if got.Username != "admin" || got.LastUsedAt == nil {
+		t.Errorf("unexpected token: %+v", got)
+	}
+	if !got.Allows(rbac.PermAppList) || got.Allows(rbac.PermAppInstall) {
+		t.Error("token scope not applied")
+	}
+
+	if _, err := Authenticate(raw + "x"); err != ErrInvalidToken {
+		t.Errorf("expected ErrInvalidToken for wrong secret, got %v", err)
+	}
+	if _, err := Authenticate("sov_nope"); err != ErrInvalidToken {
+		t.Errorf("expected ErrInvalidToken for malformed token, got %v", err)
+	}
+}
+
+func TestCreateRejectsEscalation(t *testing.T) {
+	setupHome(t)
+
+	if _, _, err := Create("viewer", "sneaky", []rbac.Permission{rbac.PermAppInstall}, 0); err == nil {
+		t.Error("viewer should not get a token with app.install")
+	}
+	if _, _, err := Create("nobody", "ghost", nil, 0); err == nil {
+		t.Error("expected error for unknown user")
+	}
+	if _, err := ParsePermissions("app.list,not.a.perm"); err == nil {
+		t.Error("expected error for unknown permission")
+	}
+}
+
+func TestExpiryAndRevoke(t *testing.T) {
+	setupHome(t)
+
+	expired, _, _ := Create("admin", "old", nil, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if _, err := Authenticate(expired); err != ErrInvalidToken {
+		t.Errorf("expected ErrInvalidToken for expired token, got %v", err)
+	}
+
+	raw, tok, _ := Create("admin", "temp", nil, time.Hour)
+	if err := Revoke(tok.ID); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	if _, err := Authenticate(raw); err != ErrInvalidToken {
+		t.Errorf("expected ErrInvalidToken after revoke, got %v", err)
+	}
+
+	list, _ := List("admin")
+	if len(list) != 1 {
+		t.Errorf("expected 1 remaining token, got %d", len(list))
+	}
+}